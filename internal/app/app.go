@@ -5,6 +5,11 @@ import (
 	"github.com/MarlyasDad/rd-hub-go/internal/app/http"
 	appconfig "github.com/MarlyasDad/rd-hub-go/internal/config"
 	tgBot "github.com/MarlyasDad/rd-hub-go/internal/infra/telegram"
+	"github.com/MarlyasDad/rd-hub-go/internal/repository"
+	barstofile "github.com/MarlyasDad/rd-hub-go/internal/services/algo/bars_to_file"
+	httpPortfoliosCommand "github.com/MarlyasDad/rd-hub-go/internal/services/http/portfolios"
+	httpSubscribersCommand "github.com/MarlyasDad/rd-hub-go/internal/services/http/subscribers"
+	"github.com/MarlyasDad/rd-hub-go/internal/services/scheduler/restore"
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
 	"github.com/MarlyasDad/rd-hub-go/pkg/scheduler"
 	"go.uber.org/zap"
@@ -12,8 +17,15 @@ import (
 	"log/slog"
 	"os"
 	"sync"
+	"time"
 )
 
+// Как часто сохраняем Storage подписчиков
+const saveStateInterval = 30 * time.Second
+
+// clockSyncInterval как часто сверять часы биржи, системные часы уходят со временем
+const clockSyncInterval = 10 * time.Minute
+
 type (
 	App struct {
 		ctx          context.Context
@@ -23,6 +35,7 @@ type (
 		brokerClient *alor.Client
 		tgBot        tgBot.TgClient
 		httpServer   http.Server
+		restore      *restore.Service // nil, если БД не настроена
 		zapLogger    *zap.Logger
 	}
 )
@@ -41,6 +54,9 @@ func NewApp(ctx context.Context, wg *sync.WaitGroup, config appconfig.Config) (*
 	//err := errors.New("failure")
 	//slog.Error("slog", slog.Any("error", err), slog.Int("pid", os.Getpid()))
 
+	// Стратегии пишут файлы только в каталог данных из конфига
+	barstofile.SetDataDir(config.DataDir)
+
 	// create a scheduler
 	sch, err := scheduler.NewScheduler()
 	if err != nil {
@@ -53,6 +69,27 @@ func NewApp(ctx context.Context, wg *sync.WaitGroup, config appconfig.Config) (*
 	// brokerClient := broker.New(alorClient)
 	slog.Info("Broker setup successful")
 
+	// Postgres для сохранения подписчиков, без него всё живёт в памяти
+	var (
+		subscribersService *httpSubscribersCommand.Service
+		restoreService     *restore.Service
+	)
+
+	if config.Repository.Host != "" {
+		pool, err := repository.NewPgxConn(ctx, config.Repository)
+		if err != nil {
+			return nil, err
+		}
+
+		repo := repository.NewRepo(pool)
+		subscribersService = httpSubscribersCommand.New(alorClient, repo)
+		restoreService = restore.New(repo, subscribersService)
+		slog.Info("Repository setup successful")
+	} else {
+		subscribersService = httpSubscribersCommand.New(alorClient, nil)
+		slog.Warn("Repository is not configured, subscribers will not survive restart")
+	}
+
 	// Telegram bot
 	//bot, err := tgBot.New(ctx, config.Telegram)
 	//if err != nil {
@@ -64,7 +101,7 @@ func NewApp(ctx context.Context, wg *sync.WaitGroup, config appconfig.Config) (*
 
 	// Http server
 	httpServer := http.New(config.Server)
-	http.RegisterHandlers(httpServer.Mux, alorClient)
+	http.RegisterHandlers(httpServer.Mux, subscribersService, httpPortfoliosCommand.New(alorClient))
 
 	// Merge all components into app
 	return &App{
@@ -74,6 +111,7 @@ func NewApp(ctx context.Context, wg *sync.WaitGroup, config appconfig.Config) (*
 		scheduler:    sch,
 		brokerClient: alorClient,
 		httpServer:   httpServer,
+		restore:      restoreService,
 		// zapLogger:    zapLog,
 		// tgBot:        bot,
 	}, nil
@@ -86,6 +124,29 @@ func (a *App) Start() error {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
+	// Позиции и риски счетов из конфига доступны http и стратегиям
+	for _, portfolio := range a.config.Portfolios {
+		if _, err := a.brokerClient.SubscribePortfolio(portfolio.Exchange, portfolio.Portfolio, portfolio.Opcodes...); err != nil {
+			slog.Error("Portfolio subscription failed", slog.String("portfolio", portfolio.Portfolio), slog.Any("error", err))
+		}
+	}
+
+	// Таймеры баров идут по часам биржи, поправку держим свежей
+	if _, err := a.scheduler.NewDurationJob(clockSyncInterval, a.syncClock); err != nil {
+		return err
+	}
+
+	// Поднимаем подписчиков, работавших до рестарта
+	if a.restore != nil {
+		if err := a.restore.Restore(a.ctx); err != nil {
+			slog.Error("Some subscribers were not restored", slog.Any("error", err))
+		}
+
+		if _, err := a.scheduler.NewDurationJob(saveStateInterval, a.saveState); err != nil {
+			return err
+		}
+	}
+
 	// Начинаем принимать команды от http
 	a.httpServer.Start()
 	// Начинаем выполнять задания по расписанию
@@ -140,6 +201,10 @@ func (a *App) Stop() error {
 	// a.tgBot.Stop()
 	// Прекращаем получать команды от http
 	a.httpServer.Stop()
+	// Сохраняем последнее состояние подписчиков
+	if a.restore != nil {
+		a.saveState()
+	}
 	// Отключаемся от брокера
 	a.brokerClient.Stop()
 
@@ -149,3 +214,19 @@ func (a *App) Stop() error {
 
 	return nil
 }
+
+func (a *App) saveState() {
+	// Контекст приложения при остановке уже отменён
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := a.restore.SaveState(ctx); err != nil {
+		slog.Error("Subscribers state was not saved", slog.Any("error", err))
+	}
+}
+
+func (a *App) syncClock() {
+	if err := a.brokerClient.Clock.Sync(); err != nil {
+		slog.Error("Exchange clock was not synced", slog.Any("error", err))
+	}
+}
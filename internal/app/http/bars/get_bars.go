@@ -10,11 +10,12 @@ import (
 	"github.com/google/uuid"
 	"log"
 	"net/http"
+	"strconv"
 )
 
 type (
 	getSubscriberBarsCommand interface {
-		GetSubscriberBars(subscriberID alor.SubscriberID, heikenAshi bool) ([]*alor.Bar, error)
+		GetSubscriberBars(subscriberID alor.SubscriberID, transform string) ([]*alor.Bar, error)
 	}
 
 	GetSubscriberBarsHandler struct {
@@ -24,7 +25,8 @@ type (
 
 	GetSubscriberBarsRequest struct {
 		SubscriberID alor.SubscriberID `json:"subscriber_id"`
-		HeikenAshi   bool              `json:"heiken_ashi"`
+		HeikenAshi   bool              `json:"heiken_ashi"` // То же, что transform=heikin_ashi
+		Transform    string            `json:"transform"`   // heikin_ashi, log_returns или normalized
 	}
 
 	GetSubscriberBarsResponse struct{}
@@ -55,7 +57,7 @@ func (h *GetSubscriberBarsHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 	//	return
 	//}
 
-	subscribers, err := h.getSubscriberBarsCommand.GetSubscriberBars(requestData.SubscriberID, requestData.HeikenAshi)
+	subscribers, err := h.getSubscriberBarsCommand.GetSubscriberBars(requestData.SubscriberID, requestData.Transform)
 	if err != nil {
 		log.Printf("route %s with error: %s", h.name, err)
 
@@ -64,6 +66,11 @@ func (h *GetSubscriberBarsHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
+		if errors.Is(err, alor.ErrUnknownBarTransform) {
+			responses.GetErrorResponse(w, h.name, err, http.StatusBadRequest)
+			return
+		}
+
 		responses.GetErrorResponse(w, h.name, err, http.StatusInternalServerError)
 		return
 	}
@@ -87,10 +94,15 @@ func (h *GetSubscriberBarsHandler) getRequestData(r *http.Request) (requestData
 
 	requestData.SubscriberID = alor.SubscriberID(subscriberID)
 
-	//heikenAshi, err := strconv.ParseBool(r.FormValue("heiken_ashi"))
-	//if err == nil {
-	//	requestData.HeikenAshi = heikenAshi
-	//}
+	requestData.Transform = r.FormValue("transform")
+
+	if heikenAshi, parseErr := strconv.ParseBool(r.FormValue("heiken_ashi")); parseErr == nil {
+		requestData.HeikenAshi = heikenAshi
+	}
+
+	if requestData.Transform == "" && requestData.HeikenAshi {
+		requestData.Transform = alor.HeikinAshiTransform
+	}
 
 	return
 }
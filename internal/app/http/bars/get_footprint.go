@@ -0,0 +1,108 @@
+package bars
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MarlyasDad/rd-hub-go/internal/app/http/responses"
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// defaultFootprintCount сколько последних баров отдавать без параметра count
+const defaultFootprintCount = 50
+
+type (
+	getSubscriberFootprintsCommand interface {
+		GetSubscriberFootprints(subscriberID alor.SubscriberID, timeframe int64, count int) ([]alor.BarFootprint, error)
+	}
+
+	GetSubscriberFootprintsHandler struct {
+		name                           string
+		getSubscriberFootprintsCommand getSubscriberFootprintsCommand
+	}
+
+	GetSubscriberFootprintsRequest struct {
+		SubscriberID alor.SubscriberID `json:"subscriber_id"`
+		Timeframe    int64             `json:"timeframe"` // Старший таймфрейм подписчика, по умолчанию основной
+		Count        int               `json:"count"`     // Сколько последних баров, по умолчанию 50
+	}
+)
+
+func NewSubscriberFootprintsHandler(command getSubscriberFootprintsCommand, name string) *GetSubscriberFootprintsHandler {
+	return &GetSubscriberFootprintsHandler{
+		name:                           name,
+		getSubscriberFootprintsCommand: command,
+	}
+}
+
+func (h *GetSubscriberFootprintsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		requestData *GetSubscriberFootprintsRequest
+		err         error
+	)
+
+	if requestData, err = h.getRequestData(r); err != nil {
+		responses.GetErrorResponse(w, h.name, err, http.StatusBadRequest)
+		return
+	}
+
+	footprints, err := h.getSubscriberFootprintsCommand.GetSubscriberFootprints(requestData.SubscriberID, requestData.Timeframe, requestData.Count)
+	if err != nil {
+		log.Printf("route %s with error: %s", h.name, err)
+
+		if errors.Is(err, domain.ErrSubscriberNotFound) {
+			responses.GetErrorResponse(w, h.name, err, http.StatusNotFound)
+			return
+		}
+
+		if errors.Is(err, alor.ErrUnknownTimeframe) {
+			responses.GetErrorResponse(w, h.name, err, http.StatusBadRequest)
+			return
+		}
+
+		responses.GetErrorResponse(w, h.name, err, http.StatusInternalServerError)
+		return
+	}
+
+	footprintsJson, err := json.Marshal(footprints)
+	if err != nil {
+		responses.GetErrorResponse(w, h.name, fmt.Errorf("json marshalling failed: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	responses.GetSuccessResponse(w, footprintsJson)
+}
+
+func (h *GetSubscriberFootprintsHandler) getRequestData(r *http.Request) (*GetSubscriberFootprintsRequest, error) {
+	requestData := &GetSubscriberFootprintsRequest{Count: defaultFootprintCount}
+
+	subscriberID, err := uuid.Parse(r.PathValue("subscriber_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	requestData.SubscriberID = alor.SubscriberID(subscriberID)
+
+	if value := r.FormValue("timeframe"); value != "" {
+		if requestData.Timeframe, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("timeframe: %w", err)
+		}
+	}
+
+	if value := r.FormValue("count"); value != "" {
+		if requestData.Count, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("count: %w", err)
+		}
+
+		if requestData.Count <= 0 {
+			return nil, fmt.Errorf("count must be positive")
+		}
+	}
+
+	return requestData, nil
+}
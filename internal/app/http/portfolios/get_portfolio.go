@@ -0,0 +1,64 @@
+package portfolios
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MarlyasDad/rd-hub-go/internal/app/http/responses"
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"net/http"
+)
+
+type (
+	getPortfolioCommand interface {
+		GetPortfolio(exchange alor.Exchange, portfolio string) (alor.PortfolioSnapshot, error)
+	}
+
+	GetPortfolioHandler struct {
+		name                string
+		getPortfolioCommand getPortfolioCommand
+	}
+
+	GetPortfolioRequest struct {
+		Exchange  alor.Exchange `json:"exchange"`
+		Portfolio string        `json:"portfolio"`
+	}
+)
+
+func NewPortfolioHandler(command getPortfolioCommand, name string) *GetPortfolioHandler {
+	return &GetPortfolioHandler{
+		name:                name,
+		getPortfolioCommand: command,
+	}
+}
+
+func (h *GetPortfolioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestData := h.getRequestData(r)
+
+	portfolio, err := h.getPortfolioCommand.GetPortfolio(requestData.Exchange, requestData.Portfolio)
+	if err != nil {
+		// Клиент не подписан на этот портфель
+		if errors.Is(err, alor.ErrPortfolioNotFound) {
+			responses.GetErrorResponse(w, h.name, err, http.StatusNotFound)
+			return
+		}
+
+		responses.GetErrorResponse(w, h.name, err, http.StatusInternalServerError)
+		return
+	}
+
+	portfolioJson, err := json.Marshal(portfolio)
+	if err != nil {
+		responses.GetErrorResponse(w, h.name, fmt.Errorf("json marshalling failed: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	responses.GetSuccessResponse(w, portfolioJson)
+}
+
+func (h *GetPortfolioHandler) getRequestData(r *http.Request) *GetPortfolioRequest {
+	return &GetPortfolioRequest{
+		Exchange:  alor.Exchange(r.PathValue("exchange")),
+		Portfolio: r.PathValue("portfolio"),
+	}
+}
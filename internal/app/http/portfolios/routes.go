@@ -0,0 +1,18 @@
+package portfolios
+
+import (
+	"net/http"
+
+	httpPortfoliosCommand "github.com/MarlyasDad/rd-hub-go/internal/services/http/portfolios"
+)
+
+func RegisterRoutes(mux *http.ServeMux, portfoliosService *httpPortfoliosCommand.Service) {
+	getPortfolioPattern := "GET /api/portfolio/{exchange}/{portfolio}"
+	mux.Handle(
+		getPortfolioPattern,
+		NewPortfolioHandler(
+			portfoliosService,
+			getPortfolioPattern,
+		),
+	)
+}
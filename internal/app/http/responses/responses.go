@@ -14,6 +14,12 @@ func GetErrorResponse(w http.ResponseWriter, handlerName string, err error, stat
 	_, _ = w.Write(buf.Bytes())
 }
 
+func GetErrorResponseWithBody(w http.ResponseWriter, body []byte, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
+}
+
 func GetUnauthorizedResponse(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusUnauthorized)
 }
@@ -2,12 +2,18 @@ package http
 
 import (
 	"github.com/MarlyasDad/rd-hub-go/internal/app/http/index"
+	"github.com/MarlyasDad/rd-hub-go/internal/app/http/portfolios"
+	"github.com/MarlyasDad/rd-hub-go/internal/app/http/strategies"
 	"github.com/MarlyasDad/rd-hub-go/internal/app/http/subscribers"
+	httpPortfoliosCommand "github.com/MarlyasDad/rd-hub-go/internal/services/http/portfolios"
+	httpSubscribersCommand "github.com/MarlyasDad/rd-hub-go/internal/services/http/subscribers"
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
 	"net/http"
 )
 
-func RegisterHandlers(mux *http.ServeMux, brokerClient *alor.Client) {
+func RegisterHandlers(mux *http.ServeMux, subscribersService *httpSubscribersCommand.Service, portfoliosService *httpPortfoliosCommand.Service) {
 	index.RegisterRoutes(mux)
-	subscribers.RegisterRoutes(mux, brokerClient)
+	subscribers.RegisterRoutes(mux, subscribersService)
+	portfolios.RegisterRoutes(mux, portfoliosService)
+	strategies.RegisterRoutes(mux, alor.Strategies())
 }
@@ -0,0 +1,39 @@
+package strategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MarlyasDad/rd-hub-go/internal/app/http/responses"
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"net/http"
+)
+
+type (
+	getStrategiesListCommand interface {
+		GetStrategies() []alor.StrategySchema
+	}
+
+	GetStrategiesListHandler struct {
+		name                     string
+		getStrategiesListCommand getStrategiesListCommand
+	}
+)
+
+func NewStrategiesListHandler(command getStrategiesListCommand, name string) *GetStrategiesListHandler {
+	return &GetStrategiesListHandler{
+		name:                     name,
+		getStrategiesListCommand: command,
+	}
+}
+
+func (h *GetStrategiesListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	strategies := h.getStrategiesListCommand.GetStrategies()
+
+	strategiesJson, err := json.Marshal(strategies)
+	if err != nil {
+		responses.GetErrorResponse(w, h.name, fmt.Errorf("json marshalling failed: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	responses.GetSuccessResponse(w, strategiesJson)
+}
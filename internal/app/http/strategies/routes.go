@@ -0,0 +1,19 @@
+package strategies
+
+import (
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"net/http"
+
+	httpStrategiesCommand "github.com/MarlyasDad/rd-hub-go/internal/services/http/strategies"
+)
+
+func RegisterRoutes(mux *http.ServeMux, strategyRegistry *alor.StrategyRegistry) {
+	getStrategiesPattern := "GET /api/strategies"
+	mux.Handle(
+		getStrategiesPattern,
+		NewStrategiesListHandler(
+			httpStrategiesCommand.New(strategyRegistry),
+			getStrategiesPattern,
+		),
+	)
+}
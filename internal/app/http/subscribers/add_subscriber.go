@@ -3,6 +3,7 @@ package subscribers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/MarlyasDad/rd-hub-go/internal/app/http/responses"
 	"github.com/MarlyasDad/rd-hub-go/internal/services/http/subscribers"
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
@@ -19,59 +20,6 @@ type (
 		name                 string
 		addSubscriberCommand addSubscriberCommand
 	}
-
-	addSubscriberRequest struct {
-		Description   string        `json:"description"`
-		Instrument    Instrument    `json:"instrument"`
-		Strategy      Strategy      `json:"strategy"`
-		Subscriptions Subscriptions `json:"subscriptions"`
-		Indicators    []Indicator   `json:"indicators"`
-		Async         bool          `json:"async"`
-	}
-
-	Instrument struct {
-		Exchange  string `json:"exchange"`
-		Code      string `json:"code"`
-		Board     string `json:"board"`
-		Timeframe int64  `json:"timeframe"`
-	}
-
-	Strategy struct {
-		Name                 string          `json:"name"`
-		Settings             json.RawMessage `json:"settings"`
-		WithDelta            bool            `json:"withDelta"`
-		WithMarketProfile    bool            `json:"withMarketProfile"`
-		WithOrderBookProfile bool            `json:"withOrderBookProfile"`
-	}
-
-	Subscriptions struct {
-		AllTrades *AllTradesParams `json:"allTrades"`
-		OrderBook *OrderBookParams `json:"orderBook"`
-		Bars      *BarsParams      `json:"bars"`
-	}
-
-	AllTradesParams struct {
-		Frequency            int  `json:"frequency"`
-		Depth                int  `json:"depth"`
-		IncludeVirtualTrades bool `json:"includeVirtualTrades"`
-	}
-
-	OrderBookParams struct {
-		Frequency int `json:"frequency"`
-		Depth     int `json:"depth"`
-	}
-
-	BarsParams struct {
-		Frequency   int   `json:"frequency"`
-		From        int64 `json:"from"`
-		SkipHistory bool  `json:"skipHistory"`
-		SplitAdjust bool  `json:"splitAdjust"`
-	}
-
-	Indicator struct {
-		Name     string          `json:"name"`
-		Settings json.RawMessage `json:"settings"`
-	}
 )
 
 func NewAddSubscriberHandler(command addSubscriberCommand, name string) *AddSubscriberHandler {
@@ -93,10 +41,48 @@ func (h *AddSubscriberHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err = h.validateRequestData(requestData); err != nil {
+		responses.GetErrorResponse(w, h.name, err, http.StatusBadRequest)
+		return
+	}
+
 	// добавляем подписчика
 
 	subscriberID, err := h.addSubscriberCommand.AddSubscriber(ctx, requestData)
 	if err != nil {
+		// Ошибки настроек стратегии и индикаторов отдаём по полям, чтобы UI подсветил форму
+		var settingsErr *alor.StrategySettingsError
+		if errors.As(err, &settingsErr) {
+			bodyBytes, _ := json.Marshal(settingsErr)
+			responses.GetErrorResponseWithBody(w, bodyBytes, http.StatusBadRequest)
+			return
+		}
+
+		var indicatorSettingsErr *alor.IndicatorSettingsError
+		if errors.As(err, &indicatorSettingsErr) {
+			bodyBytes, _ := json.Marshal(indicatorSettingsErr)
+			responses.GetErrorResponseWithBody(w, bodyBytes, http.StatusBadRequest)
+			return
+		}
+
+		if errors.Is(err, alor.ErrUnknownStrategy) || errors.Is(err, alor.ErrUnknownIndicator) || errors.Is(err, alor.ErrSecurityNotFound) ||
+			errors.Is(err, alor.ErrInvalidTimeframe) || errors.Is(err, alor.ErrInvalidBarSize) || errors.Is(err, alor.ErrUnknownBarType) ||
+			errors.Is(err, alor.ErrUnknownEmptyBarPolicy) {
+			responses.GetErrorResponse(w, h.name, err, http.StatusBadRequest)
+			return
+		}
+
+		// Брокер отклонил подписку или не ответил на неё
+		if errors.Is(err, alor.ErrSubscriptionRejected) {
+			responses.GetErrorResponse(w, h.name, err, http.StatusBadGateway)
+			return
+		}
+
+		if errors.Is(err, alor.ErrSubscriptionTimeout) {
+			responses.GetErrorResponse(w, h.name, err, http.StatusGatewayTimeout)
+			return
+		}
+
 		responses.GetErrorResponse(w, h.name, err, http.StatusInternalServerError)
 		return
 	}
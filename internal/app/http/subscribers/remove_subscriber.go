@@ -1,6 +1,7 @@
 package subscribers
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/MarlyasDad/rd-hub-go/internal/app/http/responses"
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
@@ -10,7 +11,7 @@ import (
 
 type (
 	removeSubscriberCommand interface {
-		RemoveSubscriber(id alor.SubscriberID) error
+		RemoveSubscriber(ctx context.Context, id alor.SubscriberID) error
 	}
 
 	RemoveSubscriberHandler struct {
@@ -43,7 +44,7 @@ func NewRemoveSubscriberHandler(command removeSubscriberCommand, name string) *R
 
 func (h *RemoveSubscriberHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
-		// ctx = r.Context()
+		ctx         = r.Context()
 		requestData *removeSubscriberRequest
 		err         error
 	)
@@ -59,7 +60,7 @@ func (h *RemoveSubscriberHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = h.removeSubscriberCommand.RemoveSubscriber(requestData.ID)
+	err = h.removeSubscriberCommand.RemoveSubscriber(ctx, requestData.ID)
 	if err != nil {
 		responses.GetErrorResponse(w, h.name, err, http.StatusInternalServerError)
 		return
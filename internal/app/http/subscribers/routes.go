@@ -2,18 +2,17 @@ package subscribers
 
 import (
 	"github.com/MarlyasDad/rd-hub-go/internal/app/http/bars"
-	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
 	"net/http"
 
 	httpSubscribersCommand "github.com/MarlyasDad/rd-hub-go/internal/services/http/subscribers"
 )
 
-func RegisterRoutes(mux *http.ServeMux, brokerClient *alor.Client) {
+func RegisterRoutes(mux *http.ServeMux, subscribersService *httpSubscribersCommand.Service) {
 	getSubscriberPattern := "GET /api/subscriber"
 	mux.Handle(
 		getSubscriberPattern,
 		NewSubscriberHandler(
-			httpSubscribersCommand.New(brokerClient),
+			subscribersService,
 			getSubscriberPattern,
 		),
 	)
@@ -22,7 +21,7 @@ func RegisterRoutes(mux *http.ServeMux, brokerClient *alor.Client) {
 	mux.Handle(
 		getSubscribersPattern,
 		NewSubscribersListHandler(
-			httpSubscribersCommand.New(brokerClient),
+			subscribersService,
 			getSubscribersPattern,
 		),
 	)
@@ -31,16 +30,25 @@ func RegisterRoutes(mux *http.ServeMux, brokerClient *alor.Client) {
 	mux.Handle(
 		getSubscriberBarsPattern,
 		bars.NewSubscriberBarsHandler(
-			httpSubscribersCommand.New(brokerClient),
+			subscribersService,
 			getSubscriberBarsPattern,
 		),
 	)
 
+	getSubscriberFootprintsPattern := "GET /api/subscriber/{subscriber_id}/footprint"
+	mux.Handle(
+		getSubscriberFootprintsPattern,
+		bars.NewSubscriberFootprintsHandler(
+			subscribersService,
+			getSubscriberFootprintsPattern,
+		),
+	)
+
 	addSubscriberPattern := "POST /api/subscriber"
 	mux.Handle(
 		addSubscriberPattern,
 		NewAddSubscriberHandler(
-			httpSubscribersCommand.New(brokerClient),
+			subscribersService,
 			addSubscriberPattern,
 		),
 	)
@@ -49,7 +57,7 @@ func RegisterRoutes(mux *http.ServeMux, brokerClient *alor.Client) {
 	mux.Handle(
 		removeSubscriberPattern,
 		NewRemoveSubscriberHandler(
-			httpSubscribersCommand.New(brokerClient),
+			subscribersService,
 			removeSubscriberPattern,
 		),
 	)
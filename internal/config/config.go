@@ -3,7 +3,11 @@ package config
 import (
 	"github.com/MarlyasDad/rd-hub-go/internal/app/http"
 	"github.com/MarlyasDad/rd-hub-go/internal/infra/jaeger"
+	"github.com/MarlyasDad/rd-hub-go/internal/repository"
 	"github.com/MarlyasDad/rd-hub-go/pkg/logger"
+	"log"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/MarlyasDad/rd-hub-go/internal/infra/telegram"
@@ -12,24 +16,44 @@ import (
 
 type (
 	EnvVars struct {
-		ApiHost               string    `envconfig:"rd_server_host"`
-		ApiPort               int64     `envconfig:"rd_server_port"`
-		BrokerRefreshToken    string    `envconfig:"broker_refresh"`
-		BrokerRefreshTokenExp time.Time `envconfig:"broker_refresh_exp"`
-		BrokerDevCircuit      bool      `envconfig:"broker_dev_circuit" default:"true"`
-		OtelGrpcEndpoint      string    `envconfig:"otel_grpc_endpoint"`
-		OtelRatioBased        float64   `envconfig:"otel_ratio_based" default:"0.0"`
-		DebugMode             bool      `envconfig:"debug_mode" default:"false"`
-		TelegramBotToken      string    `envconfig:"telegram_bot_token"`
+		ApiHost               string        `envconfig:"rd_server_host"`
+		ApiPort               int64         `envconfig:"rd_server_port"`
+		BrokerRefreshToken    string        `envconfig:"broker_refresh"`
+		BrokerRefreshTokenExp time.Time     `envconfig:"broker_refresh_exp"`
+		BrokerDevCircuit      bool          `envconfig:"broker_dev_circuit" default:"true"`
+		BrokerPortfolios      []string      `envconfig:"broker_portfolios"` // MOEX:D38572, с суффиксом :forts добавляются риски срочного рынка
+		BrokerWsConnections   int           `envconfig:"broker_ws_max_connections"`
+		BrokerWsSubscriptions int           `envconfig:"broker_ws_max_subscriptions"`
+		BrokerWsQueuePolicy   string        `envconfig:"broker_ws_queue_policy"` // reject, block, drop_oldest, drop_newest, conflate
+		BrokerWsStaleAfter    time.Duration `envconfig:"broker_ws_stale_after"`  // Подписка без данных во время торгов дольше переподписывается
+		OtelGrpcEndpoint      string        `envconfig:"otel_grpc_endpoint"`
+		OtelRatioBased        float64       `envconfig:"otel_ratio_based" default:"0.0"`
+		DebugMode             bool          `envconfig:"debug_mode" default:"false"`
+		TelegramBotToken      string        `envconfig:"telegram_bot_token"`
+		DatabaseHost          string        `envconfig:"database_host"`
+		DatabasePort          int           `envconfig:"database_port" default:"5432"`
+		DatabaseName          string        `envconfig:"database_name"`
+		DatabaseUsername      string        `envconfig:"database_username"`
+		DatabasePassword      string        `envconfig:"database_password"`
+		DataDir               string        `envconfig:"data_dir" default:"data"` // Каталог для файлов стратегий
 	}
 
 	Config struct {
-		Server   http.Config
-		Broker   alor.Config
-		Tracer   jaeger.Config
-		Logger   logger.Config
-		Telegram telegram.Config
-		// repository db_repo.Config
+		Server     http.Config
+		Broker     alor.Config
+		Tracer     jaeger.Config
+		Logger     logger.Config
+		Telegram   telegram.Config
+		Repository repository.Config
+		Portfolios []Portfolio
+		DataDir    string
+	}
+
+	// Portfolio портфель, на данные которого клиент подписывается при старте
+	Portfolio struct {
+		Exchange  alor.Exchange
+		Portfolio string
+		Opcodes   []alor.Opcode
 	}
 )
 
@@ -43,6 +67,14 @@ func NewConfig(f EnvVars) Config {
 			RefreshToken:    f.BrokerRefreshToken,
 			RefreshTokenExp: f.BrokerRefreshTokenExp,
 			DevCircuit:      f.BrokerDevCircuit,
+			Pool: alor.PoolLimits{
+				MaxConnections:                f.BrokerWsConnections,
+				MaxSubscriptionsPerConnection: f.BrokerWsSubscriptions,
+				QueuePolicy:                   alor.OverflowPolicy(f.BrokerWsQueuePolicy),
+			},
+			Heartbeat: alor.HeartbeatConfig{
+				StaleAfter: f.BrokerWsStaleAfter,
+			},
 		},
 		Tracer: jaeger.Config{
 			Endpoint:          f.OtelGrpcEndpoint,
@@ -54,12 +86,41 @@ func NewConfig(f EnvVars) Config {
 		Telegram: telegram.Config{
 			BotToken: f.TelegramBotToken,
 		},
-		// repository: db_repo.Config{
-		// 	Host:     f.DatabaseHost,
-		// 	Port:     f.DatabasePort,
-		// 	Name:     f.DatabaseName,
-		// 	Username: f.DatabaseUsername,
-		// 	Password: f.DatabasePassword,
-		// },
+		Repository: repository.Config{
+			Host:     f.DatabaseHost,
+			Port:     f.DatabasePort,
+			Name:     f.DatabaseName,
+			Username: f.DatabaseUsername,
+			Password: f.DatabasePassword,
+		},
+		Portfolios: parsePortfolios(f.BrokerPortfolios),
+		DataDir:    f.DataDir,
 	}
 }
+
+// parsePortfolios разбирает записи вида MOEX:D38572 или MOEX:7500ABC:forts, кривые записи пропускает
+func parsePortfolios(values []string) []Portfolio {
+	portfolios := make([]Portfolio, 0, len(values))
+
+	for _, value := range values {
+		parts := strings.Split(strings.TrimSpace(value), ":")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			log.Printf("invalid broker portfolio %q, expected EXCHANGE:PORTFOLIO", value)
+			continue
+		}
+
+		portfolio := Portfolio{
+			Exchange:  alor.Exchange(parts[0]),
+			Portfolio: parts[1],
+			Opcodes:   alor.PortfolioOpcodes,
+		}
+
+		if len(parts) > 2 && parts[2] == "forts" {
+			portfolio.Opcodes = append(slices.Clone(alor.PortfolioOpcodes), alor.SpectralRisksOpcode)
+		}
+
+		portfolios = append(portfolios, portfolio)
+	}
+
+	return portfolios
+}
@@ -1,18 +1,29 @@
 package domain
 
 import (
+	"encoding/json"
 	"github.com/google/uuid"
 	"time"
 )
 
+// Subscriber сохранённый подписчик, из него робот пересоздаётся после рестарта.
+// Параметры подписок, индикаторов и бумажного счёта хранятся в том виде, в каком пришли в POST /api/subscriber.
 type Subscriber struct {
-	ID            uuid.UUID         `json:"id"`
-	Description   string            `json:"description"`
-	CreatedAt     time.Time         `json:"createdAt"`
-	ClientID      int64             `json:"clientID"`
-	Exchange      string            `json:"exchange"`
-	Code          string            `json:"code"`
-	Board         string            `json:"board"`
-	Timeframe     int64             `json:"timeframe"`
-	Subscriptions map[string]string `json:"subscriptions,omitempty"` // map[Opcode]Guid
+	ID               uuid.UUID       `json:"id"`
+	Description      string          `json:"description"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	ClientID         int64           `json:"clientID"`
+	Exchange         string          `json:"exchange"`
+	Code             string          `json:"code"`
+	Board            string          `json:"board"`
+	Timeframe        int64           `json:"timeframe"`
+	Async            bool            `json:"async"`
+	Mode             string          `json:"mode"`
+	StrategyName     string          `json:"strategyName"`
+	StrategySettings json.RawMessage `json:"strategySettings,omitempty"`
+	Detailing        json.RawMessage `json:"detailing,omitempty"` // Расчёт дельты, профиля, стакана
+	Subscriptions    json.RawMessage `json:"subscriptions,omitempty"`
+	Indicators       json.RawMessage `json:"indicators,omitempty"`
+	Paper            json.RawMessage `json:"paper,omitempty"`   // Настройки бумажного счёта, не его состояние
+	Storage          json.RawMessage `json:"storage,omitempty"` // Состояние alor.Storage
 }
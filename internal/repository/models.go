@@ -113,6 +113,28 @@ type File struct {
 	Ocr          []byte
 }
 
+type Subscriber struct {
+	ID               pgtype.UUID
+	Description      string
+	CreatedAt        pgtype.Timestamp
+	UpdatedAt        pgtype.Timestamp
+	DeletedAt        pgtype.Timestamp
+	ClientID         int64
+	Exchange         string
+	Code             string
+	Board            string
+	Timeframe        int64
+	Async            bool
+	Mode             string
+	StrategyName     string
+	StrategySettings []byte
+	Detailing        []byte
+	Subscriptions    []byte
+	Indicators       []byte
+	Paper            []byte
+	Storage          []byte
+}
+
 type User struct {
 	ID         int64
 	KeycloakID pgtype.UUID
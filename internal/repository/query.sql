@@ -2,3 +2,45 @@
 INSERT INTO files (user_id, parent_id, attachment_id, created_at, type, name, original_name)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING id;
+
+-- name: SqlcUpsertSubscriber :exec
+INSERT INTO subscribers (id, description, created_at, client_id, exchange, code, board, timeframe, async, mode,
+                         strategy_name, strategy_settings, detailing, subscriptions, indicators, paper, storage)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+ON CONFLICT (id) DO UPDATE
+    SET description       = EXCLUDED.description,
+        updated_at        = NOW(),
+        deleted_at        = NULL,
+        exchange          = EXCLUDED.exchange,
+        code              = EXCLUDED.code,
+        board             = EXCLUDED.board,
+        timeframe         = EXCLUDED.timeframe,
+        async             = EXCLUDED.async,
+        mode              = EXCLUDED.mode,
+        strategy_name     = EXCLUDED.strategy_name,
+        strategy_settings = EXCLUDED.strategy_settings,
+        detailing         = EXCLUDED.detailing,
+        subscriptions     = EXCLUDED.subscriptions,
+        indicators        = EXCLUDED.indicators,
+        paper             = EXCLUDED.paper,
+        storage           = EXCLUDED.storage;
+
+-- name: SqlcUpdateSubscriberStorage :execrows
+UPDATE subscribers
+SET storage    = $2,
+    updated_at = NOW()
+WHERE id = $1
+  AND deleted_at IS NULL;
+
+-- name: SqlcDeleteSubscriber :execrows
+UPDATE subscribers
+SET deleted_at = NOW()
+WHERE id = $1
+  AND deleted_at IS NULL;
+
+-- name: SqlcGetActiveSubscribers :many
+SELECT id, description, created_at, updated_at, deleted_at, client_id, exchange, code, board, timeframe, async, mode,
+       strategy_name, strategy_settings, detailing, subscriptions, indicators, paper, storage
+FROM subscribers
+WHERE deleted_at IS NULL
+ORDER BY created_at;
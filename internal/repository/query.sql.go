@@ -41,3 +41,153 @@ func (q *Queries) SqlcCreateFilesItem(ctx context.Context, arg SqlcCreateFilesIt
 	err := row.Scan(&id)
 	return id, err
 }
+
+const sqlcDeleteSubscriber = `-- name: SqlcDeleteSubscriber :execrows
+UPDATE subscribers
+SET deleted_at = NOW()
+WHERE id = $1
+  AND deleted_at IS NULL
+`
+
+func (q *Queries) SqlcDeleteSubscriber(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, sqlcDeleteSubscriber, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const sqlcGetActiveSubscribers = `-- name: SqlcGetActiveSubscribers :many
+SELECT id, description, created_at, updated_at, deleted_at, client_id, exchange, code, board, timeframe, async, mode,
+       strategy_name, strategy_settings, detailing, subscriptions, indicators, paper, storage
+FROM subscribers
+WHERE deleted_at IS NULL
+ORDER BY created_at
+`
+
+func (q *Queries) SqlcGetActiveSubscribers(ctx context.Context) ([]Subscriber, error) {
+	rows, err := q.db.Query(ctx, sqlcGetActiveSubscribers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscriber
+	for rows.Next() {
+		var i Subscriber
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.ClientID,
+			&i.Exchange,
+			&i.Code,
+			&i.Board,
+			&i.Timeframe,
+			&i.Async,
+			&i.Mode,
+			&i.StrategyName,
+			&i.StrategySettings,
+			&i.Detailing,
+			&i.Subscriptions,
+			&i.Indicators,
+			&i.Paper,
+			&i.Storage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sqlcUpdateSubscriberStorage = `-- name: SqlcUpdateSubscriberStorage :execrows
+UPDATE subscribers
+SET storage    = $2,
+    updated_at = NOW()
+WHERE id = $1
+  AND deleted_at IS NULL
+`
+
+type SqlcUpdateSubscriberStorageParams struct {
+	ID      pgtype.UUID
+	Storage []byte
+}
+
+func (q *Queries) SqlcUpdateSubscriberStorage(ctx context.Context, arg SqlcUpdateSubscriberStorageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, sqlcUpdateSubscriberStorage, arg.ID, arg.Storage)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const sqlcUpsertSubscriber = `-- name: SqlcUpsertSubscriber :exec
+INSERT INTO subscribers (id, description, created_at, client_id, exchange, code, board, timeframe, async, mode,
+                         strategy_name, strategy_settings, detailing, subscriptions, indicators, paper, storage)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+ON CONFLICT (id) DO UPDATE
+    SET description       = EXCLUDED.description,
+        updated_at        = NOW(),
+        deleted_at        = NULL,
+        exchange          = EXCLUDED.exchange,
+        code              = EXCLUDED.code,
+        board             = EXCLUDED.board,
+        timeframe         = EXCLUDED.timeframe,
+        async             = EXCLUDED.async,
+        mode              = EXCLUDED.mode,
+        strategy_name     = EXCLUDED.strategy_name,
+        strategy_settings = EXCLUDED.strategy_settings,
+        detailing         = EXCLUDED.detailing,
+        subscriptions     = EXCLUDED.subscriptions,
+        indicators        = EXCLUDED.indicators,
+        paper             = EXCLUDED.paper,
+        storage           = EXCLUDED.storage
+`
+
+type SqlcUpsertSubscriberParams struct {
+	ID               pgtype.UUID
+	Description      string
+	CreatedAt        pgtype.Timestamp
+	ClientID         int64
+	Exchange         string
+	Code             string
+	Board            string
+	Timeframe        int64
+	Async            bool
+	Mode             string
+	StrategyName     string
+	StrategySettings []byte
+	Detailing        []byte
+	Subscriptions    []byte
+	Indicators       []byte
+	Paper            []byte
+	Storage          []byte
+}
+
+func (q *Queries) SqlcUpsertSubscriber(ctx context.Context, arg SqlcUpsertSubscriberParams) error {
+	_, err := q.db.Exec(ctx, sqlcUpsertSubscriber,
+		arg.ID,
+		arg.Description,
+		arg.CreatedAt,
+		arg.ClientID,
+		arg.Exchange,
+		arg.Code,
+		arg.Board,
+		arg.Timeframe,
+		arg.Async,
+		arg.Mode,
+		arg.StrategyName,
+		arg.StrategySettings,
+		arg.Detailing,
+		arg.Subscriptions,
+		arg.Indicators,
+		arg.Paper,
+		arg.Storage,
+	)
+	return err
+}
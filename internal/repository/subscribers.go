@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (r *Repository) SaveSubscriber(ctx context.Context, subscriber domain.Subscriber) error {
+	return r.queries.SqlcUpsertSubscriber(ctx, SqlcUpsertSubscriberParams{
+		ID:               pgtype.UUID{Bytes: subscriber.ID, Valid: true},
+		Description:      subscriber.Description,
+		CreatedAt:        pgtype.Timestamp{Time: subscriber.CreatedAt, Valid: true},
+		ClientID:         subscriber.ClientID,
+		Exchange:         subscriber.Exchange,
+		Code:             subscriber.Code,
+		Board:            subscriber.Board,
+		Timeframe:        subscriber.Timeframe,
+		Async:            subscriber.Async,
+		Mode:             subscriber.Mode,
+		StrategyName:     subscriber.StrategyName,
+		StrategySettings: subscriber.StrategySettings,
+		Detailing:        subscriber.Detailing,
+		Subscriptions:    subscriber.Subscriptions,
+		Indicators:       subscriber.Indicators,
+		Paper:            subscriber.Paper,
+		Storage:          subscriber.Storage,
+	})
+}
+
+func (r *Repository) UpdateSubscriberStorage(ctx context.Context, subscriberID uuid.UUID, storage []byte) error {
+	rows, err := r.queries.SqlcUpdateSubscriberStorage(ctx, SqlcUpdateSubscriberStorageParams{
+		ID:      pgtype.UUID{Bytes: subscriberID, Valid: true},
+		Storage: storage,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrSubscriberNotFound
+	}
+
+	return nil
+}
+
+// DeleteSubscriber помечает подписчика удалённым, после рестарта он не восстанавливается
+func (r *Repository) DeleteSubscriber(ctx context.Context, subscriberID uuid.UUID) error {
+	rows, err := r.queries.SqlcDeleteSubscriber(ctx, pgtype.UUID{Bytes: subscriberID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrSubscriberNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) GetActiveSubscribers(ctx context.Context) ([]domain.Subscriber, error) {
+	rows, err := r.queries.SqlcGetActiveSubscribers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := make([]domain.Subscriber, 0, len(rows))
+	for _, row := range rows {
+		subscriber := domain.Subscriber{
+			Description:      row.Description,
+			ClientID:         row.ClientID,
+			Exchange:         row.Exchange,
+			Code:             row.Code,
+			Board:            row.Board,
+			Timeframe:        row.Timeframe,
+			Async:            row.Async,
+			Mode:             row.Mode,
+			StrategyName:     row.StrategyName,
+			StrategySettings: row.StrategySettings,
+			Detailing:        row.Detailing,
+			Subscriptions:    row.Subscriptions,
+			Indicators:       row.Indicators,
+			Paper:            row.Paper,
+			Storage:          row.Storage,
+		}
+
+		if id := NConvertUUID(row.ID); id != nil {
+			subscriber.ID = *id
+		}
+
+		if createdAt := NConvertPgTimestamp(row.CreatedAt); createdAt != nil {
+			subscriber.CreatedAt = *createdAt
+		}
+
+		subscribers = append(subscribers, subscriber)
+	}
+
+	return subscribers, nil
+}
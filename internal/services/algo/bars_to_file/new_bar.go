@@ -7,20 +7,15 @@ import (
 	"log"
 )
 
-func (s *Service) NewBar(data alor.BarsSlimData) error {
-	log.Println("NewBar", data.Time)
-
-	bar, err := s.History.GetLastFinalizedBar()
-	if err != nil {
-		return err
-	}
+func (s *Service) OnBarClosed(bar *alor.Bar) error {
+	log.Println("OnBarClosed", bar.Time)
 
 	barBytes, err := json.Marshal(bar)
 	if err != nil {
 		return err
 	}
 
-	_, err = fmt.Fprintln(s.File, barBytes)
+	_, err = fmt.Fprintln(s.File, string(barBytes))
 	if err != nil {
 		return err
 	}
@@ -1,7 +0,0 @@
-package barstofile
-
-import "github.com/MarlyasDad/rd-hub-go/pkg/alor"
-
-func (s *Service) NewOrderBook(data alor.OrderBookSlimData) error {
-	return nil
-}
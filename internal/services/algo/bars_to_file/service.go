@@ -6,22 +6,55 @@ import (
 	"path/filepath"
 )
 
-type Service struct {
-	Name    string
-	File    *os.File
-	History *alor.BarQueue
+func init() {
+	alor.RegisterStrategy("bars_to_file", "Записывает закрытые бары в файл построчно в JSON", Settings{}, New)
+}
+
+// dataDir каталог, в котором стратегия создаёт файлы, задаётся конфигом приложения
+var dataDir = "data"
+
+// SetDataDir задаёт каталог для файлов стратегии, вызывается до создания подписчиков
+func SetDataDir(dir string) {
+	if dir != "" {
+		dataDir = dir
+	}
 }
 
-func New(filename string) *Service {
-	return &Service{Name: filename}
+type Settings struct {
+	Filename string `json:"filename" validate:"required" description:"Имя файла в каталоге данных"`
 }
 
-func (s *Service) SetHistory(history *alor.BarQueue) {
-	s.History = history
+type Service struct {
+	alor.BaseStrategy
+	Name string
+	File *os.File
 }
 
-func (s *Service) Init() error {
-	file, err := os.Create(filepath.Join("data", s.Name))
+func New(settings Settings) (alor.Strategy, error) {
+	// Имя приходит из http, поэтому только файл в каталоге данных, без путей
+	if !filepath.IsLocal(settings.Filename) || filepath.Base(settings.Filename) != settings.Filename {
+		return nil, &alor.StrategySettingsError{
+			Strategy: "bars_to_file",
+			Fields: []alor.SettingsFieldError{{
+				Field:   "filename",
+				Rule:    "filename",
+				Message: "must be a file name without directories",
+			}},
+		}
+	}
+
+	return &Service{
+		Name: settings.Filename,
+	}, nil
+}
+
+func (s *Service) OnStart() error {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+
+	// Дописываем в конец: после рестарта подписчик продолжает тот же файл
+	file, err := os.OpenFile(filepath.Join(dataDir, s.Name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
 	if err != nil {
 		return err
 	}
@@ -29,6 +62,10 @@ func (s *Service) Init() error {
 	return nil
 }
 
-func (s *Service) DeInit() error {
+func (s *Service) OnStop() error {
+	if s.File == nil {
+		return nil
+	}
+
 	return s.File.Close()
 }
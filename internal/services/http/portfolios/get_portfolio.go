@@ -0,0 +1,14 @@
+package portfolios
+
+import (
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+)
+
+func (s Service) GetPortfolio(exchange alor.Exchange, portfolio string) (alor.PortfolioSnapshot, error) {
+	state, err := s.brokerClient.GetPortfolio(exchange, portfolio)
+	if err != nil {
+		return alor.PortfolioSnapshot{}, err
+	}
+
+	return state.Snapshot(), nil
+}
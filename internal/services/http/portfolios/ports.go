@@ -0,0 +1,9 @@
+package portfolios
+
+import (
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+)
+
+type brokerClient interface {
+	GetPortfolio(exchange alor.Exchange, portfolio string) (*alor.PortfolioState, error)
+}
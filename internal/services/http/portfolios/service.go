@@ -0,0 +1,11 @@
+package portfolios
+
+type Service struct {
+	brokerClient brokerClient
+}
+
+func New(bc brokerClient) *Service {
+	return &Service{
+		brokerClient: bc,
+	}
+}
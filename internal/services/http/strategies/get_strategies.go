@@ -0,0 +1,9 @@
+package strategies
+
+import (
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+)
+
+func (s Service) GetStrategies() []alor.StrategySchema {
+	return s.strategyRegistry.Schemas()
+}
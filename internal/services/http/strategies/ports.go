@@ -0,0 +1,9 @@
+package strategies
+
+import (
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+)
+
+type strategyRegistry interface {
+	Schemas() []alor.StrategySchema
+}
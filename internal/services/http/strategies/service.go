@@ -0,0 +1,11 @@
+package strategies
+
+type Service struct {
+	strategyRegistry strategyRegistry
+}
+
+func New(sr strategyRegistry) *Service {
+	return &Service{
+		strategyRegistry: sr,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
 	"github.com/google/uuid"
 	"log"
+	"slices"
 	"time"
 )
 
@@ -16,6 +17,8 @@ type AddSubscriberParams struct {
 	Subscriptions Subscriptions `json:"subscriptions"`
 	Indicators    []Indicator   `json:"indicators"`
 	Async         bool          `json:"async"`
+	Mode          string        `json:"mode" validate:"omitempty,oneof=live paper"` // live (по умолчанию) или paper
+	Paper         *PaperParams  `json:"paper"`                                      // Настройки виртуального счёта для paper
 }
 
 type Instrument struct {
@@ -26,44 +29,162 @@ type Instrument struct {
 }
 
 type Strategy struct {
-	Name                 string          `json:"name"`
-	Settings             json.RawMessage `json:"settings"`
-	WithDelta            bool            `json:"withDelta"`
-	WithMarketProfile    bool            `json:"withMarketProfile"`
-	WithOrderBookProfile bool            `json:"withOrderBookProfile"`
+	Name                 string           `json:"name"`
+	Settings             json.RawMessage  `json:"settings"`
+	WithDelta            bool             `json:"withDelta"`
+	WithMarketProfile    bool             `json:"withMarketProfile"`
+	WithOrderBookProfile bool             `json:"withOrderBookProfile"`
+	Timeframes           []int64          `json:"timeframes" validate:"omitempty,dive,gt=0"` // Старшие таймфреймы из ленты сделок, кратные основному
+	BarType              string           `json:"barType" validate:"omitempty,oneof=time tick volume range renko dollar"`
+	BarSize              float64          `json:"barSize" validate:"gte=0"` // Сделок, лотов, пунктов цены или рублей оборота на бар
+	BarTimer             *BarTimerParams  `json:"barTimer"`                 // Закрывать бары по часам биржи, без него - сделкой следующего бара
+	Footprint            *FootprintParams `json:"footprint"`                // Кластеры закрытых баров, включают профиль
+	// Ряды производных баров, которые ведутся вместе с основными и отдаются GET .../bars?transform=
+	Transforms []string `json:"transforms" validate:"omitempty,dive,oneof=heikin_ashi log_returns normalized"`
+}
+
+// FootprintParams нулевые значения заменяются настройками по умолчанию
+type FootprintParams struct {
+	ImbalanceRatio   float64 `json:"imbalanceRatio" validate:"gte=0"`
+	StackedLevels    int     `json:"stackedLevels" validate:"gte=0"`
+	ValueArea        float64 `json:"valueArea" validate:"gte=0,lte=1"`
+	AbsorptionShare  float64 `json:"absorptionShare" validate:"gte=0,lte=1"`
+	AbsorptionVolume float64 `json:"absorptionVolume" validate:"gte=0"`
+	AbsorptionBars   int     `json:"absorptionBars" validate:"gte=0"`
+}
+
+type BarTimerParams struct {
+	Delay     int64  `json:"delay" validate:"gte=0"`                                 // Ожидание опоздавших сделок после конца бара, мс
+	EmptyBars string `json:"emptyBars" validate:"omitempty,oneof=skip fill_forward"` // Бары без сделок, по умолчанию skip
 }
 
 type Subscriptions struct {
 	AllTrades *AllTradesParams `json:"allTrades"`
 	OrderBook *OrderBookParams `json:"orderBook"`
 	Bars      *BarsParams      `json:"bars"`
+	Quotes    *QuotesParams    `json:"quotes"`
+	Portfolio *PortfolioParams `json:"portfolio"` // Позиции и риски счёта для стратегии
 }
 
 type AllTradesParams struct {
-	Frequency            int  `json:"frequency"`
-	Depth                int  `json:"depth"`
-	IncludeVirtualTrades bool `json:"includeVirtualTrades"`
+	Frequency            int    `json:"frequency"`
+	Depth                int    `json:"depth"`
+	IncludeVirtualTrades bool   `json:"includeVirtualTrades"`
+	Format               string `json:"format" validate:"omitempty,oneof=Simple Slim Heavy"` // Формат данных брокера, по умолчанию Slim
 }
 
 type OrderBookParams struct {
-	Frequency int `json:"frequency"`
-	Depth     int `json:"depth"`
+	Frequency int    `json:"frequency"`
+	Depth     int    `json:"depth"`
+	Format    string `json:"format" validate:"omitempty,oneof=Simple Slim Heavy"`
 }
 
 type BarsParams struct {
-	Frequency   int   `json:"frequency"`
-	From        int64 `json:"from"`
-	SkipHistory bool  `json:"skipHistory"`
-	SplitAdjust bool  `json:"splitAdjust"`
+	Frequency   int    `json:"frequency"`
+	From        int64  `json:"from"`
+	SkipHistory bool   `json:"skipHistory"`
+	SplitAdjust bool   `json:"splitAdjust"`
+	Format      string `json:"format" validate:"omitempty,oneof=Simple Slim Heavy"`
+}
+
+type QuotesParams struct {
+	Frequency int    `json:"frequency"`
+	Format    string `json:"format" validate:"omitempty,oneof=Simple Slim Heavy"`
+}
+
+type PortfolioParams struct {
+	Exchange     string `json:"exchange"`
+	Portfolio    string `json:"portfolio"`
+	SpectraRisks bool   `json:"spectraRisks"` // Риски срочного рынка, только для портфелей FORTS
+}
+
+// PaperParams настройки виртуального счёта. Сохраняются только они: после рестарта счёт
+// начинается заново с InitialCash, позиция, заявки и сделки прошлого запуска не восстанавливаются.
+type PaperParams struct {
+	InitialCash float64 `json:"initialCash" validate:"gte=0"`
+	Commission  float64 `json:"commission" validate:"gte=0"`
+	Slippage    float64 `json:"slippage" validate:"gte=0"`
+	LotSize     float64 `json:"lotSize" validate:"gte=0"`
 }
 
 type Indicator struct {
-	Name     string          `json:"name"`
+	Name     string          `json:"name"` // Имя, под которым значения лежат в баре
+	Type     string          `json:"type"` // Тип индикатора (sma, ema, rsi...), по умолчанию совпадает с Name
 	Settings json.RawMessage `json:"settings"`
 }
 
 func (s Service) AddSubscriber(ctx context.Context, params *AddSubscriberParams) (alor.SubscriberID, error) {
-	var options []alor.SubscriberOption
+	subscriber, err := s.addSubscriber(ctx, params)
+	if err != nil {
+		return alor.SubscriberID(uuid.Nil), err
+	}
+
+	if s.repository == nil {
+		return subscriber.ID, nil
+	}
+
+	saved, err := newSavedSubscriber(subscriber, params)
+	if err == nil {
+		err = s.repository.SaveSubscriber(ctx, saved)
+	}
+
+	if err != nil {
+		// Несохранённый робот не переживёт рестарт, поэтому не оставляем его работать
+		if removeErr := s.brokerClient.RemoveSubscriber(subscriber.ID); removeErr != nil {
+			log.Printf("subscriber %s not saved and not removed: %s", subscriber.ID, removeErr)
+		}
+
+		return alor.SubscriberID(uuid.Nil), err
+	}
+
+	return subscriber.ID, nil
+}
+
+// addSubscriber создаёт подписчика, прогоняет через него историю и подписывает на живые данные.
+// extra - опции восстановления (ID, состояние Storage), применяются последними.
+func (s Service) addSubscriber(ctx context.Context, params *AddSubscriberParams, extra ...alor.SubscriberOption) (subscriber *alor.Subscriber, err error) {
+	// Всё, что проверяется без брокера, проверяем до подписок: ошибка запроса не должна их оставлять.
+	// Подписка справочника на инструмент общая и живёт, пока работает клиент.
+	strategy, err := alor.NewStrategy(params.Strategy.Name, params.Strategy.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	transforms := make([]alor.SubscriberOption, 0, len(params.Strategy.Transforms))
+	for _, name := range params.Strategy.Transforms {
+		transform, err := alor.NewBarTransform(name)
+		if err != nil {
+			return nil, err
+		}
+
+		transforms = append(transforms, alor.WithBarTransform(name, transform))
+	}
+
+	indicators := make([]alor.SubscriberOption, 0, len(params.Indicators))
+	for _, indicator := range params.Indicators {
+		kind := indicator.Type
+		if kind == "" {
+			kind = indicator.Name
+		}
+
+		newIndicator, err := alor.NewIndicator(kind, indicator.Settings)
+		if err != nil {
+			return nil, err
+		}
+
+		indicators = append(indicators, alor.WithIndicator(indicator.Name, newIndicator))
+	}
+
+	// Неизвестный тикер или режим торгов отсекаем до загрузки истории
+	security, err := s.brokerClient.SubscribeSecurity(alor.Exchange(params.Instrument.Exchange), params.Instrument.Code, params.Instrument.Board)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []alor.SubscriberOption{
+		alor.WithCommandBus(s.brokerClient),
+		alor.WithSecurities(s.brokerClient.SecuritiesDirectory()),
+	}
 
 	if params.Strategy.WithDelta {
 		options = append(options, alor.WithDelta())
@@ -77,19 +198,117 @@ func (s Service) AddSubscriber(ctx context.Context, params *AddSubscriberParams)
 		options = append(options, alor.WithOrderBookProfile())
 	}
 
+	if params.Strategy.BarType != "" {
+		builder, err := alor.NewBarBuilder(alor.BarType(params.Strategy.BarType), params.Strategy.BarSize, alor.Timeframe(params.Instrument.Timeframe), security.LotSize)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, alor.WithBarBuilder(builder))
+	}
+
+	if timer := params.Strategy.BarTimer; timer != nil {
+		options = append(options, alor.WithBarTimer(alor.BarTimerConfig{
+			Delay:     time.Duration(timer.Delay) * time.Millisecond,
+			EmptyBars: alor.EmptyBarPolicy(timer.EmptyBars),
+		}))
+	}
+
+	if footprint := params.Strategy.Footprint; footprint != nil {
+		options = append(options, alor.WithFootprint(alor.FootprintConfig{
+			ImbalanceRatio:   footprint.ImbalanceRatio,
+			StackedLevels:    footprint.StackedLevels,
+			ValueArea:        footprint.ValueArea,
+			AbsorptionShare:  footprint.AbsorptionShare,
+			AbsorptionVolume: footprint.AbsorptionVolume,
+			AbsorptionBars:   footprint.AbsorptionBars,
+		}))
+	}
+
+	options = append(options, transforms...)
+
+	if len(params.Strategy.Timeframes) != 0 {
+		timeframes := make([]alor.Timeframe, 0, len(params.Strategy.Timeframes))
+		for _, timeframe := range params.Strategy.Timeframes {
+			timeframes = append(timeframes, alor.Timeframe(timeframe))
+		}
+
+		options = append(options, alor.WithTimeframes(timeframes...))
+	}
+
+	options = append(options, indicators...)
+
+	// Бумажный режим подменяет шину команд виртуальным брокером
+	if alor.SubscriberMode(params.Mode) == alor.PaperSubscriberMode {
+		var paper PaperParams
+		if params.Paper != nil {
+			paper = *params.Paper
+		}
+
+		options = append(options, alor.WithPaperTrading(alor.PaperTradingConfig{
+			InitialCash: paper.InitialCash,
+			Commission:  paper.Commission,
+			Slippage:    paper.Slippage,
+			LotSize:     paper.LotSize,
+		}))
+	}
+
 	if params.Subscriptions.AllTrades != nil {
-		options = append(options, alor.WithAllTradesSubscription(params.Subscriptions.AllTrades.Frequency, 50, false))
+		options = append(options, alor.WithAllTradesSubscription(params.Subscriptions.AllTrades.Frequency, 50, false, responseFormat(params.Subscriptions.AllTrades.Format)...))
 	}
 
 	if params.Subscriptions.OrderBook != nil {
-		options = append(options, alor.WithOrderBookSubscription(params.Subscriptions.OrderBook.Frequency, 10))
+		options = append(options, alor.WithOrderBookSubscription(params.Subscriptions.OrderBook.Frequency, 10, responseFormat(params.Subscriptions.OrderBook.Format)...))
 	}
 
 	if params.Subscriptions.Bars != nil {
-		options = append(options, alor.WithBarsSubscription(params.Subscriptions.Bars.Frequency, 10, params.Subscriptions.Bars.SkipHistory, params.Subscriptions.Bars.SplitAdjust))
+		options = append(options, alor.WithBarsSubscription(params.Subscriptions.Bars.Frequency, 10, params.Subscriptions.Bars.SkipHistory, params.Subscriptions.Bars.SplitAdjust, responseFormat(params.Subscriptions.Bars.Format)...))
 	}
 
-	subscriber := alor.NewSubscriber(
+	if portfolio := params.Subscriptions.Portfolio; portfolio != nil {
+		opcodes := slices.Clone(alor.PortfolioOpcodes)
+		if portfolio.SpectraRisks {
+			opcodes = append(opcodes, alor.SpectralRisksOpcode)
+		}
+
+		// Живой стратегии нужны статусы и исполнения её заявок
+		if alor.SubscriberMode(params.Mode) != alor.PaperSubscriberMode {
+			opcodes = append(opcodes, alor.OrdersOpcodes...)
+		}
+
+		// Подписка общая для всех подписчиков портфеля, повторно не создаётся
+		_, lookupErr := s.brokerClient.GetPortfolio(alor.Exchange(portfolio.Exchange), portfolio.Portfolio)
+		opened := lookupErr != nil
+
+		state, subscribeErr := s.brokerClient.SubscribePortfolio(alor.Exchange(portfolio.Exchange), portfolio.Portfolio, opcodes...)
+		if subscribeErr != nil {
+			return nil, subscribeErr
+		}
+
+		// Портфель, который открыли для этого подписчика, при ошибке дальше закрываем.
+		// err - результат addSubscriber, его выставляет любой return с ошибкой.
+		if opened {
+			defer func() {
+				if err == nil {
+					return
+				}
+
+				if unsubscribeErr := s.brokerClient.UnsubscribePortfolio(alor.Exchange(portfolio.Exchange), portfolio.Portfolio); unsubscribeErr != nil {
+					log.Printf("portfolio %s not unsubscribed: %s", portfolio.Portfolio, unsubscribeErr)
+				}
+			}()
+		}
+
+		options = append(options, alor.WithPortfolio(state))
+	}
+
+	if params.Subscriptions.Quotes != nil {
+		options = append(options, alor.WithQuotesSubscription(params.Subscriptions.Quotes.Frequency, responseFormat(params.Subscriptions.Quotes.Format)...))
+	}
+
+	options = append(options, extra...)
+
+	subscriber = alor.NewSubscriber(
 		params.Description,
 		alor.Exchange(params.Instrument.Exchange),
 		params.Instrument.Code,
@@ -99,16 +318,8 @@ func (s Service) AddSubscriber(ctx context.Context, params *AddSubscriberParams)
 		options...,
 	)
 
-	strategy, err := alor.NewStrategy(params.Strategy.Name, params.Strategy.Settings)
-	if err != nil {
-		return alor.SubscriberID{}, err
-	}
-
 	subscriber.SetStrategy(strategy)
 
-	// TODO: Add indicators
-	// for ...
-
 	// GET данные прошлых сессий
 	// Отправляем все данные в подписчика ====>
 
@@ -132,7 +343,7 @@ func (s Service) AddSubscriber(ctx context.Context, params *AddSubscriberParams)
 		log.Println("get data for ", subscriber.ID, " offset ", historyParams.Offset)
 		events, err := s.brokerClient.GetAllTrades(historyParams)
 		if err != nil {
-			return subscriber.ID, err
+			return nil, err
 		}
 
 		if len(events) == 0 {
@@ -145,7 +356,7 @@ func (s Service) AddSubscriber(ctx context.Context, params *AddSubscriberParams)
 		for _, event := range events {
 			// TODO: HANDLE UNIVESAL
 			if err := subscriber.HandleHistoryAlltrades(event); err != nil {
-				return subscriber.ID, err
+				return nil, err
 
 			}
 		}
@@ -158,10 +369,19 @@ func (s Service) AddSubscriber(ctx context.Context, params *AddSubscriberParams)
 
 	// Начинаем получать новые события
 	if err := s.brokerClient.AddSubscriber(subscriber); err != nil {
-		return alor.SubscriberID(uuid.Nil), err
+		return nil, err
 	}
 
 	log.Printf("subscriber %s successfully added", subscriber.ID)
 
-	return subscriber.ID, nil
+	return subscriber, nil
+}
+
+// responseFormat опции подписки для формата из запроса, пустой формат - Slim по умолчанию
+func responseFormat(format string) []alor.SubscriptionOption {
+	if format == "" {
+		return nil
+	}
+
+	return []alor.SubscriptionOption{alor.WithResponseFormat(alor.ResponseFormat(format))}
 }
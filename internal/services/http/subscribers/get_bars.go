@@ -6,12 +6,27 @@ import (
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
 )
 
-func (s Service) GetSubscriberBars(subscriberID alor.SubscriberID, heikenAshi bool) ([]*alor.Bar, error) {
-	bars, err := s.brokerClient.GetAllSubscriberBars(subscriberID)
+// GetSubscriberBars бары подписчика, с transform - производные (heikin_ashi, log_returns, normalized)
+func (s Service) GetSubscriberBars(subscriberID alor.SubscriberID, transform string) ([]*alor.Bar, error) {
+	var (
+		bars []*alor.Bar
+		err  error
+	)
+
+	if transform == "" {
+		bars, err = s.brokerClient.GetAllSubscriberBars(subscriberID)
+	} else {
+		bars, err = s.brokerClient.GetTransformedSubscriberBars(subscriberID, transform)
+	}
+
 	if err != nil {
 		if errors.Is(err, alor.ErrSubscriberNotFound) {
 			return nil, domain.ErrSubscriberNotFound
 		}
+
+		if errors.Is(err, alor.ErrUnknownBarTransform) {
+			return nil, err
+		}
 	}
 
 	return bars, nil
@@ -0,0 +1,21 @@
+package subscribers
+
+import (
+	"errors"
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+)
+
+// GetSubscriberFootprints кластеры последних count баров подписчика, timeframe 0 - основной таймфрейм
+func (s Service) GetSubscriberFootprints(subscriberID alor.SubscriberID, timeframe int64, count int) ([]alor.BarFootprint, error) {
+	footprints, err := s.brokerClient.GetSubscriberFootprints(subscriberID, alor.Timeframe(timeframe), count)
+	if err != nil {
+		if errors.Is(err, alor.ErrSubscriberNotFound) {
+			return nil, domain.ErrSubscriberNotFound
+		}
+
+		return nil, err
+	}
+
+	return footprints, nil
+}
@@ -1,14 +1,30 @@
 package subscribers
 
 import (
+	"context"
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"github.com/google/uuid"
 )
 
 type brokerClient interface {
+	alor.CommandBus
 	GetSubscribers() []*alor.Subscriber
 	GetAllSubscriberBars(subscriberID alor.SubscriberID) ([]*alor.Bar, error)
+	GetTransformedSubscriberBars(subscriberID alor.SubscriberID, transform string) ([]*alor.Bar, error)
+	GetSubscriberFootprints(subscriberID alor.SubscriberID, timeframe alor.Timeframe, count int) ([]alor.BarFootprint, error)
 	AddSubscriber(subscriber *alor.Subscriber) error
 	RemoveSubscriber(subscriberID alor.SubscriberID) error
 	GetAllTrades(params alor.GetAllTradesV2Params) ([]alor.AllTradesSlimData, error)
 	GetSubscriber(subscriberID alor.SubscriberID) (*alor.Subscriber, error)
+	SubscribePortfolio(exchange alor.Exchange, portfolio string, opcodes ...alor.Opcode) (*alor.PortfolioState, error)
+	GetPortfolio(exchange alor.Exchange, portfolio string) (*alor.PortfolioState, error)
+	UnsubscribePortfolio(exchange alor.Exchange, portfolio string) error
+	SubscribeSecurity(exchange alor.Exchange, code string, board string) (alor.Security, error)
+	SecuritiesDirectory() *alor.Securities
+}
+
+type subscribersRepository interface {
+	SaveSubscriber(ctx context.Context, subscriber domain.Subscriber) error
+	DeleteSubscriber(ctx context.Context, subscriberID uuid.UUID) error
 }
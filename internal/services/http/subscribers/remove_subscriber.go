@@ -1,9 +1,27 @@
 package subscribers
 
 import (
+	"context"
+	"errors"
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
 	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"github.com/google/uuid"
 )
 
-func (s Service) RemoveSubscriber(subscriberID alor.SubscriberID) error { // FromAlltrades
-	return s.brokerClient.RemoveSubscriber(subscriberID)
+func (s Service) RemoveSubscriber(ctx context.Context, subscriberID alor.SubscriberID) error { // FromAlltrades
+	if err := s.brokerClient.RemoveSubscriber(subscriberID); err != nil {
+		return err
+	}
+
+	if s.repository == nil {
+		return nil
+	}
+
+	// Удалённый подписчик не должен подняться после рестарта
+	err := s.repository.DeleteSubscriber(ctx, uuid.UUID(subscriberID))
+	if errors.Is(err, domain.ErrSubscriberNotFound) {
+		return nil
+	}
+
+	return err
 }
@@ -0,0 +1,149 @@
+package subscribers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"github.com/google/uuid"
+	"log"
+)
+
+// strategyDetailing детализация, тип и таймфреймы баров из Strategy, сохраняются одним полем
+type strategyDetailing struct {
+	WithDelta            bool             `json:"withDelta"`
+	WithMarketProfile    bool             `json:"withMarketProfile"`
+	WithOrderBookProfile bool             `json:"withOrderBookProfile"`
+	Timeframes           []int64          `json:"timeframes,omitempty"`
+	BarType              string           `json:"barType,omitempty"`
+	BarSize              float64          `json:"barSize,omitempty"`
+	BarTimer             *BarTimerParams  `json:"barTimer,omitempty"`
+	Footprint            *FootprintParams `json:"footprint,omitempty"`
+	Transforms           []string         `json:"transforms,omitempty"`
+}
+
+// RestoreSubscriber пересоздаёт сохранённого подписчика с прежним ID и состоянием Storage
+func (s Service) RestoreSubscriber(ctx context.Context, saved domain.Subscriber) error {
+	params := &AddSubscriberParams{
+		Description: saved.Description,
+		Instrument: Instrument{
+			Exchange:  saved.Exchange,
+			Code:      saved.Code,
+			Board:     saved.Board,
+			Timeframe: saved.Timeframe,
+		},
+		Strategy: Strategy{
+			Name:     saved.StrategyName,
+			Settings: saved.StrategySettings,
+		},
+		Async: saved.Async,
+		Mode:  saved.Mode,
+	}
+
+	var detailing strategyDetailing
+	if err := unmarshalSaved(saved.Detailing, &detailing); err != nil {
+		return err
+	}
+
+	params.Strategy.WithDelta = detailing.WithDelta
+	params.Strategy.WithMarketProfile = detailing.WithMarketProfile
+	params.Strategy.WithOrderBookProfile = detailing.WithOrderBookProfile
+	params.Strategy.Timeframes = detailing.Timeframes
+	params.Strategy.BarType = detailing.BarType
+	params.Strategy.BarSize = detailing.BarSize
+	params.Strategy.BarTimer = detailing.BarTimer
+	params.Strategy.Footprint = detailing.Footprint
+	params.Strategy.Transforms = detailing.Transforms
+
+	if err := unmarshalSaved(saved.Subscriptions, &params.Subscriptions); err != nil {
+		return err
+	}
+
+	if err := unmarshalSaved(saved.Indicators, &params.Indicators); err != nil {
+		return err
+	}
+
+	if err := unmarshalSaved(saved.Paper, &params.Paper); err != nil {
+		return err
+	}
+
+	// Состояние виртуального счёта не хранится, бумажный робот начинает с начального капитала
+	if alor.SubscriberMode(params.Mode) == alor.PaperSubscriberMode {
+		log.Printf("subscriber %s restored in paper mode, virtual account starts over", saved.ID)
+	}
+
+	options := []alor.SubscriberOption{
+		alor.WithSubscriberID(alor.SubscriberID(saved.ID)),
+	}
+
+	if len(saved.Storage) != 0 {
+		storage := &alor.Storage{}
+		if err := json.Unmarshal(saved.Storage, storage); err != nil {
+			return err
+		}
+
+		options = append(options, alor.WithStorage(storage))
+	}
+
+	_, err := s.addSubscriber(ctx, params, options...)
+
+	return err
+}
+
+func newSavedSubscriber(subscriber *alor.Subscriber, params *AddSubscriberParams) (domain.Subscriber, error) {
+	saved := domain.Subscriber{
+		ID:               uuid.UUID(subscriber.ID),
+		Description:      subscriber.Description,
+		CreatedAt:        subscriber.CreatedAt,
+		Exchange:         string(subscriber.Exchange),
+		Code:             subscriber.Code,
+		Board:            subscriber.Board,
+		Timeframe:        int64(subscriber.Timeframe),
+		Async:            subscriber.Async,
+		Mode:             string(subscriber.Mode),
+		StrategyName:     params.Strategy.Name,
+		StrategySettings: params.Strategy.Settings,
+	}
+
+	var err error
+
+	if saved.Detailing, err = json.Marshal(strategyDetailing{
+		WithDelta:            params.Strategy.WithDelta,
+		WithMarketProfile:    params.Strategy.WithMarketProfile,
+		WithOrderBookProfile: params.Strategy.WithOrderBookProfile,
+		Timeframes:           params.Strategy.Timeframes,
+		BarType:              params.Strategy.BarType,
+		BarSize:              params.Strategy.BarSize,
+		BarTimer:             params.Strategy.BarTimer,
+		Footprint:            params.Strategy.Footprint,
+		Transforms:           params.Strategy.Transforms,
+	}); err != nil {
+		return saved, err
+	}
+
+	if saved.Subscriptions, err = json.Marshal(params.Subscriptions); err != nil {
+		return saved, err
+	}
+
+	if saved.Indicators, err = json.Marshal(params.Indicators); err != nil {
+		return saved, err
+	}
+
+	if saved.Paper, err = json.Marshal(params.Paper); err != nil {
+		return saved, err
+	}
+
+	if saved.Storage, err = json.Marshal(subscriber.Storage); err != nil {
+		return saved, err
+	}
+
+	return saved, nil
+}
+
+func unmarshalSaved(data json.RawMessage, value any) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, value)
+}
@@ -2,10 +2,13 @@ package subscribers
 
 type Service struct {
 	brokerClient brokerClient
+	repository   subscribersRepository
 }
 
-func New(bc brokerClient) *Service {
+// New repository может быть nil, тогда подписчики живут только в памяти
+func New(bc brokerClient, repository subscribersRepository) *Service {
 	return &Service{
 		brokerClient: bc,
+		repository:   repository,
 	}
 }
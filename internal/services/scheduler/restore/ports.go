@@ -0,0 +1,18 @@
+package restore
+
+import (
+	"context"
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"github.com/google/uuid"
+)
+
+type repository interface {
+	GetActiveSubscribers(ctx context.Context) ([]domain.Subscriber, error)
+	UpdateSubscriberStorage(ctx context.Context, subscriberID uuid.UUID, storage []byte) error
+}
+
+type subscribersService interface {
+	RestoreSubscriber(ctx context.Context, saved domain.Subscriber) error
+	GetSubscribers() []*alor.Subscriber
+}
@@ -1 +1,67 @@
 package restore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/MarlyasDad/rd-hub-go/internal/domain"
+	"github.com/google/uuid"
+	"log/slog"
+)
+
+// Service поднимает сохранённых подписчиков после рестарта и периодически сохраняет их Storage
+type Service struct {
+	repository  repository
+	subscribers subscribersService
+}
+
+func New(repository repository, subscribers subscribersService) *Service {
+	return &Service{
+		repository:  repository,
+		subscribers: subscribers,
+	}
+}
+
+// Restore пересоздаёт и переподписывает всех активных подписчиков.
+// Ошибка одного подписчика не мешает остальным, ошибки возвращаются вместе.
+func (s *Service) Restore(ctx context.Context) error {
+	saved, err := s.repository.GetActiveSubscribers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, subscriber := range saved {
+		if err := s.subscribers.RestoreSubscriber(ctx, subscriber); err != nil {
+			slog.Error("subscriber restore failed", slog.String("id", subscriber.ID.String()), slog.Any("error", err))
+			errs = append(errs, err)
+			continue
+		}
+
+		slog.Info("subscriber restored", slog.String("id", subscriber.ID.String()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SaveState сохраняет Storage всех работающих подписчиков
+func (s *Service) SaveState(ctx context.Context) error {
+	var errs []error
+
+	for _, subscriber := range s.subscribers.GetSubscribers() {
+		storage, err := json.Marshal(subscriber.Storage)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		err = s.repository.UpdateSubscriberStorage(ctx, uuid.UUID(subscriber.ID), storage)
+		// Подписчики без сохранения (например, созданные без БД) пропускаем
+		if err != nil && !errors.Is(err, domain.ErrSubscriberNotFound) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
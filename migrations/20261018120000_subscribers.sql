@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE subscribers (
+    id UUID PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    client_id BIGINT NOT NULL DEFAULT 0,
+    exchange TEXT NOT NULL,
+    code TEXT NOT NULL,
+    board TEXT NOT NULL,
+    timeframe BIGINT NOT NULL,
+    async BOOLEAN NOT NULL DEFAULT FALSE,
+    mode TEXT NOT NULL DEFAULT 'live',
+    strategy_name TEXT NOT NULL DEFAULT '',
+    strategy_settings JSONB NULL,
+    detailing JSONB NULL,
+    subscriptions JSONB NOT NULL,
+    indicators JSONB NULL,
+    paper JSONB NULL,
+    storage JSONB NULL
+);
+
+CREATE INDEX subscribers_active_idx ON subscribers (created_at) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS subscribers CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,180 @@
+package alor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// backfillPageSize сколько сделок запрашивать за раз при заполнении пропуска
+const backfillPageSize = 5000
+
+// AllTradesBackfillOpcode событие с догруженными сделками, приходит подписчику тем же путём, что и данные брокера
+const AllTradesBackfillOpcode Opcode = "AllTradesBackfill"
+
+// AllTradesHistory загружает сделки из истории, подходит Client.GetAllTrades
+type AllTradesHistory func(params GetAllTradesV2Params) ([]AllTradesSlimData, error)
+
+// WithAllTradesHistory откуда брать сделки, пропущенные между разрывом и переподпиской.
+// Без опции пул подставляет REST клиента.
+func WithAllTradesHistory(history AllTradesHistory) SubscriberOption {
+	return func(s *Subscriber) {
+		s.tradesHistory = history
+	}
+}
+
+// allTradesBackfill загруженный пропуск, Error - почему загружен не весь
+type allTradesBackfill struct {
+	Trades []AllTradesSlimData `json:"trades"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// resumedTrades подписки на сделки, по которым после подписки ещё не пришло ни одной сделки.
+// Первая сделка после (пере)подписки помечается, подписчик сверяет её с последней обработанной.
+type resumedTrades struct {
+	list map[GUID]bool
+	mu   sync.Mutex
+}
+
+func newResumedTrades() *resumedTrades {
+	return &resumedTrades{
+		list: make(map[GUID]bool),
+	}
+}
+
+func (r *resumedTrades) mark(guid GUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.list[guid] = true
+}
+
+// take true для первого события подписки после mark
+func (r *resumedTrades) take(guid GUID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.list[guid] {
+		return false
+	}
+
+	delete(r.list, guid)
+
+	return true
+}
+
+// startBackfill при пропуске между последней обработанной сделкой и first запускает его загрузку в фоне.
+// Сетевые запросы не держат разбор соединения: пока идёт загрузка, живые сделки подписчика копятся в pendingTrades.
+// false - пропуска нет или догружать неоткуда, first обрабатывается сразу.
+func (s *Subscriber) startBackfill(first AllTradesSlimData) bool {
+	lastID := s.DataProcessor.lastAlltradesID
+	if s.tradesHistory == nil || lastID == 0 || first.ID <= lastID+1 {
+		return false
+	}
+
+	s.backfilling = true
+	s.pendingTrades = append(s.pendingTrades[:0], first)
+
+	s.wg.Add(1)
+	go s.runBackfill(lastID+1, first.ID-1)
+
+	return true
+}
+
+// runBackfill загружает пропуск и отдаёт его подписчику событием, как таймер баров
+func (s *Subscriber) runBackfill(fromID, toID int64) {
+	defer s.wg.Done()
+
+	trades, err := s.fetchAllTrades(fromID, toID)
+
+	result := allTradesBackfill{Trades: trades}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = s.injectEvent(&ChainEvent{Type: BackfillType, Opcode: AllTradesBackfillOpcode, Data: data})
+	}
+
+	if err != nil {
+		s.fail(err)
+		log.Println(s.ID, "error in backfill:", err)
+	}
+}
+
+// fetchAllTrades сделки fromID-toID по страницам. При ошибке возвращает то, что успело загрузиться.
+func (s *Subscriber) fetchAllTrades(fromID, toID int64) ([]AllTradesSlimData, error) {
+	var result []AllTradesSlimData
+
+	for fromID <= toID {
+		trades, err := s.tradesHistory(GetAllTradesV2Params{
+			Exchange:     s.Exchange,
+			Symbol:       s.Code,
+			Board:        s.Board,
+			JsonResponse: true,
+			FromID:       &fromID,
+			ToID:         &toID,
+			Take:         backfillPageSize,
+		})
+		if err != nil {
+			return result, fmt.Errorf("all trades %d-%d: %w", fromID, toID, err)
+		}
+
+		for _, trade := range trades {
+			if trade.ID < fromID || trade.ID > toID {
+				continue
+			}
+
+			result = append(result, trade)
+		}
+
+		if len(trades) < backfillPageSize {
+			return result, nil
+		}
+
+		fromID = trades[len(trades)-1].ID + 1
+	}
+
+	return result, nil
+}
+
+// handleAllTradesBackfill проводит догруженные сделки через DataProcessor по порядку, чтобы дельта и профиль остались точными,
+// затем накопленные за время загрузки живые сделки.
+// Стратегия получает закрытые на пропуске бары, но не сами сделки: по ним уже нельзя торговать.
+func (s *Subscriber) handleAllTradesBackfill(event *ChainEvent) error {
+	var result allTradesBackfill
+	if err := json.Unmarshal(event.Data, &result); err != nil {
+		return err
+	}
+
+	if result.Error != "" {
+		log.Printf("subscriber %s backfill failed, trades are missing: %s", s.ID, result.Error)
+	}
+
+	for _, trade := range result.Trades {
+		if err := s.DataProcessor.NewAllTrades(trade); err != nil {
+			return err
+		}
+
+		if err := s.handleClosedBar(TradeBarClose); err != nil {
+			return err
+		}
+	}
+
+	pending := s.pendingTrades
+	s.backfilling, s.pendingTrades = false, nil
+
+	for _, trade := range pending {
+		if s.DataProcessor.seenTrade(trade.ID) {
+			continue
+		}
+
+		if err := s.handleAllTrades(trade); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package alor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriberBackfillDoesNotBlockHandler(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	history := func(params GetAllTradesV2Params) ([]AllTradesSlimData, error) {
+		<-release
+
+		trades := make([]AllTradesSlimData, 0, 2)
+		for id := *params.FromID; id <= *params.ToID; id++ {
+			trades = append(trades, AllTradesSlimData{ID: id, Price: 100, Qty: id, Timestamp: 1_000 + id, Side: BuySide})
+		}
+
+		return trades, nil
+	}
+
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false,
+		WithAllTradesSubscription(10, 0, false),
+		WithAllTradesHistory(history),
+	)
+	t.Cleanup(subscriber.Stop)
+
+	trade := func(id int64) AllTradesSlimData {
+		return AllTradesSlimData{ID: id, Price: 100, Qty: id, Timestamp: 1_000 + id, Side: BuySide}
+	}
+
+	require.NoError(t, subscriber.HandleEvent(newAllTradesEvent(t, trade(1))))
+
+	// Сделки 2-3 пропущены, история отвечает не сразу, а обработчик соединения не ждёт её
+	resumed := newAllTradesEvent(t, trade(4))
+	resumed.Resumed = true
+	require.NoError(t, subscriber.HandleEvent(resumed))
+	require.NoError(t, subscriber.HandleEvent(newAllTradesEvent(t, trade(5))))
+
+	volume := func() (int64, int64) {
+		subscriber.handleMu.Lock()
+		defer subscriber.handleMu.Unlock()
+
+		bar, err := subscriber.DataProcessor.GetLastBar()
+		require.NoError(t, err)
+
+		return subscriber.DataProcessor.lastAlltradesID, bar.Volume
+	}
+
+	lastID, barVolume := volume()
+	require.EqualValues(t, 1, lastID)
+	require.EqualValues(t, 1, barVolume)
+
+	close(release)
+
+	// Пропуск проведён раньше накопленных живых сделок: объём бара 1+2+3+4+5
+	require.Eventually(t, func() bool {
+		lastID, barVolume := volume()
+		return lastID == 5 && barVolume == 15
+	}, 5*time.Second, 10*time.Millisecond)
+}
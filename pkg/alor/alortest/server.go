@@ -0,0 +1,570 @@
+// Package alortest локальный поддельный брокер Alor для интеграционных тестов pkg/alor.
+// Отдаёт /refresh, REST md/v2 и вебсокет с подписками в формате Slim по заранее заданному сценарию.
+package alortest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// Request запрос, пришедший в вебсокет
+type Request struct {
+	Opcode          alor.Opcode         `json:"opcode"`
+	Guid            alor.GUID           `json:"guid"`
+	Token           string              `json:"token"`
+	Exchange        alor.Exchange       `json:"exchange"`
+	Code            string              `json:"code"`
+	Portfolio       string              `json:"portfolio"`
+	InstrumentGroup string              `json:"instrumentGroup"`
+	Format          alor.ResponseFormat `json:"format"`
+	Tf              alor.Timeframe      `json:"tf"`
+	Depth           int                 `json:"depth"`
+}
+
+// key инструмент для рыночных данных или портфель для данных счёта
+func (r Request) key() string {
+	if r.Portfolio != "" {
+		return r.Portfolio
+	}
+
+	return r.Code
+}
+
+type subscription struct {
+	request Request
+	conn    *conn
+}
+
+type conn struct {
+	ws      *websocket.Conn
+	mu      sync.Mutex  // gorilla/websocket не допускает параллельную запись
+	stalled atomic.Bool // Не отвечает на ping, как полуоткрытое соединение
+}
+
+func (c *conn) write(message any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ws.WriteJSON(message)
+}
+
+type subscribeError struct {
+	httpCode int
+	message  string
+}
+
+// Server поддельный брокер. Все методы безопасны для вызова из теста параллельно с клиентом.
+type Server struct {
+	server         *httptest.Server
+	upgrader       websocket.Upgrader
+	mu             sync.Mutex
+	accessTTL      time.Duration
+	issued         map[string]bool
+	refreshToken   string
+	refreshStatus  []int
+	time           int64
+	allTrades      map[string][]alor.AllTradesSlimData
+	securities     map[alor.Exchange][]alor.Security
+	history        map[alor.Opcode]map[string][]any
+	subscribeFails map[alor.Opcode][]subscribeError
+	conns          map[*conn]bool
+	subscriptions  map[alor.GUID]subscription
+	requests       []Request
+	ackDelay       time.Duration
+	acks           sync.WaitGroup // Отложенные ответы на подписку
+	acksPending    int
+	acksPendingMax int
+}
+
+// NewServer запускает брокера, принимающего refresh токен refreshToken
+func NewServer(refreshToken string) *Server {
+	s := &Server{
+		accessTTL:      30 * time.Minute,
+		issued:         make(map[string]bool),
+		refreshToken:   refreshToken,
+		time:           time.Now().Unix(),
+		allTrades:      make(map[string][]alor.AllTradesSlimData),
+		securities:     make(map[alor.Exchange][]alor.Security),
+		history:        make(map[alor.Opcode]map[string][]any),
+		subscribeFails: make(map[alor.Opcode][]subscribeError),
+		conns:          make(map[*conn]bool),
+		subscriptions:  make(map[alor.GUID]subscription),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /refresh", s.handleRefresh)
+	mux.HandleFunc("GET /md/v2/time", s.handleTime)
+	mux.HandleFunc("GET /md/v2/Securities/{exchange}", s.handleSecurities)
+	mux.HandleFunc("GET /md/v2/Securities/{exchange}/{symbol}/alltrades", s.handleAllTrades)
+	mux.HandleFunc("/ws", s.handleWebsocket)
+
+	s.server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Hosts адреса для alor.Config.Hosts
+func (s *Server) Hosts() alor.Hosts {
+	return alor.Hosts{
+		Authorization: s.server.URL,
+		Data:          s.server.URL,
+		Websocket:     "ws" + strings.TrimPrefix(s.server.URL, "http") + "/ws",
+	}
+}
+
+// Close рвёт все соединения и останавливает сервер
+func (s *Server) Close() {
+	s.Disconnect()
+	s.acks.Wait()
+	s.server.Close()
+}
+
+// SetAccessTokenTTL время жизни выдаваемых access токенов
+func (s *Server) SetAccessTokenTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accessTTL = ttl
+}
+
+// FailRefresh следующие обновления токена вернут эти HTTP коды, по одному на запрос
+func (s *Server) FailRefresh(statusCodes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshStatus = append(s.refreshStatus, statusCodes...)
+}
+
+// SetTime ответ /md/v2/time
+func (s *Server) SetTime(timestamp int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.time = timestamp
+}
+
+// SetAllTrades история сделок инструмента для REST /alltrades
+func (s *Server) SetAllTrades(symbol string, trades []alor.AllTradesSlimData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.allTrades[symbol] = trades
+}
+
+// SetSecurities справочник инструментов биржи для REST /Securities
+func (s *Server) SetSecurities(exchange alor.Exchange, securities ...alor.Security) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.securities[exchange] = securities
+}
+
+// SetHistory данные, которые уходят в подписку сразу после подтверждения (история перед живыми данными).
+// code - тикер, а для подписок на данные счёта - портфель.
+func (s *Server) SetHistory(opcode alor.Opcode, code string, data ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.history[opcode] == nil {
+		s.history[opcode] = make(map[string][]any)
+	}
+
+	s.history[opcode][code] = data
+}
+
+// FailSubscribe следующая подписка с этим опкодом получит ответ с ошибкой и не будет создана
+func (s *Server) FailSubscribe(opcode alor.Opcode, httpCode int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribeFails[opcode] = append(s.subscribeFails[opcode], subscribeError{httpCode: httpCode, message: message})
+}
+
+// DelayAcks следующие подписки получат ответ через delay, соединение тем временем читает дальше
+func (s *Server) DelayAcks(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ackDelay = delay
+}
+
+// MaxPendingAcks наибольшее число подписок, одновременно ждавших отложенного ответа
+func (s *Server) MaxPendingAcks() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.acksPendingMax
+}
+
+// Publish отправляет живые данные во все подписки на инструмент или портфель
+func (s *Server) Publish(opcode alor.Opcode, code string, data any) error {
+	s.mu.Lock()
+	targets := make([]subscription, 0)
+	for _, item := range s.subscriptions {
+		if item.request.Opcode == opcode && item.request.key() == code {
+			targets = append(targets, item)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, target := range targets {
+		if err := target.conn.write(dataMessage(target.request.Guid, data)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Disconnect рвёт все вебсокет соединения без закрывающего сообщения, подписки пропадают
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		_ = c.ws.Close()
+	}
+}
+
+// Stall перестаёт отвечать на ping в открытых соединениях, сами соединения не закрываются
+func (s *Server) Stall() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		c.stalled.Store(true)
+	}
+}
+
+// Connections количество открытых вебсокет соединений
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Subscriptions действующие подписки
+func (s *Server) Subscriptions() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, 0, len(s.subscriptions))
+	for _, item := range s.subscriptions {
+		requests = append(requests, item.request)
+	}
+
+	return requests
+}
+
+// Requests все запросы, пришедшие в вебсокет
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if len(s.refreshStatus) > 0 {
+		status := s.refreshStatus[0]
+		s.refreshStatus = s.refreshStatus[1:]
+		s.mu.Unlock()
+
+		w.WriteHeader(status)
+		return
+	}
+
+	if r.URL.Query().Get("token") != s.refreshToken {
+		s.mu.Unlock()
+
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	access, err := newAccessToken(time.Now().Add(s.accessTTL))
+	if err == nil {
+		s.issued[access] = true
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{"AccessToken": access})
+}
+
+func (s *Server) handleTime(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r.Header.Get("Authorization")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	timestamp := s.time
+	s.mu.Unlock()
+
+	_, _ = w.Write([]byte(strconv.FormatInt(timestamp, 10)))
+}
+
+func (s *Server) handleSecurities(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r.Header.Get("Authorization")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	securities := append([]alor.Security{}, s.securities[alor.Exchange(r.PathValue("exchange"))]...)
+	s.mu.Unlock()
+
+	writeJSON(w, securities)
+}
+
+func (s *Server) handleAllTrades(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r.Header.Get("Authorization")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	take, _ := strconv.Atoi(query.Get("take"))
+
+	s.mu.Lock()
+	trades := s.allTrades[r.PathValue("symbol")]
+	s.mu.Unlock()
+
+	// fromID и toID включительно, как у брокера
+	if query.Has("fromID") || query.Has("toID") {
+		fromID, _ := strconv.ParseInt(query.Get("fromID"), 10, 64)
+		toID, err := strconv.ParseInt(query.Get("toID"), 10, 64)
+		if err != nil {
+			toID = math.MaxInt64
+		}
+
+		filtered := make([]alor.AllTradesSlimData, 0, len(trades))
+		for _, trade := range trades {
+			if trade.ID >= fromID && trade.ID <= toID {
+				filtered = append(filtered, trade)
+			}
+		}
+		trades = filtered
+	}
+
+	offset = min(offset, len(trades))
+	end := len(trades)
+	if take > 0 {
+		end = min(offset+take, len(trades))
+	}
+
+	writeJSON(w, append([]alor.AllTradesSlimData{}, trades[offset:end]...))
+}
+
+func (s *Server) handleWebsocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &conn{ws: ws}
+	ws.SetPingHandler(func(data string) error {
+		if c.stalled.Load() {
+			return nil
+		}
+
+		return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+
+	defer s.dropConn(c)
+
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var request Request
+		if err := json.Unmarshal(message, &request); err != nil {
+			_ = c.write(ackMessage("", http.StatusBadRequest, "invalid request"))
+			continue
+		}
+
+		if err := s.handleRequest(c, request); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handleRequest(c *conn, request Request) error {
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	s.mu.Unlock()
+
+	if !s.authorized("Bearer " + request.Token) {
+		return c.write(ackMessage(request.Guid, http.StatusUnauthorized, "Invalid JWT token!"))
+	}
+
+	switch request.Opcode {
+	case alor.UnsubscribeOpcode:
+		s.mu.Lock()
+		delete(s.subscriptions, request.Guid)
+		s.mu.Unlock()
+
+		return c.write(ackMessage(request.Guid, http.StatusOK, "Handled successfully"))
+	case alor.BarsOpcode, alor.AllTradesOpcode, alor.OrderBookOpcode, alor.QuotesOpcode,
+		alor.PositionsOpcode, alor.SummariesOpcode, alor.RisksOpcode, alor.SpectralRisksOpcode,
+		alor.OrdersOpcode, alor.StopOrdersOpcode, alor.TradesOpcode, alor.InstrumentsOpcode:
+	default:
+		return c.write(ackMessage(request.Guid, http.StatusBadRequest, fmt.Sprintf("unsupported opcode %s", request.Opcode)))
+	}
+
+	s.mu.Lock()
+	if fails := s.subscribeFails[request.Opcode]; len(fails) > 0 {
+		s.subscribeFails[request.Opcode] = fails[1:]
+		s.mu.Unlock()
+
+		return c.write(ackMessage(request.Guid, fails[0].httpCode, fails[0].message))
+	}
+
+	s.subscriptions[request.Guid] = subscription{request: request, conn: c}
+	history := s.history[request.Opcode][request.key()]
+	delay := s.ackDelay
+	if delay > 0 {
+		s.acksPending++
+		s.acksPendingMax = max(s.acksPendingMax, s.acksPending)
+		s.acks.Add(1)
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		go func() {
+			defer s.acks.Done()
+
+			time.Sleep(delay)
+
+			s.mu.Lock()
+			s.acksPending--
+			s.mu.Unlock()
+
+			_ = s.ack(c, request.Guid, history)
+		}()
+
+		return nil
+	}
+
+	return s.ack(c, request.Guid, history)
+}
+
+// ack подтверждает подписку и отдаёт накопленную историю
+func (s *Server) ack(c *conn, guid alor.GUID, history []any) error {
+	if err := c.write(ackMessage(guid, http.StatusOK, "Handled successfully")); err != nil {
+		return err
+	}
+
+	for _, data := range history {
+		if err := c.write(dataMessage(guid, data)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) dropConn(c *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, c)
+
+	for guid, item := range s.subscriptions {
+		if item.conn == c {
+			delete(s.subscriptions, guid)
+		}
+	}
+
+	_ = c.ws.Close()
+}
+
+func (s *Server) authorized(header string) bool {
+	access, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	issued := s.issued[access]
+	s.mu.Unlock()
+
+	if !issued {
+		return false
+	}
+
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(access, &claims); err != nil {
+		return false
+	}
+
+	return claims.ExpiresAt != nil && time.Now().Before(claims.ExpiresAt.Time)
+}
+
+func ackMessage(guid alor.GUID, httpCode int, message string) map[string]any {
+	return map[string]any{
+		"requestGuid": guid,
+		"httpCode":    httpCode,
+		"message":     message,
+	}
+}
+
+func dataMessage(guid alor.GUID, data any) map[string]any {
+	return map[string]any{
+		"data": data,
+		"guid": guid,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// newAccessToken JWT с теми же полями, что выдаёт Alor. Подпись не проверяется ни клиентом, ни сервером.
+func newAccessToken(exp time.Time) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"ent":        "client",
+		"clientid":   "115177",
+		"portfolios": "D38572 G14708",
+		"agreements": "38572",
+		"ein":        "37817",
+		"scope":      "OrdersRead OrdersCreate Trades Personal Stats",
+		"iss":        "Alor.Identity",
+		"aud":        "Client WARP subscriptionsApi CommandApi InstrumentApi",
+		"sub":        "P000000",
+		"azp":        "alortest",
+		"exp":        exp.Unix(),
+		"iat":        time.Now().Unix(),
+		"jti":        strconv.FormatInt(time.Now().UnixNano(), 10),
+	}).SignedString([]byte("alortest"))
+}
@@ -0,0 +1,708 @@
+package alortest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MarlyasDad/rd-hub-go/pkg/alor"
+	"github.com/stretchr/testify/require"
+)
+
+// barsStrategy запоминает бары, пришедшие из вебсокета
+type barsStrategy struct {
+	alor.BaseStrategy
+	mu   sync.Mutex
+	bars []alor.BarsSlimData
+}
+
+func (s *barsStrategy) OnBar(data alor.BarsSlimData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bars = append(s.bars, data)
+	return nil
+}
+
+func (s *barsStrategy) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.bars)
+}
+
+func newTestClient(t *testing.T, server *Server) *alor.Client {
+	t.Helper()
+
+	hosts := server.Hosts()
+
+	return alor.New(alor.Config{
+		RefreshToken:    "refresh_token",
+		RefreshTokenExp: time.Now().AddDate(1, 0, 0),
+		Hosts:           &hosts,
+	})
+}
+
+func TestServerREST(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	client := newTestClient(t, server)
+
+	server.FailRefresh(http.StatusInternalServerError)
+	require.Error(t, client.RefreshToken())
+	require.NoError(t, client.RefreshToken())
+	require.Equal(t, int64(115177), client.Token.GetData().ClientId)
+
+	server.SetTime(1722431736)
+	timestamp, err := client.GetUnixTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, int64(1722431736), timestamp)
+
+	server.SetAllTrades("SBER", []alor.AllTradesSlimData{{ID: 1}, {ID: 2}, {ID: 3}})
+	trades, err := client.GetAllTrades(alor.GetAllTradesV2Params{Exchange: alor.MOEXExchange, Symbol: "SBER", Offset: 1, Take: 5})
+	require.NoError(t, err)
+	require.Equal(t, []alor.AllTradesSlimData{{ID: 2}, {ID: 3}}, trades)
+}
+
+func TestServerWebsocket(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	server.SetHistory(alor.BarsOpcode, "SBER",
+		alor.BarsSlimData{Time: 0, Open: 100, High: 101, Low: 99, Close: 100, Volume: 10},
+		alor.BarsSlimData{Time: 60, Open: 100, High: 102, Low: 100, Close: 101, Volume: 5},
+	)
+
+	client := newTestClient(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+
+	subscriber := alor.NewSubscriber("bars", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, false,
+		alor.WithBarsSubscription(10, 0, false, false),
+	)
+	strategy := &barsStrategy{}
+	subscriber.SetStrategy(strategy)
+
+	require.NoError(t, client.AddSubscriber(subscriber))
+
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return strategy.count() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, server.Publish(alor.BarsOpcode, "SBER", alor.BarsSlimData{Time: 120, Open: 101, High: 101, Low: 101, Close: 101, Volume: 1}))
+	require.Eventually(t, func() bool { return strategy.count() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	request := server.Subscriptions()[0]
+	require.Equal(t, alor.SlimResponseFormat, request.Format)
+	require.Equal(t, alor.M1TF, request.Tf)
+
+	require.NoError(t, client.RemoveSubscriber(subscriber.ID))
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// Отказ брокера: подписка не создаётся, данные не приходят
+	server.FailSubscribe(alor.OrderBookOpcode, http.StatusBadRequest, "Invalid request")
+
+	failed := alor.NewSubscriber("order book", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, false,
+		alor.WithOrderBookSubscription(10, 10),
+	)
+	err := client.AddSubscriber(failed)
+	require.ErrorIs(t, err, alor.ErrSubscriptionRejected)
+
+	var brokerErr *alor.BrokerError
+	require.ErrorAs(t, err, &brokerErr)
+	require.Equal(t, http.StatusBadRequest, brokerErr.HttpCode)
+	require.Equal(t, "Invalid request", brokerErr.Message)
+	require.False(t, failed.Ready)
+
+	_, err = client.GetSubscriber(failed.ID)
+	require.ErrorIs(t, err, alor.ErrSubscriberNotFound)
+	require.Len(t, server.Requests(), 3)
+	require.Empty(t, server.Subscriptions())
+}
+
+func TestServerPool(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	server.SetHistory(alor.BarsOpcode, "SBER", alor.BarsSlimData{Time: 0, Open: 100, High: 101, Low: 99, Close: 100, Volume: 10})
+	server.SetHistory(alor.BarsOpcode, "GAZP", alor.BarsSlimData{Time: 0, Open: 150, High: 151, Low: 149, Close: 150, Volume: 10})
+
+	hosts := server.Hosts()
+	client := alor.New(alor.Config{
+		RefreshToken:    "refresh_token",
+		RefreshTokenExp: time.Now().AddDate(1, 0, 0),
+		Hosts:           &hosts,
+		Pool:            alor.PoolLimits{MaxConnections: 2, MaxSubscriptionsPerConnection: 1},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+
+	sber := alor.NewSubscriber("sber", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, false,
+		alor.WithBarsSubscription(10, 0, false, false),
+	)
+	sberStrategy := &barsStrategy{}
+	sber.SetStrategy(sberStrategy)
+	require.NoError(t, client.AddSubscriber(sber))
+
+	gazp := alor.NewSubscriber("gazp", alor.MOEXExchange, "GAZP", "TQBR", alor.M1TF, false,
+		alor.WithBarsSubscription(10, 0, false, false),
+	)
+	gazpStrategy := &barsStrategy{}
+	gazp.SetStrategy(gazpStrategy)
+	require.NoError(t, client.AddSubscriber(gazp))
+
+	// Во втором соединении своя очередь и свой читатель
+	require.Eventually(t, func() bool { return server.Connections() == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return sberStrategy.count() == 1 && gazpStrategy.count() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	stats := client.Pool.Stats()
+	require.Len(t, stats, 2)
+	require.Equal(t, 1, stats[0].Subscriptions)
+	require.Equal(t, 1, stats[1].Subscriptions)
+
+	// Места кончились
+	extra := alor.NewSubscriber("extra", alor.MOEXExchange, "SBER", "TQBR", alor.M5TF, false,
+		alor.WithBarsSubscription(10, 0, false, false),
+	)
+	require.ErrorIs(t, client.AddSubscriber(extra), alor.ErrPoolExhausted)
+
+	// Освободившееся место занимает новый подписчик
+	require.NoError(t, client.RemoveSubscriber(sber.ID))
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, client.AddSubscriber(extra))
+	require.Equal(t, 2, server.Connections())
+}
+
+func TestServerReconnect(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	server.SetHistory(alor.BarsOpcode, "SBER", alor.BarsSlimData{Time: 0, Open: 100, High: 101, Low: 99, Close: 100, Volume: 10})
+
+	hosts := server.Hosts()
+	client := alor.New(alor.Config{
+		RefreshToken:    "refresh_token",
+		RefreshTokenExp: time.Now().AddDate(1, 0, 0),
+		Hosts:           &hosts,
+		Heartbeat: alor.HeartbeatConfig{
+			PingInterval:      20 * time.Millisecond,
+			ReadTimeout:       200 * time.Millisecond,
+			StaleAfter:        400 * time.Millisecond,
+			ReconnectMinDelay: 10 * time.Millisecond,
+			ReconnectMaxDelay: 50 * time.Millisecond,
+			TradingSession:    alor.AlwaysTradingSession,
+		},
+	})
+
+	var (
+		events []alor.ConnectionEvent
+		mu     sync.Mutex
+	)
+	count := func(eventType alor.ConnectionEventType) int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		n := 0
+		for _, event := range events {
+			if event.Type == eventType {
+				n++
+			}
+		}
+
+		return n
+	}
+	client.OnConnectionEvent(func(event alor.ConnectionEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		events = append(events, event)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+	require.Equal(t, 1, count(alor.ConnectedEvent))
+
+	subscriber := alor.NewSubscriber("bars", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, false,
+		alor.WithBarsSubscription(10, 0, false, false),
+	)
+	subscriber.SetStrategy(&barsStrategy{})
+	require.NoError(t, client.AddSubscriber(subscriber))
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// Разрыв: переподключаемся и восстанавливаем подписку
+	server.Disconnect()
+	require.Eventually(t, func() bool { return count(alor.DisconnectedEvent) == 1 && count(alor.ConnectedEvent) == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// Полуоткрытое соединение: pong не приходит, срабатывает дедлайн чтения
+	server.Stall()
+	require.Eventually(t, func() bool { return count(alor.DisconnectedEvent) == 2 && count(alor.ConnectedEvent) == 3 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.True(t, client.Pool.Stats()[0].Connected)
+
+	// Без данных дольше StaleAfter подписка переподписывается
+	require.Eventually(t, func() bool { return count(alor.ResubscribedEvent) > 0 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServerResubscribeStaleTogether(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	hosts := server.Hosts()
+	client := alor.New(alor.Config{
+		RefreshToken:    "refresh_token",
+		RefreshTokenExp: time.Now().AddDate(1, 0, 0),
+		Hosts:           &hosts,
+		Heartbeat: alor.HeartbeatConfig{
+			PingInterval:      20 * time.Millisecond,
+			ReadTimeout:       time.Second,
+			StaleAfter:        400 * time.Millisecond,
+			ReconnectMinDelay: 10 * time.Millisecond,
+			ReconnectMaxDelay: 50 * time.Millisecond,
+			TradingSession:    alor.AlwaysTradingSession,
+		},
+	})
+
+	var (
+		resubscribed = make(map[alor.GUID]bool)
+		mu           sync.Mutex
+	)
+	client.OnConnectionEvent(func(event alor.ConnectionEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if event.Type == alor.ResubscribedEvent {
+			resubscribed[event.GUID] = true
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+
+	codes := []string{"SBER", "GAZP", "LKOH", "ROSN"}
+	for _, code := range codes {
+		subscriber := alor.NewSubscriber(code, alor.MOEXExchange, code, "TQBR", alor.M1TF, false,
+			alor.WithBarsSubscription(10, 0, false, false),
+		)
+		subscriber.SetStrategy(&barsStrategy{})
+		require.NoError(t, client.AddSubscriber(subscriber))
+	}
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == len(codes) }, 5*time.Second, 10*time.Millisecond)
+
+	// Данных нет, все подписки устаревают разом. Ответ на подписку идёт дольше интервала проверки:
+	// последовательная переподписка ждала бы каждый ответ и не держала бы больше одного запроса сразу
+	server.DelayAcks(300 * time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(resubscribed) == len(codes)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Greater(t, server.MaxPendingAcks(), 1)
+}
+
+// tradesStrategy запоминает объём бара на момент каждой живой сделки
+type tradesStrategy struct {
+	alor.BaseStrategy
+	mu      sync.Mutex
+	volumes map[int64]int64
+}
+
+func (s *tradesStrategy) OnTrade(data alor.AllTradesSlimData) error {
+	bar, err := s.Processor.GetLastBar()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.volumes[data.ID] = bar.Volume
+	return nil
+}
+
+func (s *tradesStrategy) volume(id int64) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	volume, ok := s.volumes[id]
+	return volume, ok
+}
+
+func TestServerAllTradesBackfill(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	trades := make([]alor.AllTradesSlimData, 0, 5)
+	for id := int64(1); id <= 5; id++ {
+		trades = append(trades, alor.AllTradesSlimData{ID: id, Symbol: "SBER", Price: 100, Qty: id, Timestamp: 1_000 + id, Side: alor.BuySide})
+	}
+	server.SetAllTrades("SBER", trades)
+
+	hosts := server.Hosts()
+	client := alor.New(alor.Config{
+		RefreshToken:    "refresh_token",
+		RefreshTokenExp: time.Now().AddDate(1, 0, 0),
+		Hosts:           &hosts,
+		Heartbeat: alor.HeartbeatConfig{
+			ReconnectMinDelay: 10 * time.Millisecond,
+			ReconnectMaxDelay: 50 * time.Millisecond,
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+
+	subscriber := alor.NewSubscriber("trades", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, false,
+		alor.WithAllTradesSubscription(10, 0, false),
+		alor.WithDelta(),
+	)
+	strategy := &tradesStrategy{volumes: make(map[int64]int64)}
+	subscriber.SetStrategy(strategy)
+	require.NoError(t, client.AddSubscriber(subscriber))
+
+	require.NoError(t, server.Publish(alor.AllTradesOpcode, "SBER", trades[0]))
+	require.Eventually(t, func() bool { _, ok := strategy.volume(1); return ok }, 5*time.Second, 10*time.Millisecond)
+
+	// Сделки 2-4 прошли, пока соединения не было
+	requests := len(server.Requests())
+	server.Disconnect()
+	require.Eventually(t, func() bool {
+		return len(server.Requests()) > requests && len(server.Subscriptions()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Повтор последней обработанной сделки не учитывается дважды
+	require.NoError(t, server.Publish(alor.AllTradesOpcode, "SBER", trades[0]))
+	require.NoError(t, server.Publish(alor.AllTradesOpcode, "SBER", trades[4]))
+	require.Eventually(t, func() bool { _, ok := strategy.volume(5); return ok }, 5*time.Second, 10*time.Millisecond)
+
+	// Пропуск догружен до живой сделки: объём бара 1+2+3+4+5
+	volume, _ := strategy.volume(5)
+	require.EqualValues(t, 15, volume)
+}
+
+func TestServerPortfolio(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	server.SetHistory(alor.PositionsOpcode, "D38572",
+		alor.PositionSlimData{Portfolio: "D38572", Symbol: "SBER", Qty: 10, AvgPrice: 250},
+		alor.PositionSlimData{Portfolio: "D38572", Symbol: "RUB", IsCurrency: true, Volume: 50000},
+	)
+	server.SetHistory(alor.SummariesOpcode, "D38572", alor.SummarySlimData{BuyingPower: 50000})
+
+	client := newTestClient(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+
+	state, err := client.SubscribePortfolio(alor.MOEXExchange, "D38572")
+	require.NoError(t, err)
+
+	// Повторная подписка не дублирует потоки
+	again, err := client.SubscribePortfolio(alor.MOEXExchange, "D38572")
+	require.NoError(t, err)
+	require.Same(t, state, again)
+
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == len(alor.PortfolioOpcodes) }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		buyingPower, err := state.GetBuyingPower()
+		return err == nil && buyingPower == 50000
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return len(state.Snapshot().Positions) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, server.Publish(alor.PositionsOpcode, "D38572", alor.PositionSlimData{Portfolio: "D38572", Symbol: "SBER", Qty: 0}))
+	require.Eventually(t, func() bool {
+		position, ok := state.GetPosition("SBER")
+		return ok && position.Qty == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = state.GetInitialMargin()
+	require.ErrorIs(t, err, alor.ErrNoPortfolioData)
+
+	require.NoError(t, client.UnsubscribePortfolio(alor.MOEXExchange, "D38572"))
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	_, err = client.GetPortfolio(alor.MOEXExchange, "D38572")
+	require.ErrorIs(t, err, alor.ErrPortfolioNotFound)
+}
+
+// ordersStrategy запоминает события собственных заявок
+type ordersStrategy struct {
+	alor.BaseStrategy
+	mu      sync.Mutex
+	updates []alor.OrderUpdate
+	fills   []alor.Fill
+}
+
+func (s *ordersStrategy) OnOrderUpdate(update alor.OrderUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates = append(s.updates, update)
+	return nil
+}
+
+func (s *ordersStrategy) OnFill(fill alor.Fill) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fills = append(s.fills, fill)
+	return nil
+}
+
+func (s *ordersStrategy) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.updates), len(s.fills)
+}
+
+// stubCommandBus принимает заявки, не отправляя их брокеру
+type stubCommandBus struct {
+	alor.CommandBus
+	comments []string
+	prices   []float64
+}
+
+func (b *stubCommandBus) CreateLimitOrder(params alor.LimitOrderParams) (alor.OrderID, error) {
+	b.comments = append(b.comments, params.Comment)
+	b.prices = append(b.prices, params.Price)
+	return "42", nil
+}
+
+func TestServerOrders(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	client := newTestClient(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+	require.NoError(t, client.SubscribeOrders(alor.MOEXExchange, "D38572"))
+
+	commandBus := &stubCommandBus{}
+	subscriber := alor.NewSubscriber("orders", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, false,
+		alor.WithCommandBus(commandBus),
+	)
+	strategy := &ordersStrategy{}
+	subscriber.SetStrategy(strategy)
+	require.NoError(t, client.AddSubscriber(subscriber))
+
+	orderID, err := strategy.CommandBus.CreateLimitOrder(alor.LimitOrderParams{
+		OrderTarget: alor.OrderTarget{Portfolio: "D38572", Exchange: alor.MOEXExchange, Code: "SBER", Side: alor.BuySide, Quantity: 1, Comment: "entry"},
+		Price:       250,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{subscriber.ID.String() + " entry"}, commandBus.comments)
+
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == len(alor.OrdersOpcodes) }, 5*time.Second, 10*time.Millisecond)
+
+	// Чужая заявка не доходит до стратегии
+	require.NoError(t, server.Publish(alor.OrdersOpcode, "D38572", alor.OrderSlimData{ID: "1", Symbol: "SBER", Status: alor.WorkingOrderStatus}))
+	// Своя по номеру заявки
+	require.NoError(t, server.Publish(alor.OrdersOpcode, "D38572", alor.OrderSlimData{ID: string(orderID), Symbol: "SBER", Status: alor.FilledOrderStatus, Qty: 1, FilledQty: 1}))
+	require.NoError(t, server.Publish(alor.TradesOpcode, "D38572", alor.TradeSlimData{ID: "7", OrderNumber: string(orderID), Symbol: "SBER", Qty: 1, Price: 250}))
+	// Своя по комментарию, номер заявки ещё неизвестен
+	require.NoError(t, server.Publish(alor.StopOrdersOpcode, "D38572", alor.StopOrderSlimData{ID: "43", Comment: subscriber.ID.String(), Status: alor.WorkingOrderStatus, TriggerPrice: 240}))
+
+	require.Eventually(t, func() bool {
+		updates, fills := strategy.counts()
+		return updates == 2 && fills == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	strategy.mu.Lock()
+	defer strategy.mu.Unlock()
+
+	require.Equal(t, orderID, strategy.updates[0].ID)
+	require.True(t, strategy.updates[0].Status.IsFinal())
+	require.Equal(t, orderID, strategy.fills[0].OrderID)
+	require.True(t, strategy.updates[1].Stop)
+	require.Equal(t, 240.0, strategy.updates[1].TriggerPrice)
+}
+
+func TestServerSecurities(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	server.SetSecurities(alor.MOEXExchange, alor.Security{
+		Symbol:        "SBER",
+		Exchange:      alor.MOEXExchange,
+		Board:         "TQBR",
+		PrimaryBoard:  "TQBR",
+		Currency:      "RUB",
+		LotSize:       10,
+		PriceStep:     0.01,
+		PriceStepCost: 0.1,
+		PriceMin:      200,
+		PriceMax:      300,
+		TradingStatus: alor.NormalTradingStatus,
+	})
+
+	client := newTestClient(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+
+	security, err := client.SubscribeSecurity(alor.MOEXExchange, "SBER", "")
+	require.NoError(t, err)
+	require.Equal(t, "TQBR", security.Board)
+	require.True(t, security.IsTrading())
+	require.False(t, security.InPriceLimits(301))
+
+	_, err = client.SubscribeSecurity(alor.MOEXExchange, "SBER", "SMAL")
+	require.ErrorIs(t, err, alor.ErrSecurityNotFound)
+
+	// Подписчик на неизвестный режим торгов не добавляется
+	unknown := alor.NewSubscriber("unknown", alor.MOEXExchange, "SBER", "SMAL", alor.M1TF, false,
+		alor.WithSecurities(client.Securities),
+	)
+	require.ErrorIs(t, client.AddSubscriber(unknown), alor.ErrSecurityNotFound)
+
+	commandBus := &stubCommandBus{}
+	subscriber := alor.NewSubscriber("securities", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, false,
+		alor.WithCommandBus(commandBus),
+		alor.WithSecurities(client.Securities),
+	)
+	strategy := &ordersStrategy{}
+	subscriber.SetStrategy(strategy)
+	require.NoError(t, client.AddSubscriber(subscriber))
+
+	_, err = strategy.CommandBus.CreateLimitOrder(alor.LimitOrderParams{
+		OrderTarget: alor.OrderTarget{Portfolio: "D38572", Exchange: alor.MOEXExchange, Code: "SBER", Board: "TQBR", Side: alor.BuySide, Quantity: 1},
+		Price:       250.126,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []float64{250.13}, commandBus.prices)
+
+	// Обновление приходит частично, остальные поля сохраняются
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, server.Publish(alor.InstrumentsOpcode, "SBER", map[string]any{"tradingStatus": 18, "minstep": 0.05}))
+	require.Eventually(t, func() bool {
+		security, err := client.Securities.Get(alor.MOEXExchange, "SBER", "TQBR")
+		return err == nil && security.TradingStatus == 18
+	}, 5*time.Second, 10*time.Millisecond)
+
+	security, err = client.Securities.Get(alor.MOEXExchange, "SBER", "TQBR")
+	require.NoError(t, err)
+	require.Equal(t, 0.05, security.PriceStep)
+	require.Equal(t, 10.0, security.LotSize)
+	require.Equal(t, 250.15, security.RoundPrice(250.126))
+}
+
+// blockingStrategy держит воркер на первом баре, пока тест не отпустит
+type blockingStrategy struct {
+	alor.BaseStrategy
+	release chan struct{}
+}
+
+func (s *blockingStrategy) OnBar(alor.BarsSlimData) error {
+	<-s.release
+	return nil
+}
+
+func TestServerSlowSubscriberEviction(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("refresh_token")
+	t.Cleanup(server.Close)
+
+	client := newTestClient(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		client.Stop()
+		cancel()
+	})
+
+	require.NoError(t, client.Connect(ctx, true))
+
+	subscriber := alor.NewSubscriber("slow", alor.MOEXExchange, "SBER", "TQBR", alor.M1TF, true,
+		alor.WithBarsSubscription(10, 0, false, false),
+		alor.WithQueueLimits(alor.QueueLimits{Limit: 1}),
+	)
+	strategy := &blockingStrategy{release: make(chan struct{})}
+	subscriber.SetStrategy(strategy)
+
+	require.NoError(t, client.AddSubscriber(subscriber))
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	for i := int64(0); i < 5; i++ {
+		require.NoError(t, server.Publish(alor.BarsOpcode, "SBER", alor.BarsSlimData{Time: i * 60, Open: 100, High: 100, Low: 100, Close: 100, Volume: 1}))
+	}
+
+	require.Eventually(t, subscriber.IsDone, 5*time.Second, 10*time.Millisecond)
+	close(strategy.release)
+
+	// Отключённый подписчик отписан от брокера и убран из пула
+	require.Eventually(t, func() bool { return len(server.Subscriptions()) == 0 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, err := client.GetSubscriber(subscriber.ID)
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Zero(t, client.Pool.Stats()[0].Subscriptions)
+}
@@ -0,0 +1,261 @@
+package alor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// BacktestEvent событие истории с временем в миллисекундах
+type BacktestEvent struct {
+	Timestamp int64
+	Event     *ChainEvent
+}
+
+func NewAllTradesBacktestEvent(data AllTradesSlimData) (BacktestEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return BacktestEvent{}, err
+	}
+
+	return BacktestEvent{
+		Timestamp: data.Timestamp,
+		Event:     &ChainEvent{Type: DataType, Opcode: AllTradesOpcode, Data: raw},
+	}, nil
+}
+
+func NewBarsBacktestEvent(data BarsSlimData) (BacktestEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return BacktestEvent{}, err
+	}
+
+	// Время бара приходит в секундах
+	return BacktestEvent{
+		Timestamp: data.Time * 1000,
+		Event:     &ChainEvent{Type: DataType, Opcode: BarsOpcode, Data: raw},
+	}, nil
+}
+
+// BacktestSource источник исторических событий
+type BacktestSource interface {
+	Load() ([]BacktestEvent, error)
+}
+
+// BacktestEvents готовый набор событий в памяти
+type BacktestEvents []BacktestEvent
+
+func (e BacktestEvents) Load() ([]BacktestEvent, error) {
+	return e, nil
+}
+
+// FileBacktestSource файл JSON lines, в каждой строке сделка или бар в slim формате
+type FileBacktestSource struct {
+	Path   string
+	Opcode Opcode // AllTradesOpcode или BarsOpcode
+}
+
+func (s FileBacktestSource) Load() ([]BacktestEvent, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []BacktestEvent
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		event, err := s.decodeLine(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.Path, line, err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s FileBacktestSource) decodeLine(line []byte) (BacktestEvent, error) {
+	switch s.Opcode {
+	case AllTradesOpcode:
+		var data AllTradesSlimData
+		if err := json.Unmarshal(line, &data); err != nil {
+			return BacktestEvent{}, err
+		}
+
+		return NewAllTradesBacktestEvent(data)
+	case BarsOpcode:
+		var data BarsSlimData
+		if err := json.Unmarshal(line, &data); err != nil {
+			return BacktestEvent{}, err
+		}
+
+		return NewBarsBacktestEvent(data)
+	default:
+		return BacktestEvent{}, fmt.Errorf("%w: %s", ErrUnsupportedBacktestOpcode, s.Opcode)
+	}
+}
+
+type AllTradesGetter interface {
+	GetAllTrades(params GetAllTradesV2Params) ([]AllTradesSlimData, error)
+}
+
+// AllTradesHistorySource обезличенные сделки из REST API, выкачиваются страницами
+type AllTradesHistorySource struct {
+	Client AllTradesGetter
+	Params GetAllTradesV2Params
+}
+
+func (s AllTradesHistorySource) Load() ([]BacktestEvent, error) {
+	params := s.Params
+	if params.Take == 0 {
+		params.Take = 5000
+	}
+
+	var events []BacktestEvent
+
+	for {
+		trades, err := s.Client.GetAllTrades(params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, trade := range trades {
+			event, err := NewAllTradesBacktestEvent(trade)
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, event)
+		}
+
+		if int64(len(trades)) < params.Take {
+			break
+		}
+
+		params.Offset += params.Take
+	}
+
+	return events, nil
+}
+
+type BacktestConfig struct {
+	InitialCash    float64 // Стартовый капитал
+	Commission     float64 // Комиссия, доля от оборота
+	Slippage       float64 // Проскальзывание рыночных и стоп заявок, в пунктах цены
+	LotSize        float64 // Количество единиц инструмента в лоте, по умолчанию 1
+	PeriodsPerYear float64 // Баров в году для приведения Sharpe к годовому, 0 - без приведения
+}
+
+// Backtest прогоняет историю через подписчика и его стратегию.
+// События идут по времени через HandleEventSync, заявки стратегии исполняет FillSimulator.
+type Backtest struct {
+	subscriber *Subscriber
+	sources    []BacktestSource
+	config     BacktestConfig
+	clock      *SimulatedClock
+	simulator  *FillSimulator
+}
+
+func NewBacktest(subscriber *Subscriber, config BacktestConfig, sources ...BacktestSource) *Backtest {
+	if config.LotSize == 0 {
+		config.LotSize = 1
+	}
+
+	clock := NewSimulatedClock(time.Time{})
+
+	return &Backtest{
+		subscriber: subscriber,
+		sources:    sources,
+		config:     config,
+		clock:      clock,
+		simulator:  NewFillSimulator(clock, subscriber.Code, config),
+	}
+}
+
+// Clock симулированное время, стратегия может брать его вместо time.Now
+func (b *Backtest) Clock() *SimulatedClock {
+	return b.clock
+}
+
+func (b *Backtest) Simulator() *FillSimulator {
+	return b.simulator
+}
+
+func (b *Backtest) Run() (report *BacktestReport, err error) {
+	var events []BacktestEvent
+
+	for _, source := range b.sources {
+		sourceEvents, err := source.Load()
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, sourceEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp < events[j].Timestamp
+	})
+
+	if b.subscriber.Strategy != nil {
+		b.subscriber.Strategy.SetCommandBus(b.simulator)
+	}
+
+	b.subscriber.Ready = true
+
+	if err := b.subscriber.Init(); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = errors.Join(err, b.subscriber.DeInit())
+		if err != nil {
+			report = nil
+		}
+	}()
+
+	curve := newEquityCurve()
+
+	for _, event := range events {
+		b.clock.Set(time.UnixMilli(event.Timestamp).UTC())
+
+		// Сначала исполняем заявки, выставленные на прошлых событиях, потом отдаём событие стратегии
+		if err := b.simulator.OnEvent(event.Event); err != nil {
+			return nil, err
+		}
+
+		if err := b.subscriber.HandleEventSync(event.Event); err != nil {
+			return nil, err
+		}
+
+		curve.record(b.barTime(), b.simulator.Equity())
+	}
+
+	return newBacktestReport(b.config, b.simulator, curve.points, len(events)), nil
+}
+
+// barTime время текущего бара, точки кривой доходности ставятся по барам
+func (b *Backtest) barTime() time.Time {
+	if bar := b.subscriber.DataProcessor.lastBar; bar != nil {
+		return bar.Time
+	}
+
+	return b.clock.Now()
+}
@@ -0,0 +1,361 @@
+package alor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+type simulatedOrderType int
+
+const (
+	marketSimulatedOrder simulatedOrderType = iota
+	limitSimulatedOrder
+	stopSimulatedOrder
+	stopLimitSimulatedOrder
+)
+
+type simulatedOrder struct {
+	id           OrderID
+	orderType    simulatedOrderType
+	side         OrderSide
+	quantity     int64
+	price        float64 // Цена лимитной заявки
+	triggerPrice float64 // Цена срабатывания стоп заявки
+	condition    StopCondition
+	timeInForce  TimeInForce
+	comment      string
+}
+
+// priceRange цены события, по которым проверяется исполнение заявок
+type priceRange struct {
+	open, high, low, close float64
+}
+
+// FillSimulator реализует CommandBus для бэктеста.
+// Заявки исполняются по ценам следующих событий, поэтому стратегия не заглядывает в будущее:
+// рыночные - по цене открытия, лимитные - по своей цене или лучше, стоп - по цене срабатывания или гэпу.
+// Частичных исполнений нет, заявка исполняется целиком.
+type FillSimulator struct {
+	mu      sync.Mutex
+	clock   Clock
+	code    string
+	config  BacktestConfig
+	nextID  int64
+	orders  []*simulatedOrder
+	account virtualAccount
+}
+
+func NewFillSimulator(clock Clock, code string, config BacktestConfig) *FillSimulator {
+	return &FillSimulator{
+		clock:   clock,
+		code:    code,
+		config:  config,
+		account: newVirtualAccount(config.InitialCash, config.Commission, config.LotSize),
+	}
+}
+
+// CommandBus
+
+func (s *FillSimulator) CreateMarketOrder(params MarketOrderParams) (OrderID, error) {
+	return s.addOrder(params.OrderTarget, &simulatedOrder{
+		orderType:   marketSimulatedOrder,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (s *FillSimulator) CreateLimitOrder(params LimitOrderParams) (OrderID, error) {
+	return s.addOrder(params.OrderTarget, &simulatedOrder{
+		orderType:   limitSimulatedOrder,
+		price:       params.Price,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (s *FillSimulator) CreateStopOrder(params StopOrderParams) (OrderID, error) {
+	return s.addOrder(params.OrderTarget, &simulatedOrder{
+		orderType:    stopSimulatedOrder,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+	})
+}
+
+func (s *FillSimulator) CreateStopLimitOrder(params StopLimitOrderParams) (OrderID, error) {
+	return s.addOrder(params.OrderTarget, &simulatedOrder{
+		orderType:    stopLimitSimulatedOrder,
+		price:        params.Price,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+		timeInForce:  params.TimeInForce,
+	})
+}
+
+func (s *FillSimulator) ModifyMarketOrder(orderID OrderID, params MarketOrderParams) (OrderID, error) {
+	return s.replaceOrder(orderID, params.OrderTarget, &simulatedOrder{
+		orderType:   marketSimulatedOrder,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (s *FillSimulator) ModifyLimitOrder(orderID OrderID, params LimitOrderParams) (OrderID, error) {
+	return s.replaceOrder(orderID, params.OrderTarget, &simulatedOrder{
+		orderType:   limitSimulatedOrder,
+		price:       params.Price,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (s *FillSimulator) ModifyStopOrder(orderID OrderID, params StopOrderParams) (OrderID, error) {
+	return s.replaceOrder(orderID, params.OrderTarget, &simulatedOrder{
+		orderType:    stopSimulatedOrder,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+	})
+}
+
+func (s *FillSimulator) ModifyStopLimitOrder(orderID OrderID, params StopLimitOrderParams) (OrderID, error) {
+	return s.replaceOrder(orderID, params.OrderTarget, &simulatedOrder{
+		orderType:    stopLimitSimulatedOrder,
+		price:        params.Price,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+		timeInForce:  params.TimeInForce,
+	})
+}
+
+func (s *FillSimulator) CancelOrder(params CancelOrderParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.findOrder(params.OrderID)
+	if index < 0 {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, params.OrderID)
+	}
+
+	s.orders = append(s.orders[:index], s.orders[index+1:]...)
+
+	return nil
+}
+
+func (s *FillSimulator) addOrder(target OrderTarget, order *simulatedOrder) (OrderID, error) {
+	if err := checkVirtualOrderTarget(s.code, target); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	order.id = OrderID(strconv.FormatInt(s.nextID, 10))
+	order.side = target.Side
+	order.quantity = target.Quantity
+	order.comment = target.Comment
+
+	s.orders = append(s.orders, order)
+
+	return order.id, nil
+}
+
+// replaceOrder изменение заявки, как и у брокера, сохраняет её номер
+func (s *FillSimulator) replaceOrder(orderID OrderID, target OrderTarget, order *simulatedOrder) (OrderID, error) {
+	if err := checkVirtualOrderTarget(s.code, target); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.findOrder(orderID)
+	if index < 0 {
+		return "", fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+
+	order.id = orderID
+	order.side = target.Side
+	order.quantity = target.Quantity
+	order.comment = target.Comment
+
+	s.orders[index] = order
+
+	return orderID, nil
+}
+
+// checkVirtualOrderTarget проверка заявки перед виртуальным исполнением, code - симулируемый инструмент
+func checkVirtualOrderTarget(code string, target OrderTarget) error {
+	if target.Quantity <= 0 {
+		return fmt.Errorf("%w: quantity must be positive", ErrCommandFailed)
+	}
+
+	if target.Side != BuySide && target.Side != SellSide {
+		return fmt.Errorf("%w: unknown side %q", ErrCommandFailed, target.Side)
+	}
+
+	if code != "" && target.Code != code {
+		return fmt.Errorf("%w: instrument %s is not simulated", ErrCommandFailed, target.Code)
+	}
+
+	return nil
+}
+
+func (s *FillSimulator) findOrder(orderID OrderID) int {
+	for index, order := range s.orders {
+		if order.id == orderID {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// Исполнение
+
+// OnEvent проверяет активные заявки по ценам события
+func (s *FillSimulator) OnEvent(event *ChainEvent) error {
+	prices, ok, err := eventPriceRange(event)
+	if err != nil || !ok {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := s.orders[:0]
+
+	for _, order := range s.orders {
+		if s.tryFill(order, prices) {
+			continue
+		}
+
+		// Неисполненный сразу остаток IOC и FOK снимается
+		if order.orderType == limitSimulatedOrder &&
+			(order.timeInForce == ImmediateOrCancelTimeInForce || order.timeInForce == FillOrKillTimeInForce) {
+			continue
+		}
+
+		active = append(active, order)
+	}
+
+	s.orders = active
+	s.account.lastPrice = prices.close
+
+	return nil
+}
+
+func (s *FillSimulator) tryFill(order *simulatedOrder, prices priceRange) bool {
+	switch order.orderType {
+	case marketSimulatedOrder:
+		s.fill(order, s.slipped(order.side, prices.open))
+		return true
+	case limitSimulatedOrder:
+		return s.tryFillLimit(order, prices)
+	case stopSimulatedOrder:
+		if !stopTriggered(order, prices) {
+			return false
+		}
+
+		s.fill(order, s.slipped(order.side, stopFillPrice(order, prices)))
+		return true
+	case stopLimitSimulatedOrder:
+		if !stopTriggered(order, prices) {
+			return false
+		}
+
+		// Сработавшая стоп-лимит заявка становится обычной лимитной
+		order.orderType = limitSimulatedOrder
+
+		return s.tryFillLimit(order, prices)
+	}
+
+	return false
+}
+
+func (s *FillSimulator) tryFillLimit(order *simulatedOrder, prices priceRange) bool {
+	if order.side == BuySide && prices.low <= order.price {
+		s.fill(order, min(order.price, prices.open))
+		return true
+	}
+
+	if order.side == SellSide && prices.high >= order.price {
+		s.fill(order, max(order.price, prices.open))
+		return true
+	}
+
+	return false
+}
+
+func stopTriggered(order *simulatedOrder, prices priceRange) bool {
+	switch order.condition {
+	case MoreStopCondition:
+		return prices.high > order.triggerPrice
+	case MoreOrEqualStopCondition:
+		return prices.high >= order.triggerPrice
+	case LessStopCondition:
+		return prices.low < order.triggerPrice
+	case LessOrEqualStopCondition:
+		return prices.low <= order.triggerPrice
+	}
+
+	return false
+}
+
+// stopFillPrice цена срабатывания, а при гэпе через неё - цена открытия
+func stopFillPrice(order *simulatedOrder, prices priceRange) float64 {
+	if order.condition == MoreStopCondition || order.condition == MoreOrEqualStopCondition {
+		return max(order.triggerPrice, prices.open)
+	}
+
+	return min(order.triggerPrice, prices.open)
+}
+
+func (s *FillSimulator) slipped(side OrderSide, price float64) float64 {
+	if side == BuySide {
+		return price + s.config.Slippage
+	}
+
+	return price - s.config.Slippage
+}
+
+func (s *FillSimulator) fill(order *simulatedOrder, price float64) {
+	s.account.fill(order.id, order.side, order.quantity, price, order.comment, s.clock.Now())
+}
+
+// Состояние счёта
+
+// Position позиция в лотах и её средняя цена
+func (s *FillSimulator) Position() (int64, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.account.position, s.account.avgPrice
+}
+
+// Equity стоимость счёта с переоценкой позиции по последней цене
+func (s *FillSimulator) Equity() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.account.equity()
+}
+
+func eventPriceRange(event *ChainEvent) (priceRange, bool, error) {
+	switch event.Opcode {
+	case AllTradesOpcode:
+		var data AllTradesSlimData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return priceRange{}, false, err
+		}
+
+		return priceRange{open: data.Price, high: data.Price, low: data.Price, close: data.Price}, true, nil
+	case BarsOpcode:
+		var data BarsSlimData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return priceRange{}, false, err
+		}
+
+		return priceRange{open: data.Open, high: data.High, low: data.Low, close: data.Close}, true, nil
+	}
+
+	// По стакану заявки не исполняем
+	return priceRange{}, false, nil
+}
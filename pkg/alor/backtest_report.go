@@ -0,0 +1,141 @@
+package alor
+
+import (
+	"math"
+	"time"
+)
+
+type EquityPoint struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+type BacktestReport struct {
+	Events         int             `json:"events"`       // Сколько событий прогнано
+	InitialCash    float64         `json:"initial_cash"` // Стартовый капитал
+	FinalEquity    float64         `json:"final_equity"` // Капитал с переоценкой открытой позиции
+	PnL            float64         `json:"pnl"`          // Итог за вычетом комиссий
+	RealizedPnL    float64         `json:"realized_pnl"` // По закрытым сделкам без комиссий
+	Commission     float64         `json:"commission"`
+	MaxDrawdown    float64         `json:"max_drawdown"`     // Максимальная просадка, доля от пика
+	MaxDrawdownAbs float64         `json:"max_drawdown_abs"` // Максимальная просадка в деньгах
+	SharpeRatio    float64         `json:"sharpe_ratio"`     // По доходностям между точками кривой
+	WinRate        float64         `json:"win_rate"`         // Доля прибыльных сделок
+	Position       int64           `json:"position"`         // Открытая позиция на конец прогона
+	Trades         []BacktestTrade `json:"trades"`
+	Fills          []BacktestFill  `json:"fills"`
+	EquityCurve    []EquityPoint   `json:"equity_curve"`
+}
+
+func newBacktestReport(config BacktestConfig, simulator *FillSimulator, curve []EquityPoint, events int) *BacktestReport {
+	simulator.mu.Lock()
+	defer simulator.mu.Unlock()
+
+	account := simulator.account
+	finalEquity := account.equity()
+
+	report := &BacktestReport{
+		Events:      events,
+		InitialCash: config.InitialCash,
+		FinalEquity: finalEquity,
+		PnL:         finalEquity - config.InitialCash,
+		RealizedPnL: account.realizedPnL,
+		Commission:  account.commission,
+		Position:    account.position,
+		Trades:      append([]BacktestTrade(nil), account.trades...),
+		Fills:       append([]BacktestFill(nil), account.fills...),
+		EquityCurve: curve,
+	}
+
+	report.MaxDrawdown, report.MaxDrawdownAbs = maxDrawdown(curve)
+	report.SharpeRatio = sharpeRatio(curve, config.PeriodsPerYear)
+
+	if len(report.Trades) != 0 {
+		var wins int
+		for _, trade := range report.Trades {
+			if trade.PnL > 0 {
+				wins++
+			}
+		}
+
+		report.WinRate = float64(wins) / float64(len(report.Trades))
+	}
+
+	return report
+}
+
+// equityCurve кривая доходности, одна точка на бар - последнее значение внутри бара
+type equityCurve struct {
+	points []EquityPoint
+}
+
+func newEquityCurve() *equityCurve {
+	return &equityCurve{}
+}
+
+func (c *equityCurve) record(at time.Time, equity float64) {
+	if last := len(c.points) - 1; last >= 0 && c.points[last].Time.Equal(at) {
+		c.points[last].Equity = equity
+		return
+	}
+
+	c.points = append(c.points, EquityPoint{Time: at, Equity: equity})
+}
+
+func maxDrawdown(curve []EquityPoint) (float64, float64) {
+	var peak, drawdown, drawdownAbs float64
+
+	for index, point := range curve {
+		if index == 0 || point.Equity > peak {
+			peak = point.Equity
+			continue
+		}
+
+		drawdownAbs = max(drawdownAbs, peak-point.Equity)
+
+		if peak > 0 {
+			drawdown = max(drawdown, (peak-point.Equity)/peak)
+		}
+	}
+
+	return drawdown, drawdownAbs
+}
+
+func sharpeRatio(curve []EquityPoint, periodsPerYear float64) float64 {
+	returns := make([]float64, 0, len(curve))
+
+	for index := 1; index < len(curve); index++ {
+		if curve[index-1].Equity == 0 {
+			continue
+		}
+
+		returns = append(returns, curve[index].Equity/curve[index-1].Equity-1)
+	}
+
+	if len(returns) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, value := range returns {
+		mean += value
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, value := range returns {
+		variance += (value - mean) * (value - mean)
+	}
+
+	stdDev := math.Sqrt(variance / float64(len(returns)-1))
+	if stdDev == 0 {
+		return 0
+	}
+
+	sharpe := mean / stdDev
+	if periodsPerYear > 0 {
+		sharpe *= math.Sqrt(periodsPerYear)
+	}
+
+	return sharpe
+}
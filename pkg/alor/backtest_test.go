@@ -0,0 +1,111 @@
+package alor
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// flipStrategy покупает на первом закрытом баре и продаёт на втором
+type flipStrategy struct {
+	BaseStrategy
+	closed int
+}
+
+func (s *flipStrategy) OnBarClosed(bar *Bar) error {
+	s.closed++
+
+	side := BuySide
+	switch s.closed {
+	case 1:
+	case 2:
+		side = SellSide
+	default:
+		return nil
+	}
+
+	_, err := s.CommandBus.CreateMarketOrder(MarketOrderParams{
+		OrderTarget: OrderTarget{Code: "SBER", Side: side, Quantity: 2},
+	})
+
+	return err
+}
+
+func TestBacktestRun(t *testing.T) {
+	t.Parallel()
+
+	var events BacktestEvents
+	for index, bar := range []BarsSlimData{
+		{Time: 0, Open: 100, High: 101, Low: 99, Close: 100},
+		{Time: 60, Open: 102, High: 104, Low: 101, Close: 103},
+		{Time: 120, Open: 110, High: 111, Low: 108, Close: 109},
+		{Time: 180, Open: 105, High: 106, Low: 104, Close: 105},
+	} {
+		event, err := NewBarsBacktestEvent(bar)
+		require.NoError(t, err, index)
+		events = append(events, event)
+	}
+
+	subscriber := NewSubscriber("backtest", MOEXExchange, "SBER", "TQBR", M1TF, false)
+	strategy := &flipStrategy{}
+	subscriber.SetStrategy(strategy)
+
+	report, err := NewBacktest(subscriber, BacktestConfig{InitialCash: 1000}, events).Run()
+	require.NoError(t, err)
+
+	// Покупка по открытию третьего бара, продажа по открытию четвёртого
+	require.Len(t, report.Fills, 2)
+	require.Equal(t, 110.0, report.Fills[0].Price)
+	require.Equal(t, 105.0, report.Fills[1].Price)
+
+	require.Len(t, report.Trades, 1)
+	require.Equal(t, -10.0, report.Trades[0].PnL)
+	require.Equal(t, 990.0, report.FinalEquity)
+	require.Equal(t, -10.0, report.PnL)
+	require.Equal(t, 0.0, report.WinRate)
+	require.Len(t, report.EquityCurve, 4)
+	require.InDelta(t, 0.01, report.MaxDrawdown, 1e-9)
+}
+
+func TestFillSimulatorOrders(t *testing.T) {
+	t.Parallel()
+
+	simulator := NewFillSimulator(NewSimulatedClock(time.Time{}), "SBER", BacktestConfig{InitialCash: 1000})
+	target := OrderTarget{Code: "SBER", Side: BuySide, Quantity: 1}
+
+	limitID, err := simulator.CreateLimitOrder(LimitOrderParams{OrderTarget: target, Price: 95})
+	require.NoError(t, err)
+
+	target.Side = SellSide
+	stopID, err := simulator.CreateStopOrder(StopOrderParams{OrderTarget: target, Condition: LessOrEqualStopCondition, TriggerPrice: 90})
+	require.NoError(t, err)
+
+	trade := func(price float64) {
+		event, err := NewAllTradesBacktestEvent(AllTradesSlimData{Price: price})
+		require.NoError(t, err)
+		require.NoError(t, simulator.OnEvent(event.Event))
+	}
+
+	trade(96)
+	position, _ := simulator.Position()
+	require.Zero(t, position)
+
+	trade(94)
+	position, avgPrice := simulator.Position()
+	require.Equal(t, int64(1), position)
+	require.Equal(t, 94.0, avgPrice)
+
+	// Лимитная заявка исполнена, снять её уже нельзя
+	require.ErrorIs(t, simulator.CancelOrder(CancelOrderParams{OrderID: limitID}), ErrOrderNotFound)
+
+	_, err = simulator.ModifyStopOrder(stopID, StopOrderParams{OrderTarget: target, Condition: LessOrEqualStopCondition, TriggerPrice: 93})
+	require.NoError(t, err)
+
+	trade(92)
+	position, _ = simulator.Position()
+	require.Zero(t, position)
+	require.Equal(t, 998.0, simulator.Equity())
+
+	_, err = simulator.CreateMarketOrder(MarketOrderParams{OrderTarget: OrderTarget{Code: "GAZP", Side: BuySide, Quantity: 1}})
+	require.ErrorIs(t, err, ErrCommandFailed)
+}
@@ -0,0 +1,205 @@
+package alor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// BarBuilder режет ленту сделок на бары. Цены, объём, дельту, профиль и стакан
+// бар получает в DataProcessor одинаково для всех типов, построитель только решает, где бар заканчивается.
+type BarBuilder interface {
+	// Opens сделка не помещается в формирующийся бар bar и открывает новый
+	Opens(bar *Bar, trade AllTradesSlimData) bool
+	// Start время нового бара, открытого сделкой
+	Start(trade AllTradesSlimData) time.Time
+}
+
+type BarType string
+
+const (
+	TimeBarType   BarType = "time"   // По таймфрейму подписчика
+	TickBarType   BarType = "tick"   // Каждые N сделок
+	VolumeBarType BarType = "volume" // Каждые N лотов
+	RangeBarType  BarType = "range"  // Диапазон цены бара не больше N
+	RenkoBarType  BarType = "renko"  // Кирпич размером N
+	DollarBarType BarType = "dollar" // Каждые N рублей оборота
+)
+
+// WithBarBuilder строит основные бары подписчика построителем вместо таймфрейма.
+// Бары брокера из подписки на свечи строятся только по времени и с другими построителями не смешиваются.
+func WithBarBuilder(builder BarBuilder) SubscriberOption {
+	return func(s *Subscriber) {
+		s.DataProcessor.builder = builder
+	}
+}
+
+// NewBarBuilder создаёт построитель по типу и размеру (из HTTP). Для time размер не нужен,
+// бары строятся по таймфрейму timeframe. lotSize нужен для оборота dollar баров.
+func NewBarBuilder(kind BarType, size float64, timeframe Timeframe, lotSize float64) (BarBuilder, error) {
+	kind = BarType(strings.ToLower(string(kind)))
+	if kind == "" || kind == TimeBarType {
+		return NewTimeBars(timeframe), nil
+	}
+
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: %s bars size must be positive", ErrInvalidBarSize, kind)
+	}
+
+	switch kind {
+	case TickBarType:
+		return NewTickBars(int64(size)), nil
+	case VolumeBarType:
+		return NewVolumeBars(int64(size)), nil
+	case RangeBarType:
+		return NewRangeBars(size), nil
+	case RenkoBarType:
+		return NewRenkoBars(size), nil
+	case DollarBarType:
+		return NewDollarBars(size, lotSize), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownBarType, kind)
+}
+
+// TimeBars бары по таймфрейму, выровненные по началу периода
+type TimeBars struct {
+	Timeframe Timeframe
+}
+
+func NewTimeBars(timeframe Timeframe) *TimeBars {
+	return &TimeBars{Timeframe: timeframe}
+}
+
+func (b *TimeBars) Opens(bar *Bar, trade AllTradesSlimData) bool {
+	return b.Start(trade).After(bar.Time)
+}
+
+func (b *TimeBars) Start(trade AllTradesSlimData) time.Time {
+	return tradeBarTime(trade.Timestamp, b.Timeframe)
+}
+
+// tradeStart бары не по времени начинаются с первой сделки
+func tradeStart(trade AllTradesSlimData) time.Time {
+	return time.UnixMilli(trade.Timestamp)
+}
+
+// TickBars бар из Trades сделок
+type TickBars struct {
+	Trades int64
+}
+
+func NewTickBars(trades int64) *TickBars {
+	return &TickBars{Trades: max(trades, 1)}
+}
+
+func (b *TickBars) Opens(bar *Bar, _ AllTradesSlimData) bool {
+	return bar.Trades >= b.Trades
+}
+
+func (b *TickBars) Start(trade AllTradesSlimData) time.Time {
+	return tradeStart(trade)
+}
+
+// VolumeBars бар закрывается, набрав Lots лотов. Сделка не делится, последняя может перебрать объём.
+type VolumeBars struct {
+	Lots int64
+}
+
+func NewVolumeBars(lots int64) *VolumeBars {
+	return &VolumeBars{Lots: max(lots, 1)}
+}
+
+func (b *VolumeBars) Opens(bar *Bar, _ AllTradesSlimData) bool {
+	return bar.Volume >= b.Lots
+}
+
+func (b *VolumeBars) Start(trade AllTradesSlimData) time.Time {
+	return tradeStart(trade)
+}
+
+// RangeBars разница High и Low бара не больше Range, сделка за пределами открывает новый бар
+type RangeBars struct {
+	Range float64
+}
+
+func NewRangeBars(priceRange float64) *RangeBars {
+	return &RangeBars{Range: priceRange}
+}
+
+func (b *RangeBars) Opens(bar *Bar, trade AllTradesSlimData) bool {
+	return max(bar.High, trade.Price)-min(bar.Low, trade.Price) > b.Range
+}
+
+func (b *RangeBars) Start(trade AllTradesSlimData) time.Time {
+	return tradeStart(trade)
+}
+
+// RenkoBars кирпичи размером Brick. Кирпич закрывается сделкой, прошедшей от границы прошлого кирпича
+// Brick в ту же сторону или два Brick в обратную. Сделки кирпича, как и у остальных баров, дают объём, дельту и профиль.
+type RenkoBars struct {
+	Brick     float64
+	base      float64 // Граница последнего кирпича
+	direction int     // 1 - последний кирпич вверх, -1 - вниз, 0 - кирпичей ещё не было
+}
+
+func NewRenkoBars(brick float64) *RenkoBars {
+	return &RenkoBars{Brick: brick}
+}
+
+func (b *RenkoBars) Opens(bar *Bar, _ AllTradesSlimData) bool {
+	if b.base == 0 {
+		b.base = bar.Open
+	}
+
+	up := b.base + b.Brick
+	if b.direction < 0 {
+		up += b.Brick
+	}
+
+	down := b.base - b.Brick
+	if b.direction > 0 {
+		down -= b.Brick
+	}
+
+	// Резкое движение закрывает кирпич сразу на несколько размеров
+	switch {
+	case bar.Close >= up:
+		b.base = up + math.Floor((bar.Close-up)/b.Brick)*b.Brick
+		b.direction = 1
+	case bar.Close <= down:
+		b.base = down - math.Floor((down-bar.Close)/b.Brick)*b.Brick
+		b.direction = -1
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (b *RenkoBars) Start(trade AllTradesSlimData) time.Time {
+	return tradeStart(trade)
+}
+
+// DollarBars бар закрывается, набрав Turnover рублей оборота
+type DollarBars struct {
+	Turnover float64
+	LotSize  float64 // Бумаг в лоте, оборот бара считается в лотах
+}
+
+func NewDollarBars(turnover float64, lotSize float64) *DollarBars {
+	if lotSize <= 0 {
+		lotSize = 1
+	}
+
+	return &DollarBars{Turnover: turnover, LotSize: lotSize}
+}
+
+func (b *DollarBars) Opens(bar *Bar, _ AllTradesSlimData) bool {
+	return bar.Turnover*b.LotSize >= b.Turnover
+}
+
+func (b *DollarBars) Start(trade AllTradesSlimData) time.Time {
+	return tradeStart(trade)
+}
@@ -0,0 +1,73 @@
+package alor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarBuilders(t *testing.T) {
+	t.Parallel()
+
+	prices := []float64{100, 101, 103, 104, 102, 99, 98, 101}
+
+	tests := []struct {
+		name    string
+		builder BarBuilder
+		volumes []int64 // Объём каждого бара по порядку
+	}{
+		{name: "tick", builder: NewTickBars(3), volumes: []int64{3, 3, 2}},
+		{name: "volume", builder: NewVolumeBars(4), volumes: []int64{4, 4}},
+		{name: "range", builder: NewRangeBars(3), volumes: []int64{3, 2, 3}},
+		{name: "renko", builder: NewRenkoBars(2), volumes: []int64{3, 1, 2, 1, 1}},
+		{name: "dollar", builder: NewDollarBars(4000, 10), volumes: []int64{4, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithBarBuilder(tt.builder), WithDelta(), WithMarketProfile())
+
+			for i, price := range prices {
+				side := BuySide
+				if i%2 == 1 {
+					side = SellSide
+				}
+
+				require.NoError(t, subscriber.DataProcessor.NewAllTrades(AllTradesSlimData{ID: int64(i + 1), Price: price, Qty: 1, Timestamp: int64(i) * 1_000, Side: side}))
+			}
+
+			bars, err := subscriber.DataProcessor.bars.GetAllBars()
+			require.NoError(t, err)
+
+			volumes := make([]int64, 0, len(bars))
+			for _, bar := range bars {
+				volumes = append(volumes, bar.Volume)
+
+				// Детализация заполняется для любого типа баров
+				require.Equal(t, bar.Volume, bar.Delta.Buy+bar.Delta.Sell)
+				require.Equal(t, bar.Volume, bar.MarketProfile.POCVolume+otherProfileVolume(bar))
+				require.Equal(t, bar.Volume, bar.Trades)
+			}
+
+			require.Equal(t, tt.volumes, volumes)
+		})
+	}
+
+	_, err := NewBarBuilder("renko", 0, M1TF, 1)
+	require.ErrorIs(t, err, ErrInvalidBarSize)
+
+	_, err = NewBarBuilder("kagi", 1, M1TF, 1)
+	require.ErrorIs(t, err, ErrUnknownBarType)
+}
+
+// otherProfileVolume объём профиля вне POC
+func otherProfileVolume(bar *Bar) int64 {
+	var total int64
+	for _, unit := range bar.MarketProfile.Values {
+		total += unit.Total
+	}
+
+	return total - bar.MarketProfile.POCVolume
+}
@@ -0,0 +1,251 @@
+package alor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// BarTimerOpcode событие таймера баров, приходит подписчику тем же путём, что и данные брокера
+const BarTimerOpcode Opcode = "BarTimer"
+
+// EmptyBarPolicy что делать с интервалами таймфрейма, в которых не было сделок
+type EmptyBarPolicy string
+
+const (
+	SkipEmptyBars        EmptyBarPolicy = "skip"         // Интервала без сделок среди баров нет
+	FillForwardEmptyBars EmptyBarPolicy = "fill_forward" // Пустой бар по цене закрытия прошлого с нулевым объёмом
+)
+
+func (p EmptyBarPolicy) IsValid() bool {
+	switch p {
+	case SkipEmptyBars, FillForwardEmptyBars:
+		return true
+	}
+
+	return false
+}
+
+// BarTimerConfig закрытие баров по часам биржи, не дожидаясь первой сделки следующего бара
+type BarTimerConfig struct {
+	Delay          time.Duration        // Сколько после конца бара ждать опоздавшие сделки, по умолчанию 500 мс
+	EmptyBars      EmptyBarPolicy       // Бары без сделок, по умолчанию SkipEmptyBars
+	TradingSession func(time.Time) bool // Когда достраиваются пустые бары, по умолчанию MOEXTradingSession
+}
+
+var DefaultBarTimerConfig = BarTimerConfig{
+	Delay:          500 * time.Millisecond,
+	EmptyBars:      SkipEmptyBars,
+	TradingSession: MOEXTradingSession,
+}
+
+func (c BarTimerConfig) withDefaults() BarTimerConfig {
+	if c.Delay <= 0 {
+		c.Delay = DefaultBarTimerConfig.Delay
+	}
+
+	if c.EmptyBars == "" {
+		c.EmptyBars = DefaultBarTimerConfig.EmptyBars
+	}
+
+	if c.TradingSession == nil {
+		c.TradingSession = DefaultBarTimerConfig.TradingSession
+	}
+
+	return c
+}
+
+// WithBarTimer закрывает бары по времени: стратегия получает бар сразу после конца интервала,
+// даже если следующей сделки нет. Работает только для баров по времени, часы подписчику даёт пул.
+func WithBarTimer(config BarTimerConfig) SubscriberOption {
+	return func(s *Subscriber) {
+		config = config.withDefaults()
+		if !config.EmptyBars.IsValid() {
+			s.err = fmt.Errorf("%w: %s", ErrUnknownEmptyBarPolicy, config.EmptyBars)
+			return
+		}
+
+		s.barTimer = &config
+		s.DataProcessor.emptyBars = config.EmptyBars
+		s.DataProcessor.session = config.TradingSession
+	}
+}
+
+// WithClock часы таймера баров вместо часов биржи из пула, например, в тестах
+func WithClock(clock Clock) SubscriberOption {
+	return func(s *Subscriber) {
+		s.clock = clock
+	}
+}
+
+type BarCloseReason string
+
+const (
+	TradeBarClose BarCloseReason = "trade" // Пришла сделка следующего бара
+	TimerBarClose BarCloseReason = "timer" // Интервал бара истёк по часам биржи
+)
+
+// BarClosedEvent закрытый бар со всеми расчётами: дельтой, профилем и индикаторами
+type BarClosedEvent struct {
+	SubscriberID SubscriberID   `json:"subscriber_id"`
+	Timeframe    Timeframe      `json:"timeframe"`
+	Reason       BarCloseReason `json:"reason"`
+	Bar          *Bar           `json:"bar"`
+}
+
+// WithBarClosedListener колбэк на каждый закрытый бар основного и старших таймфреймов.
+// Вызывается в обработчике подписчика, долгая работа задержит следующие события.
+func WithBarClosedListener(listener func(BarClosedEvent)) SubscriberOption {
+	return func(s *Subscriber) {
+		s.barListeners = append(s.barListeners, listener)
+	}
+}
+
+func (s *Subscriber) emitBarClosed(event BarClosedEvent) {
+	event.SubscriberID = s.ID
+
+	for _, listener := range s.barListeners {
+		listener(event)
+	}
+}
+
+// runBarTimer раз в таймфрейм, через Delay после конца интервала, отправляет подписчику событие закрытия баров
+func (s *Subscriber) runBarTimer(ctx context.Context) {
+	defer s.wg.Done()
+
+	var clock Clock = SystemClock{}
+	if s.clock != nil {
+		clock = s.clock
+	}
+
+	duration := time.Duration(s.DataProcessor.timeframe) * time.Second
+
+	for {
+		now := clock.Now()
+		// Пока не вышла задержка, ждём закрытия прошлого интервала
+		next := tradeBarTime(now.Add(-s.barTimer.Delay).UnixMilli(), s.DataProcessor.timeframe).Add(duration + s.barTimer.Delay)
+
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if s.IsDone() {
+			return
+		}
+
+		if err := s.handleTimerEvent(clock.Now()); err != nil {
+			s.fail(err)
+			log.Println(s.ID, "error in bar timer:", err)
+			return
+		}
+	}
+}
+
+// handleTimerEvent передаёт подписчику событие таймера
+func (s *Subscriber) handleTimerEvent(now time.Time) error {
+	return s.injectEvent(&ChainEvent{
+		Type:   TimerType,
+		Opcode: BarTimerOpcode,
+		Data:   json.RawMessage(strconv.FormatInt(now.UnixMilli(), 10)),
+	})
+}
+
+// handleBarTimer закрывает бары, время которых вышло к моменту события таймера
+func (s *Subscriber) handleBarTimer(event *ChainEvent) error {
+	var timestamp int64
+	if err := json.Unmarshal(event.Data, &timestamp); err != nil {
+		return err
+	}
+
+	if err := s.DataProcessor.FinalizeBars(time.UnixMilli(timestamp)); err != nil {
+		return err
+	}
+
+	return s.handleClosedBar(TimerBarClose)
+}
+
+// FinalizeBars закрывает бары всех таймфреймов, интервал которых к now истёк.
+// Опоздавшая сделка ещё попадёт в объём, дельту и профиль закрытого бара, но индикаторы и стратегия его повторно не получат.
+// Бары не по времени закрываются только сделками.
+func (p *DataProcessor) FinalizeBars(now time.Time) error {
+	if p.timeBars() {
+		if err := p.finalizeFrame(&p.timeframeBars, now); err != nil {
+			return err
+		}
+	}
+
+	for _, frame := range p.frames {
+		if err := p.finalizeFrame(frame, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *DataProcessor) finalizeFrame(f *timeframeBars, now time.Time) error {
+	if f.lastBar == nil {
+		return nil
+	}
+
+	// Пустые интервалы, которые к now уже закончились
+	if err := p.fillEmptyBars(f, tradeBarTime(now.UnixMilli(), f.timeframe)); err != nil {
+		return err
+	}
+
+	if !f.lastBar.Time.Add(f.duration()).After(now) {
+		f.closeLast()
+	}
+
+	return nil
+}
+
+// fillEmptyBars при FillForwardEmptyBars достраивает бары за интервалы торговой сессии без сделок до before.
+// Пустой бар стоит на цене закрытия прошлого, объём и дельта нулевые.
+func (p *DataProcessor) fillEmptyBars(f *timeframeBars, before time.Time) error {
+	if p.emptyBars != FillForwardEmptyBars || f.lastBar == nil {
+		return nil
+	}
+
+	duration := f.duration()
+	start := f.lastBar.Time.Add(duration)
+
+	// Больше размера очереди баров всё равно не сохранится
+	if limit := before.Add(-time.Duration(f.bars.Size) * duration); start.Before(limit) {
+		start = limit
+	}
+
+	for barTime := start; barTime.Before(before); barTime = barTime.Add(duration) {
+		if !p.session(barTime) {
+			continue
+		}
+
+		price := f.lastBar.Close
+
+		bar := p.NewBlankBar(barTime)
+		bar.Open, bar.High, bar.Low, bar.Close = price, price, price, price
+		bar.Empty = true
+
+		if err := f.push(bar); err != nil {
+			return err
+		}
+
+		if err := f.updateDerived(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *timeframeBars) duration() time.Duration {
+	return time.Duration(f.timeframe) * time.Second
+}
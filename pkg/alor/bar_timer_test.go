@@ -0,0 +1,138 @@
+package alor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinalizeBars(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC).Local() // Бары из сделок в местном времени
+	trade := func(id int64, offset time.Duration, price float64) AllTradesSlimData {
+		return AllTradesSlimData{ID: id, Price: price, Qty: 1, Timestamp: start.Add(offset).UnixMilli(), Side: BuySide}
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithBarTimer(BarTimerConfig{TradingSession: AlwaysTradingSession}))
+		processor := subscriber.DataProcessor
+
+		require.NoError(t, processor.NewAllTrades(trade(1, 10*time.Second, 100)))
+
+		// Интервал ещё идёт
+		require.NoError(t, processor.FinalizeBars(start.Add(59*time.Second)))
+		require.Empty(t, processor.popClosed())
+
+		require.NoError(t, processor.FinalizeBars(start.Add(3*time.Minute)))
+		closed := processor.popClosed()
+		require.Len(t, closed, 1)
+		require.Equal(t, start, closed[0].Time)
+
+		// Опоздавшая сделка попадает в бар, но повторно он не закрывается
+		require.NoError(t, processor.NewAllTrades(trade(2, 20*time.Second, 101)))
+		require.NoError(t, processor.NewAllTrades(trade(3, 4*time.Minute, 102)))
+		require.Empty(t, processor.popClosed())
+		require.Equal(t, int64(2), closed[0].Volume)
+
+		require.Equal(t, 2, processor.bars.GetLength())
+	})
+
+	t.Run("fill forward", func(t *testing.T) {
+		t.Parallel()
+
+		subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithTimeframes(M5TF),
+			WithBarTimer(BarTimerConfig{EmptyBars: FillForwardEmptyBars, TradingSession: AlwaysTradingSession}))
+		processor := subscriber.DataProcessor
+
+		require.NoError(t, processor.NewAllTrades(trade(1, 10*time.Second, 100)))
+		require.NoError(t, processor.FinalizeBars(start.Add(3*time.Minute+time.Second)))
+
+		closed := processor.popClosed()
+		require.Len(t, closed, 3)
+		require.False(t, closed[0].Empty)
+
+		for i, bar := range closed[1:] {
+			require.True(t, bar.Empty)
+			require.Equal(t, start.Add(time.Duration(i+1)*time.Minute), bar.Time)
+			require.Equal(t, 100.0, bar.Close)
+			require.Zero(t, bar.Volume)
+		}
+
+		// Пропуск до следующей сделки тоже достраивается
+		require.NoError(t, processor.NewAllTrades(trade(2, 6*time.Minute, 103)))
+		closed = processor.popClosed()
+		require.Len(t, closed, 3)
+
+		for i, bar := range closed {
+			require.True(t, bar.Empty)
+			require.Equal(t, start.Add(time.Duration(i+3)*time.Minute), bar.Time)
+		}
+
+		bars, err := processor.bars.GetAllBars()
+		require.NoError(t, err)
+		require.Len(t, bars, 7)
+
+		// Старший таймфрейм закрылся сделкой следующего интервала
+		frames := processor.popClosedTimeframeBars()
+		require.Len(t, frames, 1)
+		require.Equal(t, M5TF, frames[0].Timeframe)
+		require.Equal(t, int64(1), frames[0].Bar.Volume)
+	})
+
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithBarTimer(BarTimerConfig{EmptyBars: "zero"}))
+	require.ErrorIs(t, subscriber.err, ErrUnknownEmptyBarPolicy)
+}
+
+func TestSubscriberBarTimer(t *testing.T) {
+	t.Parallel()
+
+	for _, async := range []bool{false, true} {
+		t.Run(map[bool]string{false: "sync", true: "async"}[async], func(t *testing.T) {
+			t.Parallel()
+
+			events := make(chan BarClosedEvent, 10)
+			subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", 1, async,
+				WithBarTimer(BarTimerConfig{Delay: 10 * time.Millisecond}),
+				WithBarClosedListener(func(event BarClosedEvent) { events <- event }),
+			)
+
+			opened := time.Now()
+			require.NoError(t, subscriber.DataProcessor.NewAllTrades(AllTradesSlimData{ID: 1, Price: 100, Qty: 5, Timestamp: opened.UnixMilli(), Side: BuySide}))
+
+			subscriber.Start(context.Background())
+			defer subscriber.Stop()
+
+			select {
+			case event := <-events:
+				require.Equal(t, TimerBarClose, event.Reason)
+				require.Equal(t, subscriber.ID, event.SubscriberID)
+				require.Equal(t, int64(5), event.Bar.Volume)
+				require.Equal(t, tradeBarTime(opened.UnixMilli(), 1), event.Bar.Time)
+			case <-time.After(3 * time.Second):
+				t.Fatal("bar is not closed by timer")
+			}
+		})
+	}
+}
+
+func TestExchangeClock(t *testing.T) {
+	t.Parallel()
+
+	clock := NewExchangeClock(func() (int64, error) {
+		return time.Now().Add(time.Hour).Unix(), nil
+	})
+	require.NoError(t, clock.Sync())
+	require.InDelta(t, time.Hour, clock.Offset(), float64(time.Second))
+
+	// Расхождение в пределах точности сервера не учитывается
+	clock = NewExchangeClock(func() (int64, error) {
+		return time.Now().Unix(), nil
+	})
+	require.NoError(t, clock.Sync())
+	require.Zero(t, clock.Offset())
+}
@@ -0,0 +1,286 @@
+package alor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// BarTransform строит производный бар по бару основного ряда. prev - прошлый бар основного ряда,
+// prevDerived - прошлый производный, у первого бара оба nil. Ряд считается по одному бару, без пересчёта истории.
+type BarTransform interface {
+	Transform(bar, prev, prevDerived *Bar) *Bar
+}
+
+type BarTransformFunc func(bar, prev, prevDerived *Bar) *Bar
+
+func (f BarTransformFunc) Transform(bar, prev, prevDerived *Bar) *Bar {
+	return f(bar, prev, prevDerived)
+}
+
+const (
+	HeikinAshiTransform = "heikin_ashi" // Бары Хейкен-Аши
+	LogReturnsTransform = "log_returns" // Цены как логарифм отношения к закрытию прошлого бара
+	NormalizedTransform = "normalized"  // Цены в долях закрытия прошлого бара
+)
+
+// NewBarTransform преобразование по имени (из HTTP)
+func NewBarTransform(name string) (BarTransform, error) {
+	switch strings.ToLower(name) {
+	case HeikinAshiTransform:
+		return BarTransformFunc(HeikinAshi), nil
+	case LogReturnsTransform:
+		return BarTransformFunc(LogReturns), nil
+	case NormalizedTransform:
+		return BarTransformFunc(NormalizedBars), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownBarTransform, name)
+}
+
+// WithBarTransform ведёт рядом с основными барами ряд производных под именем name
+func WithBarTransform(name string, transform BarTransform) SubscriberOption {
+	return func(s *Subscriber) {
+		s.DataProcessor.AddBarTransform(name, transform)
+	}
+}
+
+// HeikinAshi Open - середина тела прошлого бара Хейкен-Аши, Close - среднее OHLC бара,
+// High и Low - экстремумы бара вместе с новыми Open и Close
+func HeikinAshi(bar, _, prevDerived *Bar) *Bar {
+	derived := derivedBar(bar)
+
+	derived.Close = (bar.Open + bar.High + bar.Low + bar.Close) / 4
+	derived.Open = (bar.Open + bar.Close) / 2
+	if prevDerived != nil {
+		derived.Open = (prevDerived.Open + prevDerived.Close) / 2
+	}
+
+	derived.High = max(bar.High, derived.Open, derived.Close)
+	derived.Low = min(bar.Low, derived.Open, derived.Close)
+
+	return derived
+}
+
+// LogReturns цены бара как ln(цена / закрытие прошлого бара), у первого бара - к его открытию
+func LogReturns(bar, prev, _ *Bar) *Bar {
+	derived := derivedBar(bar)
+	base := transformBase(bar, prev)
+
+	logReturn := func(price float64) float64 {
+		if base <= 0 || price <= 0 {
+			return 0
+		}
+
+		return math.Log(price / base)
+	}
+
+	derived.Open, derived.High, derived.Low, derived.Close = logReturn(bar.Open), logReturn(bar.High), logReturn(bar.Low), logReturn(bar.Close)
+
+	return derived
+}
+
+// NormalizedBars цены бара в долях закрытия прошлого бара, у первого бара - его открытия
+func NormalizedBars(bar, prev, _ *Bar) *Bar {
+	derived := derivedBar(bar)
+	base := transformBase(bar, prev)
+
+	if base > 0 {
+		derived.Open, derived.High, derived.Low, derived.Close = bar.Open/base, bar.High/base, bar.Low/base, bar.Close/base
+	}
+
+	return derived
+}
+
+func transformBase(bar, prev *Bar) float64 {
+	if prev != nil {
+		return prev.Close
+	}
+
+	return bar.Open
+}
+
+// derivedBar производный бар с временем и объёмами исходного, цены заполняет преобразование.
+// Профиль, стакан и индикаторы остаются у исходного бара.
+func derivedBar(bar *Bar) *Bar {
+	return &Bar{
+		Volume:    bar.Volume,
+		Trades:    bar.Trades,
+		Turnover:  bar.Turnover,
+		Empty:     bar.Empty,
+		Time:      bar.Time,
+		Timestamp: bar.Timestamp,
+		Delta:     bar.Delta,
+	}
+}
+
+// TransformBars производные бары по готовому ряду, например, для истории без зарегистрированного преобразования
+func TransformBars(bars []*Bar, transform BarTransform) []*Bar {
+	derived := make([]*Bar, 0, len(bars))
+
+	var prev, prevDerived *Bar
+	for _, bar := range bars {
+		prevDerived = transform.Transform(bar, prev, prevDerived)
+		prev = bar
+
+		derived = append(derived, prevDerived)
+	}
+
+	return derived
+}
+
+// derivedBars ряд производных баров, идёт вровень с барами таймфрейма
+type derivedBars struct {
+	name      string
+	transform BarTransform
+	bars      *BarQueue
+	source    *Bar // Исходный бар последнего производного
+}
+
+// addTransform добавляет ряд и сразу считает его по уже построенным барам
+func (f *timeframeBars) addTransform(name string, transform BarTransform) {
+	series := &derivedBars{
+		name:      name,
+		transform: transform,
+		bars:      NewBarQueue(f.bars.Size),
+	}
+
+	var prev *Bar
+	for _, bar := range f.bars.Elements {
+		prevDerived, _ := series.bars.GetBarFromEnd(0)
+		_ = series.bars.Enqueue(transform.Transform(bar, prev, prevDerived))
+		prev = bar
+	}
+
+	series.source = f.lastBar
+
+	for i, existing := range f.derived {
+		if existing.name == name {
+			f.derived[i] = series
+			return
+		}
+	}
+
+	f.derived = append(f.derived, series)
+}
+
+// updateDerived пересчитывает производные бары по формирующемуся бару: новый бар добавляется, изменённый заменяется
+func (f *timeframeBars) updateDerived() error {
+	if f.lastBar == nil {
+		return nil
+	}
+
+	prev, _ := f.bars.GetBarFromEnd(1)
+
+	for _, series := range f.derived {
+		if series.source == f.lastBar {
+			last, err := series.bars.GetBarFromEnd(0)
+			if err != nil {
+				return err
+			}
+
+			prevDerived, _ := series.bars.GetBarFromEnd(1)
+			*last = *series.transform.Transform(f.lastBar, prev, prevDerived)
+
+			continue
+		}
+
+		prevDerived, _ := series.bars.GetBarFromEnd(0)
+		if err := series.bars.Enqueue(series.transform.Transform(f.lastBar, prev, prevDerived)); err != nil {
+			return err
+		}
+
+		series.source = f.lastBar
+	}
+
+	return nil
+}
+
+func (f *timeframeBars) derivedSeries(name string) *derivedBars {
+	for _, series := range f.derived {
+		if series.name == name {
+			return series
+		}
+	}
+
+	return nil
+}
+
+// AddBarTransform ведёт ряд производных баров основного таймфрейма
+func (p *DataProcessor) AddBarTransform(name string, transform BarTransform) {
+	p.addTransform(name, transform)
+}
+
+// AddTimeframeBarTransform ведёт ряд производных баров старшего таймфрейма
+func (p *DataProcessor) AddTimeframeBarTransform(timeframe Timeframe, name string, transform BarTransform) error {
+	if timeframe == p.timeframe {
+		p.AddBarTransform(name, transform)
+		return nil
+	}
+
+	frame := p.frame(timeframe)
+	if frame == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownTimeframe, timeframe)
+	}
+
+	frame.addTransform(name, transform)
+
+	return nil
+}
+
+// GetDerivedBars очередь производных баров name таймфрейма
+func (p *DataProcessor) GetDerivedBars(timeframe Timeframe, name string) (*BarQueue, error) {
+	frame := &p.timeframeBars
+	if timeframe != p.timeframe {
+		if frame = p.frame(timeframe); frame == nil {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownTimeframe, timeframe)
+		}
+	}
+
+	series := frame.derivedSeries(name)
+	if series == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBarTransform, name)
+	}
+
+	return series.bars, nil
+}
+
+// GetDerivedBar производный бар основного таймфрейма с конца: 0 - по формирующемуся, 1 - по последнему закрытому и т.д.
+func (p *DataProcessor) GetDerivedBar(name string, index int64) (*Bar, error) {
+	return p.GetTimeframeDerivedBar(p.timeframe, name, index)
+}
+
+// GetTimeframeDerivedBar производный бар таймфрейма с конца
+func (p *DataProcessor) GetTimeframeDerivedBar(timeframe Timeframe, name string, index int64) (*Bar, error) {
+	bars, err := p.GetDerivedBars(timeframe, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return bars.GetBarFromEnd(index)
+}
+
+// TransformedBars копии производных баров основного таймфрейма: последний бар ряда меняется вместе с формирующимся.
+// Если ряд name не ведётся, он считается по всем барам встроенным преобразованием с тем же именем.
+func (p *DataProcessor) TransformedBars(name string) ([]*Bar, error) {
+	if series := p.derivedSeries(name); series != nil {
+		bars, err := series.bars.GetAllBars()
+		if err != nil {
+			return nil, err
+		}
+
+		return cloneBars(bars), nil
+	}
+
+	transform, err := NewBarTransform(name)
+	if err != nil {
+		return nil, err
+	}
+
+	bars, err := p.bars.GetAllBars()
+	if err != nil {
+		return nil, err
+	}
+
+	return TransformBars(bars, transform), nil
+}
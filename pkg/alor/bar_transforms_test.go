@@ -0,0 +1,86 @@
+package alor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeikinAshi(t *testing.T) {
+	t.Parallel()
+
+	first := HeikinAshi(&Bar{Open: 10, High: 14, Low: 9, Close: 12}, nil, nil)
+	require.Equal(t, 11.0, first.Open)
+	require.Equal(t, 11.25, first.Close)
+	require.Equal(t, 14.0, first.High)
+	require.Equal(t, 9.0, first.Low)
+
+	second := HeikinAshi(&Bar{Open: 12, High: 13, Low: 11.5, Close: 11.5}, nil, first)
+	require.Equal(t, 11.125, second.Open)
+	require.Equal(t, 12.0, second.Close)
+	require.Equal(t, 13.0, second.High)
+	require.Equal(t, 11.125, second.Low)
+}
+
+func TestBarTransforms(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false,
+		WithBarTransform(HeikinAshiTransform, BarTransformFunc(HeikinAshi)),
+		WithBarTransform(LogReturnsTransform, BarTransformFunc(LogReturns)),
+	)
+	processor := subscriber.DataProcessor
+
+	prices := []float64{100, 102, 99, 101, 104, 103, 98, 100, 105, 107}
+	for i, price := range prices {
+		timestamp := time.Date(2025, 3, 3, 10, i/2, 0, 0, time.UTC).UnixMilli()
+		require.NoError(t, processor.NewAllTrades(AllTradesSlimData{ID: int64(i + 1), Price: price, Qty: 1, Timestamp: timestamp, Side: BuySide}))
+	}
+
+	bars, err := processor.bars.GetAllBars()
+	require.NoError(t, err)
+	require.Len(t, bars, 5)
+
+	// Ряд, посчитанный по сделкам, совпадает с пересчётом по готовым барам
+	derived, err := processor.GetDerivedBars(M1TF, HeikinAshiTransform)
+	require.NoError(t, err)
+
+	incremental, err := derived.GetAllBars()
+	require.NoError(t, err)
+	require.Equal(t, TransformBars(bars, BarTransformFunc(HeikinAshi)), incremental)
+
+	// Преобразование, добавленное позже, досчитывается по истории
+	processor.AddBarTransform("late", BarTransformFunc(HeikinAshi))
+	late, err := processor.GetDerivedBar("late", 0)
+	require.NoError(t, err)
+	require.Equal(t, incremental[4], late)
+
+	ranged, err := processor.bars.GetHeikenAshiBarsRange(1, 4)
+	require.NoError(t, err)
+	require.Equal(t, incremental[1:4], ranged)
+
+	logReturn, err := processor.GetDerivedBar(LogReturnsTransform, 0)
+	require.NoError(t, err)
+	require.InDelta(t, math.Log(107.0/100), logReturn.Close, 1e-9)
+	require.Equal(t, bars[4].Volume, logReturn.Volume)
+
+	// Ряд отдаётся копией: формирующийся бар дальше меняет обработчик
+	registered, err := processor.TransformedBars(HeikinAshiTransform)
+	require.NoError(t, err)
+	require.Equal(t, incremental, registered)
+	require.NotSame(t, incremental[4], registered[4])
+
+	// Без зарегистрированного ряда встроенное преобразование считается по запросу
+	normalized, err := processor.TransformedBars(NormalizedTransform)
+	require.NoError(t, err)
+	require.Len(t, normalized, 5)
+	require.InDelta(t, 100.0/103, normalized[3].Close, 1e-9)
+
+	_, err = processor.TransformedBars("renko")
+	require.ErrorIs(t, err, ErrUnknownBarTransform)
+
+	_, err = processor.GetDerivedBar(NormalizedTransform, 0)
+	require.ErrorIs(t, err, ErrUnknownBarTransform)
+}
@@ -1,22 +1,64 @@
 package alor
 
 import (
+	"maps"
 	"strconv"
 	"time"
 )
 
 type Bar struct {
-	High          float64       `json:"high"`
-	Open          float64       `json:"open"`
-	Close         float64       `json:"close"`
-	Low           float64       `json:"low"`
-	Volume        int64         `json:"volume"`
-	Time          time.Time     `json:"time"`
-	Timestamp     int64         `json:"timestamp"`
-	Delta         Delta         `json:"delta"`
-	MarketProfile MarketProfile `json:"market_profile"`
-	OrderFlow     OrderFlow     `json:"order_flow"`
-	Indicators    []bool        `json:"indicators"`
+	High          float64                   `json:"high"`
+	Open          float64                   `json:"open"`
+	Close         float64                   `json:"close"`
+	Low           float64                   `json:"low"`
+	Volume        int64                     `json:"volume"`
+	Trades        int64                     `json:"trades"`          // Сколько сделок в баре
+	Turnover      float64                   `json:"turnover"`        // Сумма цена × лоты, без размера лота
+	Empty         bool                      `json:"empty,omitempty"` // Достроен за интервал без сделок
+	Time          time.Time                 `json:"time"`
+	Timestamp     int64                     `json:"timestamp"`
+	Delta         Delta                     `json:"delta"`
+	MarketProfile MarketProfile             `json:"market_profile"`
+	OrderFlow     OrderFlow                 `json:"order_flow"`
+	Indicators    map[string]IndicatorValue `json:"indicators"`
+	Footprint     *Footprint                `json:"footprint,omitempty"` // Кластеры закрытого бара при WithFootprint
+}
+
+// Clone копия бара, которую можно отдавать наружу: формирующийся бар дальше меняет обработчик подписчика.
+// Кластеры закрытого бара не меняются и остаются общими.
+func (b *Bar) Clone() *Bar {
+	bar := *b
+
+	bar.MarketProfile.Values = maps.Clone(b.MarketProfile.Values)
+
+	bar.OrderFlow.LastVal = maps.Clone(b.OrderFlow.LastVal)
+	bar.OrderFlow.ValuesInc = maps.Clone(b.OrderFlow.ValuesInc)
+	bar.OrderFlow.ValuesDec = maps.Clone(b.OrderFlow.ValuesDec)
+	bar.OrderFlow.LastAsks = maps.Clone(b.OrderFlow.LastAsks)
+	bar.OrderFlow.ValuesAsksDec = maps.Clone(b.OrderFlow.ValuesAsksDec)
+	bar.OrderFlow.ValuesAsksInc = maps.Clone(b.OrderFlow.ValuesAsksInc)
+	bar.OrderFlow.LastBids = maps.Clone(b.OrderFlow.LastBids)
+	bar.OrderFlow.ValuesBidsDec = maps.Clone(b.OrderFlow.ValuesBidsDec)
+	bar.OrderFlow.ValuesBidsInc = maps.Clone(b.OrderFlow.ValuesBidsInc)
+
+	if b.Indicators != nil {
+		bar.Indicators = make(map[string]IndicatorValue, len(b.Indicators))
+		for name, value := range b.Indicators {
+			bar.Indicators[name] = maps.Clone(value)
+		}
+	}
+
+	return &bar
+}
+
+// cloneBars копии баров ряда
+func cloneBars(bars []*Bar) []*Bar {
+	copies := make([]*Bar, 0, len(bars))
+	for _, bar := range bars {
+		copies = append(copies, bar.Clone())
+	}
+
+	return copies
 }
 
 type Delta struct {
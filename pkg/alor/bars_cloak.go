@@ -8,6 +8,7 @@ import (
 var (
 	ErrQueueOverFlow  = errors.New("queue is overflow")
 	ErrQueueUnderFlow = errors.New("queue is underflow")
+	ErrQueueClosed    = errors.New("queue is closed")
 )
 
 type BarQueue struct {
@@ -34,11 +35,10 @@ func (q *BarQueue) Enqueue(bar *Bar) error {
 
 	if q.GetLength() == q.Size {
 		// return ErrQueueOverFlow
-		// Если максимальный размер, удаляем самый старый элемент
-		dequeueBar, err := q.Dequeue()
-		if err == nil {
-			delete(q.Tags, dequeueBar.Timestamp)
-		}
+		// Если максимальный размер, удаляем самый старый элемент. Dequeue берёт тот же мьютекс, поэтому на месте
+		delete(q.Tags, q.Elements[0].Timestamp)
+		q.Elements = q.Elements[1:]
+		q.Len--
 	}
 
 	q.Elements = append(q.Elements, bar)
@@ -103,6 +103,15 @@ func (q *BarQueue) GetBarByIndex(index int64) (*Bar, error) {
 	return q.Elements[index], nil
 }
 
+// GetBarFromEnd бар по индексу с конца: 0 - последний (формирующийся), 1 - предыдущий и т.д.
+func (q *BarQueue) GetBarFromEnd(index int64) (*Bar, error) {
+	if index < 0 || index >= int64(len(q.Elements)) {
+		return nil, errors.New("index out of range")
+	}
+
+	return q.Elements[int64(len(q.Elements))-1-index], nil
+}
+
 func (q *BarQueue) GetBarByTimestamp(timestamp int64) (*Bar, error) {
 	bar, ok := q.Tags[timestamp]
 	if !ok {
@@ -132,31 +141,12 @@ func (q *BarQueue) GetBarsRange(start, end int64) ([]*Bar, error) {
 	return q.Elements[start:end], nil
 }
 
+// GetHeikenAshiBarsRange бары Хейкен-Аши с start до end, как у GetBarsRange.
+// Ряд рекурсивный, поэтому считается с первого бара очереди.
 func (q *BarQueue) GetHeikenAshiBarsRange(start, end int64) ([]*Bar, error) {
-	// Open = (Цена открытия предыдущей свечи + Закрытие предыдущей свечи) / 2
-	// Close = (Открытие свечи + Максимальная точка + Минимальная точка + Закрытие свечи) / 4
-	// Min = [Минимально значение из (Минимум, Открытие, Закрытие)]
-	// Max = [Максимальное значение из (Максимум, Открытие, Закрытие)]
-
-	heikenBars := make([]*Bar, end-start)
-	for index, bar := range q.Elements[start:end] {
-		if index == 0 {
-			heikenBars = append(heikenBars, bar)
-			continue
-		}
-		barOpen := (heikenBars[index-1].Open + heikenBars[index-1].Close) / 2
-		barClose := (bar.Open + bar.Close + bar.High + bar.Low) / 4
-		barMax := max(bar.Open, bar.Close, bar.High)
-		barMin := min(bar.Open, bar.Close, bar.Low)
-		newHeikenBar := &Bar{
-			Open:   barOpen,
-			Close:  barClose,
-			High:   barMax,
-			Low:    barMin,
-			Volume: bar.Volume,
-		}
-		heikenBars = append(heikenBars, newHeikenBar)
+	if _, err := q.GetBarsRange(start, end); err != nil {
+		return nil, err
 	}
 
-	return heikenBars, nil
+	return TransformBars(q.Elements[:end], BarTransformFunc(HeikinAshi))[start:], nil
 }
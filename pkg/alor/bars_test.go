@@ -0,0 +1,32 @@
+package alor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarClone(t *testing.T) {
+	t.Parallel()
+
+	bar := &Bar{
+		Close:         100,
+		MarketProfile: MarketProfile{Values: map[string]MarketProfileUnit{"100": {Buy: 1, Total: 1}}},
+		OrderFlow:     OrderFlow{ValuesInc: map[string]int64{"100": 5}},
+		Indicators:    map[string]IndicatorValue{"sma": {IndicatorMainLine: 99}},
+		Footprint:     &Footprint{POCPrice: 100},
+	}
+
+	clone := bar.Clone()
+	require.Equal(t, bar, clone)
+
+	// Формирующийся бар меняется дальше, копия - нет
+	bar.MarketProfile.AddValue(101, 2, SellSide)
+	bar.OrderFlow.ValuesInc["100"] = 7
+	bar.Indicators["sma"][IndicatorMainLine] = 101
+
+	require.Len(t, clone.MarketProfile.Values, 1)
+	require.EqualValues(t, 5, clone.OrderFlow.ValuesInc["100"])
+	require.Equal(t, 99.0, clone.Indicators["sma"].Value())
+	require.Same(t, bar.Footprint, clone.Footprint)
+}
@@ -11,10 +11,12 @@ import (
 type Client struct {
 	Config      Config
 	Hosts       Hosts
-	Token       Token
+	Token       *Token
 	Client      *http.Client
-	Websocket   *Websocket
-	Subscribers Subscribers /// Где, блядь, эти ебаные подписчики должны быть?!
+	Pool        *WebsocketPool // Соединения вебсокета
+	Securities  *Securities    // Справочник инструментов
+	Clock       *ExchangeClock // Часы биржи для таймера баров
+	Subscribers Subscribers    /// Где, блядь, эти ебаные подписчики должны быть?!
 	mu          sync.Mutex
 }
 
@@ -25,6 +27,10 @@ func New(config Config) *Client {
 		hosts = circuits.Production
 	}
 
+	if config.Hosts != nil {
+		hosts = *config.Hosts
+	}
+
 	httpClient := &http.Client{Transport: http.DefaultTransport, Timeout: 30 * time.Second}
 	//httpClient := &http.Client{Transport: &http.Transport{
 	//	// Proxy: ProxyFromEnvironment,
@@ -42,14 +48,34 @@ func New(config Config) *Client {
 	//}}
 	// httpClient := &http.Client{Transport: &http.Transport{}}
 
-	return &Client{
+	token := NewToken(config.RefreshToken, config.RefreshTokenExp)
+	pool := NewWebsocketPool(hosts.Websocket, config.Pool, config.Heartbeat)
+
+	// Новый токен сразу уходит в повторные запросы подписок
+	token.OnRefresh(func() {
+		if err := pool.Reauthorize(token); err != nil {
+			log.Println("websocket reauthorize failed:", err)
+		}
+	})
+
+	client := &Client{
 		Config:      config,
 		Hosts:       hosts,
-		Token:       NewToken(config.RefreshToken, config.RefreshTokenExp),
+		Token:       token,
 		Client:      httpClient,
-		Websocket:   NewWebsocket(hosts.Websocket),
+		Pool:        pool,
+		Securities:  pool.securities,
 		Subscribers: NewSubscribers(),
 	}
+
+	// Сделки, пропущенные за время разрыва, догружаются через REST
+	pool.SetAllTradesHistory(client.GetAllTrades)
+
+	// Бары закрываются по часам биржи, а не по системным
+	client.Clock = NewExchangeClock(client.GetUnixTimestamp)
+	pool.SetClock(client.Clock)
+
+	return client
 }
 
 func (c *Client) Connect(ctx context.Context, websocket bool) error {
@@ -58,44 +84,47 @@ func (c *Client) Connect(ctx context.Context, websocket bool) error {
 		return err
 	}
 
+	// Обновляем access токен до истечения
+	go c.KeepTokenFresh(ctx)
+
+	// Без поправки бары закроются по системным часам
+	if err := c.Clock.Sync(); err != nil {
+		log.Println("exchange clock sync failed:", err)
+	}
+
 	if websocket {
-		// Инициализируем первое подключение
-		if err := c.Websocket.Connect(); err != nil {
+		// Инициализируем первое подключение, остальные пул откроет по мере роста подписок
+		if err := c.Pool.Connect(ctx, c.Token); err != nil {
 			log.Fatal("Ошибка первоначального подключения:", err)
 		}
-
-		// Запускаем горутину для переподключения
-		go c.Websocket.ReconnectHandler(ctx, c.Token)
-
-		// Запускаем разбор очереди
-		go c.Websocket.SortQueue(ctx, c.Token)
-		// Запускаем слушатель сообщений
-		go c.Websocket.Listen()
 	}
 
 	return nil
 }
 
-func (c *Client) Stop() {
-	token, err := c.Token.GetAccessToken()
-	if err != nil {
-		return
-	}
+// OnConnectionEvent регистрирует колбэк на подключения, разрывы и переподписки вебсокета
+func (c *Client) OnConnectionEvent(listener func(ConnectionEvent)) {
+	c.Pool.OnEvent(listener)
+}
 
-	if err := c.Websocket.RemoveAllSubscribers(token); err != nil {
-		log.Println("ahalai mahalai")
+func (c *Client) Stop() {
+	// Без токена отписаться нельзя, но воркеры подписчиков и соединения всё равно останавливаются
+	if token, err := c.Token.GetAccessToken(); err == nil {
+		if err := c.Pool.RemoveAllSubscribers(token); err != nil {
+			log.Println("ahalai mahalai")
+		}
 	}
-	if err := c.Websocket.Close(); err != nil {
+	if err := c.Pool.Close(); err != nil {
 		log.Println("abra kadabra")
 	}
 }
 
 func (c *Client) GetSubscriber(subscriberID SubscriberID) (*Subscriber, error) {
-	return c.Websocket.GetSubscriber(subscriberID)
+	return c.Pool.GetSubscriber(subscriberID)
 }
 
 func (c *Client) AddSubscriber(subscriber *Subscriber) error {
-	return c.Websocket.AddSubscriber(c.Token, subscriber)
+	return c.Pool.AddSubscriber(c.Token, subscriber)
 }
 
 func (c *Client) RemoveSubscriber(subscriberID SubscriberID) error {
@@ -104,7 +133,7 @@ func (c *Client) RemoveSubscriber(subscriberID SubscriberID) error {
 		return err
 	}
 
-	return c.Websocket.RemoveSubscriber(token, subscriberID)
+	return c.Pool.RemoveSubscriber(token, subscriberID)
 }
 
 func (c *Client) RemoveAllSubscribers() error {
@@ -113,11 +142,11 @@ func (c *Client) RemoveAllSubscribers() error {
 		return err
 	}
 
-	return c.Websocket.RemoveAllSubscribers(token)
+	return c.Pool.RemoveAllSubscribers(token)
 }
 
 func (c *Client) GetSubscribers() []*Subscriber {
-	subscribers, err := c.Websocket.GetSubscribers()
+	subscribers, err := c.Pool.GetSubscribers()
 	if err != nil {
 		return nil
 	}
@@ -131,5 +160,15 @@ func (c *Client) GetSubscribers() []*Subscriber {
 }
 
 func (c *Client) GetAllSubscriberBars(subscriberID SubscriberID) ([]*Bar, error) {
-	return c.Websocket.GetAllStrategyBars(subscriberID)
+	return c.Pool.GetAllStrategyBars(subscriberID)
+}
+
+// GetSubscriberFootprints кластеры последних count баров подписчика, timeframe 0 - основной таймфрейм
+func (c *Client) GetSubscriberFootprints(subscriberID SubscriberID, timeframe Timeframe, count int) ([]BarFootprint, error) {
+	return c.Pool.GetStrategyFootprints(subscriberID, timeframe, count)
+}
+
+// GetTransformedSubscriberBars бары подписчика после преобразования, например, Хейкен-Аши
+func (c *Client) GetTransformedSubscriberBars(subscriberID SubscriberID, transform string) ([]*Bar, error) {
+	return c.Pool.GetTransformedStrategyBars(subscriberID, transform)
 }
@@ -0,0 +1,90 @@
+package alor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock источник текущего времени. В бою - системные часы, в бэктесте - время последнего события.
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// SimulatedClock часы, которые двигает бэктест
+type SimulatedClock struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+func NewSimulatedClock(now time.Time) *SimulatedClock {
+	return &SimulatedClock{now: now}
+}
+
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.now
+}
+
+// Set переводит часы, назад время не идёт
+func (c *SimulatedClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.After(c.now) {
+		c.now = now
+	}
+}
+
+// ExchangeClock системные часы с поправкой на часы биржи. Поправку считает Sync по времени сервера,
+// по ним бары выравниваются так же, как время сделок.
+type ExchangeClock struct {
+	source func() (int64, error)
+	offset atomic.Int64
+}
+
+// NewExchangeClock часы по источнику времени биржи в Unix секундах, подходит Client.GetUnixTimestamp
+func NewExchangeClock(source func() (int64, error)) *ExchangeClock {
+	return &ExchangeClock{source: source}
+}
+
+func (c *ExchangeClock) Now() time.Time {
+	return time.Now().UTC().Add(c.Offset())
+}
+
+// Offset насколько часы биржи впереди системных
+func (c *ExchangeClock) Offset() time.Duration {
+	return time.Duration(c.offset.Load())
+}
+
+// Sync запрашивает время биржи и пересчитывает поправку. Сервер отдаёт целые секунды,
+// поэтому расхождение меньше секунды не отличить от точности ответа и оно не учитывается.
+func (c *ExchangeClock) Sync() error {
+	before := time.Now()
+
+	timestamp, err := c.source()
+	if err != nil {
+		return err
+	}
+
+	// Ответ сформирован где-то посередине запроса
+	local := before.Add(time.Since(before) / 2)
+
+	// Секунды сервера округлены вниз, в среднем они отстают на полсекунды
+	offset := time.Unix(timestamp, 0).Add(500 * time.Millisecond).Sub(local)
+	if offset.Abs() < time.Second {
+		offset = 0
+	}
+
+	c.offset.Store(int64(offset))
+
+	return nil
+}
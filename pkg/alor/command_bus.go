@@ -1 +1,86 @@
 package alor
+
+// CommandBus выставление, изменение и снятие заявок через Commands API брокера.
+// Реализуется Client, а для тестов и симуляций - любой совместимой заглушкой.
+type CommandBus interface {
+	CreateMarketOrder(params MarketOrderParams) (OrderID, error)
+	CreateLimitOrder(params LimitOrderParams) (OrderID, error)
+	CreateStopOrder(params StopOrderParams) (OrderID, error)
+	CreateStopLimitOrder(params StopLimitOrderParams) (OrderID, error)
+	ModifyMarketOrder(orderID OrderID, params MarketOrderParams) (OrderID, error)
+	ModifyLimitOrder(orderID OrderID, params LimitOrderParams) (OrderID, error)
+	ModifyStopOrder(orderID OrderID, params StopOrderParams) (OrderID, error)
+	ModifyStopLimitOrder(orderID OrderID, params StopLimitOrderParams) (OrderID, error)
+	CancelOrder(params CancelOrderParams) error
+}
+
+type OrderID string
+
+type TimeInForce string
+
+var (
+	OneDayTimeInForce            TimeInForce = "oneday"            // До конца торговой сессии
+	ImmediateOrCancelTimeInForce TimeInForce = "immediateorcancel" // Снять остаток заявки
+	FillOrKillTimeInForce        TimeInForce = "fillorkill"        // Исполнить целиком или отклонить
+	GoodTillCancelledTimeInForce TimeInForce = "goodtillcancelled" // Активна до отмены
+)
+
+type StopCondition string
+
+var (
+	MoreStopCondition        StopCondition = "More"        // Цена срабатывания больше текущей цены
+	LessStopCondition        StopCondition = "Less"        // Цена срабатывания меньше текущей цены
+	MoreOrEqualStopCondition StopCondition = "MoreOrEqual" // Цена срабатывания больше или равна текущей цене
+	LessOrEqualStopCondition StopCondition = "LessOrEqual" // Цена срабатывания меньше или равна текущей цене
+)
+
+// OrderTarget общая часть всех заявок: куда и от чьего имени отправляем
+type OrderTarget struct {
+	Portfolio string    // Идентификатор клиентского портфеля
+	Exchange  Exchange  // Биржа
+	Code      string    // Тикер (Код финансового инструмента)
+	Board     string    // Код режима торгов (Борд)
+	Side      OrderSide // Направление сделки
+	Quantity  int64     // Количество (лоты)
+	Comment   string    // Пользовательский комментарий к заявке
+}
+
+type MarketOrderParams struct {
+	OrderTarget
+	TimeInForce TimeInForce // Условие по времени действия заявки
+	AllowMargin bool        // Разрешить маржинальную торговлю
+}
+
+type LimitOrderParams struct {
+	OrderTarget
+	Price           float64     // Цена
+	TimeInForce     TimeInForce // Условие по времени действия заявки
+	IcebergFixed    int64       // Видимая постоянная часть айсберг-заявки в лотах
+	IcebergVariance float64     // Амплитуда отклонения (в % от IcebergFixed) случайной надбавки к видимой части айсберг-заявки
+	AllowMargin     bool        // Разрешить маржинальную торговлю
+}
+
+type StopOrderParams struct {
+	OrderTarget
+	Condition         StopCondition // Условие срабатывания стоп-заявки
+	TriggerPrice      float64       // Стоп-цена
+	StopEndUnixTime   int64         // Срок действия (UTC) в формате Unix Time seconds
+	ProtectingSeconds int64         // Защитное время. Непрерывный период времени в секундах, в течение которого рыночная цена инструмента должна соответствовать указанным в заявке условиям
+	Activate          bool          // Флаг указывает, создать активную заявку, или не активную
+	AllowMargin       bool          // Разрешить маржинальную торговлю
+}
+
+type StopLimitOrderParams struct {
+	StopOrderParams
+	Price           float64     // Цена выставления лимитной заявки
+	TimeInForce     TimeInForce // Условие по времени действия заявки
+	IcebergFixed    int64       // Видимая постоянная часть айсберг-заявки в лотах
+	IcebergVariance float64     // Амплитуда отклонения случайной надбавки к видимой части айсберг-заявки
+}
+
+type CancelOrderParams struct {
+	OrderID   OrderID  // Идентификатор заявки
+	Portfolio string   // Идентификатор клиентского портфеля
+	Exchange  Exchange // Биржа
+	Stop      bool     // Заявка является стоп-заявкой
+}
@@ -0,0 +1,135 @@
+package alor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newCommandBusTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &Client{
+		Hosts:  Hosts{Data: server.URL},
+		Token:  &Token{Access: "access_token"},
+		Client: server.Client(),
+	}
+}
+
+func TestCreateLimitOrder(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gotMethod, gotPath, gotReqID, gotAuth string
+		gotRequest                            LimitOrderRequest
+	)
+
+	client := newCommandBusTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		gotReqID = r.Header.Get("X-REQID")
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gotRequest)
+
+		_, _ = w.Write([]byte(`{"message":"success","orderNumber":"18995978560"}`))
+	})
+
+	orderID, err := client.CreateLimitOrder(LimitOrderParams{
+		OrderTarget: OrderTarget{
+			Portfolio: "D39004",
+			Exchange:  MOEXExchange,
+			Code:      "SBER",
+			Board:     "TQBR",
+			Side:      BuySide,
+			Quantity:  2,
+		},
+		Price:       250.5,
+		TimeInForce: OneDayTimeInForce,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, OrderID("18995978560"), orderID)
+	require.Equal(t, http.MethodPost, gotMethod)
+	require.Equal(t, "/commandapi/warptrans/TRADE/v2/client/orders/actions/limit", gotPath)
+	require.True(t, strings.HasPrefix(gotReqID, "D39004;"))
+	require.Equal(t, "Bearer access_token", gotAuth)
+	require.Equal(t, "SBER", gotRequest.Instrument.Symbol)
+	require.Equal(t, "TQBR", gotRequest.Instrument.InstrumentGroup)
+	require.Equal(t, "D39004", gotRequest.User.Portfolio)
+	require.Equal(t, 250.5, gotRequest.Price)
+	require.Equal(t, int64(2), gotRequest.Quantity)
+}
+
+func TestModifyStopLimitOrder(t *testing.T) {
+	t.Parallel()
+
+	var gotMethod, gotPath string
+
+	client := newCommandBusTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+
+		_, _ = w.Write([]byte(`{"message":"success","orderNumber":"2"}`))
+	})
+
+	orderID, err := client.ModifyStopLimitOrder("1", StopLimitOrderParams{
+		StopOrderParams: StopOrderParams{
+			OrderTarget: OrderTarget{Portfolio: "D39004", Exchange: MOEXExchange, Code: "SBER", Board: "TQBR", Side: SellSide, Quantity: 1},
+			Condition:   LessOrEqualStopCondition,
+		},
+		Price: 240,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, OrderID("2"), orderID)
+	require.Equal(t, http.MethodPut, gotMethod)
+	require.Equal(t, "/commandapi/warptrans/TRADE/v2/client/orders/actions/stopLimit/1", gotPath)
+}
+
+func TestCancelOrder(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gotMethod, gotPath string
+		gotQuery           map[string][]string
+	)
+
+	client := newCommandBusTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+
+		_, _ = w.Write([]byte(`{"message":"success","orderNumber":"42"}`))
+	})
+
+	err := client.CancelOrder(CancelOrderParams{OrderID: "42", Portfolio: "D39004", Exchange: MOEXExchange, Stop: true})
+
+	require.NoError(t, err)
+	require.Equal(t, http.MethodDelete, gotMethod)
+	require.Equal(t, "/commandapi/warptrans/TRADE/v2/client/orders/42", gotPath)
+	require.Equal(t, []string{"D39004"}, gotQuery["portfolio"])
+	require.Equal(t, []string{"true"}, gotQuery["stop"])
+}
+
+func TestCommandRejected(t *testing.T) {
+	t.Parallel()
+
+	client := newCommandBusTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"OrderToWrongBoard","message":"Instrument SBER not found on board TQBR"}`))
+	})
+
+	orderID, err := client.CreateMarketOrder(MarketOrderParams{
+		OrderTarget: OrderTarget{Portfolio: "D39004", Exchange: MOEXExchange, Code: "SBER", Board: "TQBR", Side: BuySide, Quantity: 1},
+	})
+
+	require.ErrorIs(t, err, ErrCommandFailed)
+	require.Contains(t, err.Error(), "Instrument SBER not found on board TQBR")
+	require.Empty(t, orderID)
+}
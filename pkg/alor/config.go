@@ -6,4 +6,10 @@ type Config struct {
 	RefreshToken    string
 	RefreshTokenExp time.Time
 	DevCircuit      bool
+	Hosts           *Hosts          // Свои адреса вместо контура брокера, например, alortest.Server
+	Pool            PoolLimits      // Ограничения пула соединений вебсокета, по умолчанию DefaultPoolLimits
+	Heartbeat       HeartbeatConfig // Ping, поиск зависших подписок и переподключение, по умолчанию DefaultHeartbeatConfig
+
+	AccessTokenRefreshBefore time.Duration // За сколько до истечения обновлять access токен, по умолчанию минута
+	RefreshTokenWarning      time.Duration // За сколько до истечения refresh токена начинать предупреждать, по умолчанию неделя
 }
@@ -11,19 +11,26 @@ type DataDetailing struct {
 
 func NewDataProcessor(timeframe Timeframe) *DataProcessor {
 	return &DataProcessor{
-		timeframe: timeframe,
-		bars:      NewBarQueue(5000),
-		lastBar:   nil,
+		timeframeBars: timeframeBars{
+			timeframe: timeframe,
+			bars:      NewBarQueue(5000),
+			lastBar:   nil,
+		},
+		builder:   NewTimeBars(timeframe),
+		emptyBars: SkipEmptyBars,
+		session:   MOEXTradingSession,
 	}
 }
 
 type DataProcessor struct {
-	timeframe       Timeframe
-	bars            *BarQueue
-	Indicators      []string `json:"-"` // Индикаторы
-	lastBar         *Bar
+	timeframeBars                  // Основной таймфрейм: бары, индикаторы, формирующийся и закрытые бары
+	builder         BarBuilder     // Где заканчивается бар из ленты сделок, по умолчанию по таймфрейму
+	emptyBars       EmptyBarPolicy // Что делать с интервалами без сделок
+	session         func(time.Time) bool
+	lastQuote       *QuotesSlimData
 	lastAlltradesID int64
 	detailing       DataDetailing
+	frames          []*timeframeBars // Старшие таймфреймы из той же ленты сделок, по возрастанию
 }
 
 func (p *DataProcessor) GetLastBar() (*Bar, error) {
@@ -34,45 +41,78 @@ func (p *DataProcessor) GetLastBar() (*Bar, error) {
 	return p.lastBar, nil
 }
 
+// seenTrade сделка уже обработана. Сделки без ID (бэктест) не проверяем.
+func (p *DataProcessor) seenTrade(id int64) bool {
+	return id != 0 && id <= p.lastAlltradesID
+}
+
 func (p *DataProcessor) NewAllTrades(data AllTradesSlimData) error {
-	if data.ID < p.lastAlltradesID {
+	if p.seenTrade(data.ID) {
+		return nil
+	}
+
+	p.lastAlltradesID = max(p.lastAlltradesID, data.ID)
+
+	// Сделка бара, который уже сменился следующим, ряд назад по времени не двигает
+	if p.timeBars() && p.lastBar != nil && p.builder.Start(data).Before(p.lastBar.Time) {
 		return nil
-	} else {
-		p.lastAlltradesID = data.ID
 	}
 
 	// лента, лента всех сделок, таблица всех сделок, alltrades, time and sales, T&S
 	// log.Println("AllTrades", time.Unix(data.MsTimestamp-(data.MsTimestamp%int64(p.timeframe)), 0))
-	eventBarTime := time.UnixMilli(data.Timestamp - (data.Timestamp % int64(p.timeframe*1000)))
-
-	// Создаём бар если его нет или пришёл новый
-	if p.lastBar == nil || p.lastBar.Time != eventBarTime {
+	// Создаём бар если его нет или сделка в него не помещается
+	if p.lastBar == nil || p.builder.Opens(p.lastBar, data) {
 		// newBar := p.NewBarFromAllTradesData(eventBarTime, data)
+		start := p.builder.Start(data)
+
+		// Интервалы без сделок перед новым баром
+		if p.timeBars() {
+			if err := p.fillEmptyBars(&p.timeframeBars, start); err != nil {
+				return err
+			}
+		}
 
-		newBar := p.NewBlankBar(eventBarTime)
-		// Добавляем бар в хранилище
-		err := p.bars.Enqueue(newBar)
-		if err != nil {
+		// Добавляем бар в хранилище, прошлый закрывается
+		if err := p.push(p.NewBlankBar(start)); err != nil {
 			return err
 		}
-		p.lastBar = newBar
 
 		// return nil
 	}
 
 	// Заполняем текущий бар
-	p.UpdateBarFromAllTradesData(p.lastBar, data)
+	p.applyTrade(p.lastBar, data)
+
+	if err := p.updateDerived(); err != nil {
+		return err
+	}
+
+	// Закрытый таймером бар индикаторы уже учли
+	if !p.final {
+		p.previewIndicators(p.lastBar)
+	}
+
+	// Старшие таймфреймы после основного: их бары закрываются той же сделкой
+	return p.framesTrade(data)
+}
+
+// tradeBarTime начало бара таймфрейма, в который попадает время сделки в UnixMilli
+func tradeBarTime(timestamp int64, timeframe Timeframe) time.Time {
+	return time.UnixMilli(timestamp - (timestamp % int64(timeframe*1000)))
+}
+
+// applyTrade добавляет сделку в бар вместе с дельтой и профилем
+func (p *DataProcessor) applyTrade(bar *Bar, data AllTradesSlimData) {
+	p.UpdateBarFromAllTradesData(bar, data)
 
 	// Считаем дополнительные данные
 	if p.detailing.delta {
-		p.lastBar.Delta.AddValue(data.Qty, data.Side)
+		bar.Delta.AddValue(data.Qty, data.Side)
 	}
 
 	if p.detailing.marketProfile {
-		p.lastBar.MarketProfile.AddValue(data.Price, data.Qty, data.Side)
+		bar.MarketProfile.AddValue(data.Price, data.Qty, data.Side)
 	}
-
-	return nil
 }
 
 func (p *DataProcessor) UpdateBarFromAllTradesData(lastBar *Bar, data AllTradesSlimData) {
@@ -80,16 +120,18 @@ func (p *DataProcessor) UpdateBarFromAllTradesData(lastBar *Bar, data AllTradesS
 		lastBar.Open = data.Price
 	}
 
-	if p.lastBar.High < data.Price || p.lastBar.High == 0 {
+	if lastBar.High < data.Price || lastBar.High == 0 {
 		lastBar.High = data.Price
 	}
 
-	if p.lastBar.Low > data.Price || p.lastBar.Low == 0 {
+	if lastBar.Low > data.Price || lastBar.Low == 0 {
 		lastBar.Low = data.Price
 	}
 
 	lastBar.Close = data.Price
 	lastBar.Volume += data.Qty
+	lastBar.Trades++
+	lastBar.Turnover += data.Price * float64(data.Qty)
 }
 
 func (p *DataProcessor) NewOrderBook(data OrderBookSlimData) error {
@@ -103,7 +145,7 @@ func (p *DataProcessor) NewOrderBook(data OrderBookSlimData) error {
 
 	// log.Println(p.lastBar.Time, orderBookTime, p.lastBar.Time != orderBookTime)
 	// Если свечи нет или стакан не от этой свечи, то пропускаем
-	if p.lastBar == nil || p.lastBar.Time != orderBookTime {
+	if p.lastBar == nil || p.timeBars() && p.lastBar.Time != orderBookTime {
 		return nil
 	}
 
@@ -114,7 +156,35 @@ func (p *DataProcessor) NewOrderBook(data OrderBookSlimData) error {
 	return nil
 }
 
+// NewQuote запоминает последнюю котировку: лучшие цены и дневную сводку по инструменту
+func (p *DataProcessor) NewQuote(data QuotesSlimData) error {
+	p.lastQuote = &data
+
+	return nil
+}
+
+// GetLastQuote последняя котировка инструмента
+func (p *DataProcessor) GetLastQuote() (QuotesSlimData, error) {
+	if p.lastQuote == nil {
+		return QuotesSlimData{}, ErrNoQuote
+	}
+
+	return *p.lastQuote, nil
+}
+
+// timeBars бары строятся по таймфрейму. Остальные построители не знают заранее, где кончится бар,
+// стакан попадает в формирующийся бар
+func (p *DataProcessor) timeBars() bool {
+	_, ok := p.builder.(*TimeBars)
+	return ok
+}
+
 func (p *DataProcessor) NewBar(data BarsSlimData) error {
+	// Свечи брокера нарезаны по времени, с другими барами их не смешиваем
+	if !p.timeBars() {
+		return nil
+	}
+
 	if p.lastBar != nil && data.Time < p.lastBar.Timestamp {
 		return nil
 	}
@@ -124,20 +194,29 @@ func (p *DataProcessor) NewBar(data BarsSlimData) error {
 	// fmt.Println("New bar", data.Time)
 
 	if p.lastBar == nil || p.lastBar.Time != eventBarTime {
-		newBar := p.NewBarFromBarData(eventBarTime, data)
+		if err := p.push(p.NewBarFromBarData(eventBarTime, data)); err != nil {
+			return err
+		}
 
-		err := p.bars.Enqueue(newBar)
-		if err != nil {
+		if err := p.updateDerived(); err != nil {
 			return err
 		}
 
-		p.lastBar = newBar
+		p.previewIndicators(p.lastBar)
 
 		return nil
 	}
 
 	p.UpdateBarFromBarData(p.lastBar, data)
 
+	if err := p.updateDerived(); err != nil {
+		return err
+	}
+
+	if !p.final {
+		p.previewIndicators(p.lastBar)
+	}
+
 	return nil
 }
 
@@ -181,6 +260,7 @@ func (p *DataProcessor) NewBlankBar(eventBarTime time.Time) *Bar {
 			POCPrice:  0.0,
 			Values:    make(map[string]MarketProfileUnit),
 		},
+		Indicators: make(map[string]IndicatorValue),
 		OrderFlow: OrderFlow{
 			LastVal:       make(map[string]OrderBookRow),
 			ValuesInc:     make(map[string]int64),
@@ -0,0 +1,218 @@
+package alor
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// timeframeBars бары одного таймфрейма: основного или старшего. Старшие строятся из той же ленты сделок,
+// что и основной, у каждого таймфрейма своя очередь баров и свои индикаторы.
+type timeframeBars struct {
+	timeframe  Timeframe
+	bars       *BarQueue
+	indicators []indicatorSlot // Индикаторы, пересчитываются на каждое событие
+	lastBar    *Bar
+	final      bool   // lastBar уже закрыт таймером, следующий бар не закрывает его повторно
+	closed     []*Bar // Закрытые бары, ещё не переданные стратегии
+	derived    []*derivedBars
+	footprint  *FootprintConfig // Кластеры закрытых баров, nil - не считаются
+}
+
+// push добавляет новый бар в хранилище и закрывает прошлый
+func (f *timeframeBars) push(bar *Bar) error {
+	if err := f.bars.Enqueue(bar); err != nil {
+		return err
+	}
+
+	f.closeLast()
+	f.lastBar = bar
+	f.final = false
+
+	return nil
+}
+
+// closeLast фиксирует индикаторы формирующегося бара и отдаёт его в закрытые один раз
+func (f *timeframeBars) closeLast() {
+	if f.lastBar == nil || f.final {
+		return
+	}
+
+	if f.footprint != nil {
+		f.lastBar.Footprint = BuildFootprint(f.lastBar, *f.footprint, averageVolume(f.bars, f.lastBar, f.footprint.AbsorptionBars))
+	}
+
+	commitIndicatorSlots(f.indicators, f.lastBar)
+	f.closed = append(f.closed, f.lastBar)
+	f.final = true
+}
+
+// popClosed отдаёт закрытые бары один раз
+func (f *timeframeBars) popClosed() []*Bar {
+	closed := f.closed
+	f.closed = nil
+
+	return closed
+}
+
+// TimeframeBar закрытый бар старшего таймфрейма
+type TimeframeBar struct {
+	Timeframe Timeframe
+	Bar       *Bar
+}
+
+// WithTimeframes строит из ленты сделок бары старших таймфреймов вместе с основным.
+// Таймфрейм должен быть кратен основному, тогда бары всех таймфреймов закрываются одной сделкой.
+func WithTimeframes(timeframes ...Timeframe) SubscriberOption {
+	return func(s *Subscriber) {
+		for _, timeframe := range timeframes {
+			if err := s.DataProcessor.AddTimeframe(timeframe); err != nil {
+				s.err = err
+				return
+			}
+		}
+	}
+}
+
+// AddTimeframe добавляет старший таймфрейм, бары строятся с первой следующей сделки
+func (p *DataProcessor) AddTimeframe(timeframe Timeframe) error {
+	if timeframe <= 0 || timeframe%p.timeframe != 0 {
+		return fmt.Errorf("%w: %d is not a multiple of %d", ErrInvalidTimeframe, timeframe, p.timeframe)
+	}
+
+	if timeframe == p.timeframe || p.frame(timeframe) != nil {
+		return nil
+	}
+
+	// Кластеры включённые до таймфрейма берём сразу, включённые после раздаст SetFootprint,
+	// поэтому порядок WithTimeframes и WithFootprint не важен
+	p.frames = append(p.frames, &timeframeBars{
+		timeframe: timeframe,
+		bars:      NewBarQueue(5000),
+		footprint: p.footprint,
+	})
+
+	slices.SortFunc(p.frames, func(a, b *timeframeBars) int {
+		return cmp.Compare(a.timeframe, b.timeframe)
+	})
+
+	return nil
+}
+
+func (p *DataProcessor) frame(timeframe Timeframe) *timeframeBars {
+	for _, frame := range p.frames {
+		if frame.timeframe == timeframe {
+			return frame
+		}
+	}
+
+	return nil
+}
+
+// Timeframes основной и старшие таймфреймы по возрастанию
+func (p *DataProcessor) Timeframes() []Timeframe {
+	timeframes := []Timeframe{p.timeframe}
+	for _, frame := range p.frames {
+		timeframes = append(timeframes, frame.timeframe)
+	}
+
+	return timeframes
+}
+
+// GetBars очередь баров таймфрейма, для основного - та же, что у GetBarsCloak
+func (p *DataProcessor) GetBars(timeframe Timeframe) (*BarQueue, error) {
+	if timeframe == p.timeframe {
+		return p.bars, nil
+	}
+
+	frame := p.frame(timeframe)
+	if frame == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownTimeframe, timeframe)
+	}
+
+	return frame.bars, nil
+}
+
+// GetTimeframeBar бар таймфрейма с конца: 0 - формирующийся, 1 - последний закрытый и т.д.
+func (p *DataProcessor) GetTimeframeBar(timeframe Timeframe, index int64) (*Bar, error) {
+	bars, err := p.GetBars(timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	return bars.GetBarFromEnd(index)
+}
+
+// AddTimeframeIndicator подключает индикатор к барам таймфрейма
+func (p *DataProcessor) AddTimeframeIndicator(timeframe Timeframe, name string, indicator Indicator) error {
+	if timeframe == p.timeframe {
+		p.AddIndicator(name, indicator)
+		return nil
+	}
+
+	frame := p.frame(timeframe)
+	if frame == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownTimeframe, timeframe)
+	}
+
+	frame.indicators = addIndicatorSlot(frame.indicators, name, indicator)
+
+	return nil
+}
+
+// GetTimeframeIndicatorValue значение индикатора таймфрейма на баре с конца
+func (p *DataProcessor) GetTimeframeIndicatorValue(timeframe Timeframe, name string, index int64) (IndicatorValue, error) {
+	bars, err := p.GetBars(timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	return indicatorValue(bars, name, index)
+}
+
+// framesTrade добавляет сделку в бары старших таймфреймов
+func (p *DataProcessor) framesTrade(data AllTradesSlimData) error {
+	for _, frame := range p.frames {
+		eventBarTime := tradeBarTime(data.Timestamp, frame.timeframe)
+
+		// Сделка бара, который уже сменился следующим, ряд назад по времени не двигает
+		if frame.lastBar != nil && eventBarTime.Before(frame.lastBar.Time) {
+			continue
+		}
+
+		if frame.lastBar == nil || eventBarTime.After(frame.lastBar.Time) {
+			if err := p.fillEmptyBars(frame, eventBarTime); err != nil {
+				return err
+			}
+
+			if err := frame.push(p.NewBlankBar(eventBarTime)); err != nil {
+				return err
+			}
+		}
+
+		p.applyTrade(frame.lastBar, data)
+
+		if err := frame.updateDerived(); err != nil {
+			return err
+		}
+
+		if !frame.final {
+			previewIndicatorSlots(frame.indicators, frame.lastBar)
+		}
+	}
+
+	return nil
+}
+
+// popClosedTimeframeBars закрытые бары старших таймфреймов от младшего к старшему, каждый отдаётся один раз
+func (p *DataProcessor) popClosedTimeframeBars() []TimeframeBar {
+	var closed []TimeframeBar
+
+	for _, frame := range p.frames {
+		for _, bar := range frame.popClosed() {
+			closed = append(closed, TimeframeBar{Timeframe: frame.timeframe, Bar: bar})
+		}
+	}
+
+	return closed
+}
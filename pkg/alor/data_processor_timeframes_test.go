@@ -0,0 +1,117 @@
+package alor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type timeframesStrategy struct {
+	BaseStrategy
+	closed []TimeframeBar // Основной таймфрейм тоже сюда, в порядке вызова колбэков
+}
+
+func (s *timeframesStrategy) OnBarClosed(bar *Bar) error {
+	s.closed = append(s.closed, TimeframeBar{Timeframe: M1TF, Bar: bar})
+	return nil
+}
+
+func (s *timeframesStrategy) OnTimeframeBarClosed(timeframe Timeframe, bar *Bar) error {
+	s.closed = append(s.closed, TimeframeBar{Timeframe: timeframe, Bar: bar})
+	return nil
+}
+
+func TestSubscriberTimeframes(t *testing.T) {
+	t.Parallel()
+
+	invalid := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M5TF, false, WithTimeframes(M1TF))
+	require.ErrorIs(t, invalid.err, ErrInvalidTimeframe)
+
+	strategy := &timeframesStrategy{}
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithTimeframes(H1TF, M5TF, M1TF), WithDelta())
+	subscriber.SetStrategy(strategy)
+	subscriber.Ready = true
+
+	require.NoError(t, subscriber.err)
+	require.Equal(t, []Timeframe{M1TF, M5TF, H1TF}, subscriber.DataProcessor.Timeframes())
+	require.NoError(t, subscriber.DataProcessor.AddTimeframeIndicator(M5TF, "sma", NewSMA(2, ClosePriceSource)))
+
+	trades := []AllTradesSlimData{
+		{ID: 1, Price: 100, Qty: 1, Timestamp: 0, Side: BuySide},
+		{ID: 2, Price: 102, Qty: 2, Timestamp: 61_000, Side: SellSide},
+		{ID: 3, Price: 101, Qty: 3, Timestamp: 301_000, Side: BuySide},
+		{ID: 4, Price: 103, Qty: 4, Timestamp: 3_601_000, Side: BuySide},
+	}
+	for _, trade := range trades {
+		require.NoError(t, subscriber.HandleEventSync(newAllTradesEvent(t, trade)))
+	}
+
+	// Сделка закрывает бары всех таймфреймов, граница которых пройдена, от младшего к старшему
+	var order []Timeframe
+	for _, item := range strategy.closed {
+		order = append(order, item.Timeframe)
+	}
+	require.Equal(t, []Timeframe{M1TF, M1TF, M5TF, M1TF, M5TF, H1TF}, order)
+
+	hour := strategy.closed[5].Bar
+	require.Equal(t, 100.0, hour.Open)
+	require.Equal(t, 102.0, hour.High)
+	require.Equal(t, 101.0, hour.Close)
+	require.EqualValues(t, 6, hour.Volume)
+	require.EqualValues(t, 2, hour.Delta.Total)
+
+	bars, err := subscriber.DataProcessor.GetBars(M5TF)
+	require.NoError(t, err)
+	require.Equal(t, 3, bars.GetLength())
+
+	value, err := subscriber.DataProcessor.GetTimeframeIndicatorValue(M5TF, "sma", 1)
+	require.NoError(t, err)
+	require.InDelta(t, 101.5, value.Value(), 1e-9)
+
+	_, err = subscriber.DataProcessor.GetBars(DayTF)
+	require.ErrorIs(t, err, ErrUnknownTimeframe)
+}
+
+func TestTimeframesLateTrade(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithTimeframes(M5TF))
+	processor := subscriber.DataProcessor
+
+	trades := []AllTradesSlimData{
+		{ID: 1, Price: 100, Qty: 1, Timestamp: 0, Side: BuySide},
+		{ID: 2, Price: 101, Qty: 2, Timestamp: 301_000, Side: BuySide},
+		// Опоздала из прошлого пятиминутного бара
+		{ID: 3, Price: 99, Qty: 3, Timestamp: 299_000, Side: SellSide},
+		{ID: 4, Price: 102, Qty: 4, Timestamp: 302_000, Side: BuySide},
+	}
+	for _, trade := range trades {
+		require.NoError(t, processor.NewAllTrades(trade))
+	}
+
+	// Бары не идут назад по времени ни в основном, ни в старшем таймфрейме
+	for _, timeframe := range processor.Timeframes() {
+		bars, err := processor.GetBars(timeframe)
+		require.NoError(t, err)
+		require.Len(t, bars.Elements, 2, "timeframe %d", timeframe)
+		require.True(t, bars.Elements[1].Time.After(bars.Elements[0].Time))
+		require.EqualValues(t, 6, bars.Elements[1].Volume)
+	}
+}
+
+func TestTimeframesFootprintOptionOrder(t *testing.T) {
+	t.Parallel()
+
+	before := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithFootprint(FootprintConfig{}), WithTimeframes(M5TF))
+	after := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithTimeframes(M5TF), WithFootprint(FootprintConfig{}))
+
+	for _, subscriber := range []*Subscriber{before, after} {
+		processor := subscriber.DataProcessor
+		require.NoError(t, processor.NewAllTrades(AllTradesSlimData{ID: 1, Price: 100, Qty: 1, Timestamp: 0, Side: BuySide}))
+		require.NoError(t, processor.NewAllTrades(AllTradesSlimData{ID: 2, Price: 101, Qty: 1, Timestamp: 301_000, Side: BuySide}))
+
+		closed := processor.popClosedTimeframeBars()
+		require.Len(t, closed, 1)
+		require.NotNil(t, closed[0].Bar.Footprint)
+	}
+}
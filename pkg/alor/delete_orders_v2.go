@@ -0,0 +1,24 @@
+package alor
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) CancelOrder(params CancelOrderParams) error {
+	// DELETE https://api.alor.ru/commandapi/warptrans/TRADE/v2/client/orders/:orderId
+	q := url.Values{}
+	q.Add("portfolio", params.Portfolio)
+	q.Add("exchange", string(params.Exchange))
+	q.Add("stop", strconv.FormatBool(params.Stop))
+	q.Add("jsonResponse", "true")
+	q.Add("format", string(SimpleResponseFormat))
+
+	path := fmt.Sprintf("/commandapi/warptrans/TRADE/v2/client/orders/%s?%s", params.OrderID, q.Encode())
+
+	_, err := c.sendOrderCommand(http.MethodDelete, path, params.Portfolio, nil)
+
+	return err
+}
@@ -6,4 +6,41 @@ var (
 	ErrNewBarFound        = errors.New("new bar was found")
 	ErrSubscriberNotFound = errors.New("subscriber not found")
 	ErrNoAvailableHandler = errors.New("no available handler")
+	ErrCommandFailed      = errors.New("broker command failed")
+	ErrSlowSubscriber     = errors.New("subscriber queue is overloaded")
+	ErrNoQuote            = errors.New("quote is not received yet")
+	ErrInvalidOpcode      = errors.New("invalid opcode")
+	ErrPoolExhausted      = errors.New("websocket pool limits exceeded")
+
+	ErrSubscriptionRejected = errors.New("subscription rejected by broker")
+	ErrSubscriptionTimeout  = errors.New("broker did not answer subscription request")
+	ErrUnauthorized         = errors.New("broker rejected access token")
+
+	ErrPortfolioNotFound = errors.New("portfolio not found")
+	ErrNoPortfolioData   = errors.New("portfolio data is not received yet")
+
+	ErrSecurityNotFound = errors.New("security not found")
+
+	ErrAccessTokenEmpty     = errors.New("access token is not received")
+	ErrAccessTokenExpired   = errors.New("access token is expired")
+	ErrRefreshTokenRejected = errors.New("broker rejected refresh token")
+
+	ErrUnknownStrategy         = errors.New("unknown strategy")
+	ErrInvalidStrategySettings = errors.New("invalid strategy settings")
+
+	ErrUnknownIndicator         = errors.New("unknown indicator")
+	ErrInvalidIndicatorSettings = errors.New("invalid indicator settings")
+	ErrIndicatorNotReady        = errors.New("indicator value is not ready")
+
+	ErrUnknownTimeframe = errors.New("timeframe is not built by data processor")
+	ErrInvalidTimeframe = errors.New("timeframe must be a multiple of subscriber timeframe")
+	ErrUnknownBarType   = errors.New("unknown bar type")
+	ErrInvalidBarSize   = errors.New("invalid bar size")
+
+	ErrUnknownEmptyBarPolicy = errors.New("unknown empty bar policy")
+	ErrUnknownBarTransform   = errors.New("unknown bar transform")
+
+	ErrOrderNotFound             = errors.New("order not found")
+	ErrNoMarketPrice             = errors.New("no order book or last trade to execute market order")
+	ErrUnsupportedBacktestOpcode = errors.New("unsupported backtest opcode")
 )
@@ -10,6 +10,9 @@ type EventType string
 const (
 	SystemType EventType = "system"
 	DataType   EventType = "data"
+	TimerType  EventType = "timer"
+	// BackfillType догруженные из истории сделки
+	BackfillType EventType = "backfill"
 )
 
 type ChainEvent struct {
@@ -18,37 +21,139 @@ type ChainEvent struct {
 	Guid   GUID
 	Data   json.RawMessage
 	Next   *ChainEvent
+	// Resumed первая сделка после (пере)подписки, перед ней подписчик проверяет пропуск
+	Resumed bool
+}
+
+// OverflowPolicy что делает очередь, когда в ней кончилось место
+type OverflowPolicy string
+
+const (
+	OverflowReject     OverflowPolicy = "reject"      // Enqueue возвращает ErrQueueOverFlow
+	OverflowBlock      OverflowPolicy = "block"       // Enqueue ждёт, пока освободится место
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // Выбрасываем самое старое событие
+	OverflowDropNewest OverflowPolicy = "drop_newest" // Выбрасываем новое событие
+	OverflowConflate   OverflowPolicy = "conflate"    // Храним только последний стакан подписки, остальное ждёт места
+)
+
+func (p OverflowPolicy) IsValid() bool {
+	switch p {
+	case OverflowReject, OverflowBlock, OverflowDropOldest, OverflowDropNewest, OverflowConflate:
+		return true
+	}
+
+	return false
+}
+
+// lossless событие нельзя выбросить: ответы на команды, обезличенные сделки, данные счёта.
+// При переполнении политики с выбрасыванием ждут для них места, как OverflowBlock.
+func (e *ChainEvent) lossless() bool {
+	return e.Type != DataType || e.Opcode == AllTradesOpcode || isPortfolioOpcode(e.Opcode)
+}
+
+// conflatable стакан - снимок целиком, вместо старого снимка в очереди достаточно нового
+func (e *ChainEvent) conflatable() bool {
+	return e.Type == DataType && e.Opcode == OrderBookOpcode
+}
+
+// QueueStats состояние очереди и счётчики потерь
+type QueueStats struct {
+	Policy    OverflowPolicy `json:"policy"`
+	Size      int            `json:"size"`
+	Len       int            `json:"len"`
+	HighWater int            `json:"high_water"` // Наибольшая длина очереди
+	Dropped   int            `json:"dropped"`    // Выброшено или отклонено при переполнении
+	Conflated int            `json:"conflated"`  // Заменено более свежим снимком
 }
 
 type ChainQueue struct {
 	// Elements  []ChainEvent
 	Size      int `json:"size"`
 	Len       int `json:"len"`
+	policy    OverflowPolicy
+	highWater int
+	dropped   int
+	conflated int
+	snapshots map[GUID]*ChainEvent // Последний снимок подписки в очереди для OverflowConflate
+	closed    bool
 	mu        sync.Mutex
+	notFull   *sync.Cond
 	firstElem *ChainEvent
 	lastElem  *ChainEvent
 }
 
 func NewChainQueue(size int) *ChainQueue {
-	return &ChainQueue{
+	q := &ChainQueue{
 		// Elements:  make([]ChainEvent, 0),
 		Size:      size,
 		Len:       0,
+		policy:    OverflowReject,
+		snapshots: make(map[GUID]*ChainEvent),
 		mu:        sync.Mutex{},
 		lastElem:  nil,
 		firstElem: nil,
 	}
+	q.notFull = sync.NewCond(&q.mu)
+
+	return q
+}
+
+// SetPolicy меняет политику переполнения, неизвестная политика игнорируется
+func (q *ChainQueue) SetPolicy(policy OverflowPolicy) {
+	if !policy.IsValid() {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.policy = policy
+	// Ждущие в Enqueue перепроверят место по новой политике
+	q.notFull.Broadcast()
 }
 
 func (q *ChainQueue) Enqueue(element *ChainEvent) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.GetLength() == q.Size {
-		return ErrQueueOverFlow
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	if q.policy == OverflowConflate && element.conflatable() {
+		if queued, ok := q.snapshots[element.Guid]; ok {
+			queued.Data = element.Data
+			q.conflated++
+			return nil
+		}
+	}
+
+	for q.GetLength() >= q.Size {
+		switch {
+		case q.policy == OverflowReject:
+			q.dropped++
+			return ErrQueueOverFlow
+		case q.policy == OverflowDropNewest && !element.lossless():
+			q.dropped++
+			return nil
+		case q.policy == OverflowDropOldest && !element.lossless() && q.dropOldest():
+			q.dropped++
+			continue
+		}
+
+		q.notFull.Wait()
+
+		if q.closed {
+			return ErrQueueClosed
+		}
 	}
 
 	q.Len++
+	q.highWater = max(q.highWater, q.Len)
+
+	if q.policy == OverflowConflate && element.conflatable() {
+		q.snapshots[element.Guid] = element
+	}
 
 	if q.firstElem == nil {
 		q.firstElem = element
@@ -62,6 +167,41 @@ func (q *ChainQueue) Enqueue(element *ChainEvent) error {
 	return nil
 }
 
+// dropOldest выбрасывает самое старое событие, которое можно потерять
+func (q *ChainQueue) dropOldest() bool {
+	var prev *ChainEvent
+
+	for element := q.firstElem; element != nil; prev, element = element, element.Next {
+		if element.lossless() {
+			continue
+		}
+
+		if prev == nil {
+			q.firstElem = element.Next
+		} else {
+			prev.Next = element.Next
+		}
+
+		if q.lastElem == element {
+			q.lastElem = prev
+		}
+
+		element.Next = nil
+		q.forget(element)
+		q.Len--
+
+		return true
+	}
+
+	return false
+}
+
+func (q *ChainQueue) forget(element *ChainEvent) {
+	if q.snapshots[element.Guid] == element {
+		delete(q.snapshots, element.Guid)
+	}
+}
+
 func (q *ChainQueue) Dequeue() (*ChainEvent, error) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
@@ -74,15 +214,60 @@ func (q *ChainQueue) Dequeue() (*ChainEvent, error) {
 
 	element := q.firstElem
 	q.firstElem = element.Next
+	element.Next = nil
+
+	if q.firstElem == nil {
+		q.lastElem = nil
+	}
+
+	q.forget(element)
+	q.notFull.Signal()
 
 	return element, nil // Slice off the element once it is dequeued.
 }
 
+// Close будит ждущих места в Enqueue, после него очередь не принимает события
+func (q *ChainQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.notFull.Broadcast()
+}
+
+// GetLength без блокировки, вызывается и из-под мьютекса очереди
 func (q *ChainQueue) GetLength() int {
 	// return len(q.Elements)
 	return q.Len
 }
 
+// Length длина очереди для чтения из других горутин
+func (q *ChainQueue) Length() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.Len
+}
+
 func (q *ChainQueue) IsEmpty() bool {
 	return q.Len <= 0
 }
+
+func (q *ChainQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return QueueStats{
+		Policy:    q.policy,
+		Size:      q.Size,
+		Len:       q.Len,
+		HighWater: q.highWater,
+		Dropped:   q.dropped,
+		Conflated: q.conflated,
+	}
+}
+
+// MarshalJSON очередь подписчика отдаётся в API вместе со счётчиками
+func (q *ChainQueue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Stats())
+}
@@ -6,6 +6,7 @@ import (
 	"go.uber.org/goleak"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -148,3 +149,105 @@ func TestQueueUnderFlow(t *testing.T) {
 	require.Equal(t, wantErr, err)
 	require.Nil(t, element)
 }
+
+func TestQueueOverflowPolicies(t *testing.T) {
+	t.Parallel()
+
+	orderBook := func(guid string, data string) *ChainEvent {
+		return &ChainEvent{Type: DataType, Opcode: OrderBookOpcode, Guid: GUID(guid), Data: []byte(data)}
+	}
+	allTrades := func(data string) *ChainEvent {
+		return &ChainEvent{Type: DataType, Opcode: AllTradesOpcode, Guid: "trades", Data: []byte(data)}
+	}
+
+	t.Run("drop newest", func(t *testing.T) {
+		t.Parallel()
+
+		queue := NewChainQueue(2)
+		queue.SetPolicy(OverflowDropNewest)
+
+		require.NoError(t, queue.Enqueue(orderBook("a", "1")))
+		require.NoError(t, queue.Enqueue(orderBook("b", "1")))
+		require.NoError(t, queue.Enqueue(orderBook("c", "1")))
+
+		element, err := queue.Dequeue()
+		require.NoError(t, err)
+		require.Equal(t, GUID("a"), element.Guid)
+		require.Equal(t, QueueStats{Policy: OverflowDropNewest, Size: 2, Len: 1, HighWater: 2, Dropped: 1}, queue.Stats())
+	})
+
+	t.Run("drop oldest keeps all trades", func(t *testing.T) {
+		t.Parallel()
+
+		queue := NewChainQueue(2)
+		queue.SetPolicy(OverflowDropOldest)
+
+		require.NoError(t, queue.Enqueue(allTrades("1")))
+		require.NoError(t, queue.Enqueue(orderBook("a", "1")))
+		require.NoError(t, queue.Enqueue(orderBook("b", "1")))
+
+		first, err := queue.Dequeue()
+		require.NoError(t, err)
+		require.Equal(t, AllTradesOpcode, first.Opcode)
+
+		second, err := queue.Dequeue()
+		require.NoError(t, err)
+		require.Equal(t, GUID("b"), second.Guid)
+		require.Equal(t, 1, queue.Stats().Dropped)
+	})
+
+	t.Run("conflate", func(t *testing.T) {
+		t.Parallel()
+
+		queue := NewChainQueue(10)
+		queue.SetPolicy(OverflowConflate)
+
+		require.NoError(t, queue.Enqueue(orderBook("a", "1")))
+		require.NoError(t, queue.Enqueue(allTrades("1")))
+		require.NoError(t, queue.Enqueue(orderBook("a", "2")))
+		require.NoError(t, queue.Enqueue(allTrades("2")))
+
+		require.Equal(t, 3, queue.Length())
+		require.Equal(t, 1, queue.Stats().Conflated)
+
+		element, err := queue.Dequeue()
+		require.NoError(t, err)
+		require.Equal(t, "2", string(element.Data))
+
+		// Снимок уже отдан, следующий встаёт в очередь
+		require.NoError(t, queue.Enqueue(orderBook("a", "3")))
+		require.Equal(t, 3, queue.Length())
+	})
+
+	t.Run("block", func(t *testing.T) {
+		t.Parallel()
+
+		queue := NewChainQueue(1)
+		queue.SetPolicy(OverflowBlock)
+
+		require.NoError(t, queue.Enqueue(allTrades("1")))
+
+		enqueued := make(chan error)
+		go func() {
+			enqueued <- queue.Enqueue(allTrades("2"))
+		}()
+
+		select {
+		case <-enqueued:
+			t.Fatal("enqueue must wait for free space")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		_, err := queue.Dequeue()
+		require.NoError(t, err)
+		require.NoError(t, <-enqueued)
+		require.Zero(t, queue.Stats().Dropped)
+
+		go func() {
+			enqueued <- queue.Enqueue(allTrades("3"))
+		}()
+
+		queue.Close()
+		require.ErrorIs(t, <-enqueued, ErrQueueClosed)
+	})
+}
@@ -0,0 +1,408 @@
+package alor
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// FootprintConfig параметры кластерного анализа бара
+type FootprintConfig struct {
+	ImbalanceRatio   float64 // Диагональный дисбаланс: аск уровня к биду уровнем ниже или бид к аску уровнем выше, по умолчанию 3
+	StackedLevels    int     // Сколько уровней подряд с дисбалансом одной стороны дают зону, по умолчанию 3
+	ValueArea        float64 // Доля объёма бара в зоне стоимости, по умолчанию 0.7
+	AbsorptionShare  float64 // Доля объёма бара на крайнем уровне для поглощения, по умолчанию 0.2
+	AbsorptionVolume float64 // Во сколько раз объём бара больше среднего для поглощения, по умолчанию 1.5
+	AbsorptionBars   int     // По скольким прошлым барам считается средний объём, по умолчанию 20
+	PriceStep        float64 // Шаг цены для соседних уровней, по умолчанию из справочника инструментов, без него - наименьший шаг между уровнями бара
+}
+
+var DefaultFootprintConfig = FootprintConfig{
+	ImbalanceRatio:   3,
+	StackedLevels:    3,
+	ValueArea:        0.7,
+	AbsorptionShare:  0.2,
+	AbsorptionVolume: 1.5,
+	AbsorptionBars:   20,
+}
+
+func (c FootprintConfig) withDefaults() FootprintConfig {
+	if c.ImbalanceRatio <= 1 {
+		c.ImbalanceRatio = DefaultFootprintConfig.ImbalanceRatio
+	}
+
+	if c.StackedLevels <= 1 {
+		c.StackedLevels = DefaultFootprintConfig.StackedLevels
+	}
+
+	if c.ValueArea <= 0 || c.ValueArea > 1 {
+		c.ValueArea = DefaultFootprintConfig.ValueArea
+	}
+
+	if c.AbsorptionShare <= 0 || c.AbsorptionShare > 1 {
+		c.AbsorptionShare = DefaultFootprintConfig.AbsorptionShare
+	}
+
+	if c.AbsorptionVolume <= 0 {
+		c.AbsorptionVolume = DefaultFootprintConfig.AbsorptionVolume
+	}
+
+	if c.AbsorptionBars <= 0 {
+		c.AbsorptionBars = DefaultFootprintConfig.AbsorptionBars
+	}
+
+	return c
+}
+
+// WithFootprint считает кластеры закрытых баров всех таймфреймов, профиль включается вместе с ними
+func WithFootprint(config FootprintConfig) SubscriberOption {
+	return func(s *Subscriber) {
+		s.DataProcessor.SetFootprint(config)
+		s.setFootprintPriceStep()
+	}
+}
+
+// setFootprintPriceStep берёт шаг цены кластеров из справочника, если он не задан явно
+func (s *Subscriber) setFootprintPriceStep() {
+	footprint := s.DataProcessor.footprint
+	if footprint == nil || footprint.PriceStep > 0 || s.securities == nil {
+		return
+	}
+
+	if security, err := s.securities.Get(s.Exchange, s.Code, s.Board); err == nil {
+		footprint.PriceStep = security.PriceStep
+	}
+}
+
+// FootprintLevel уровень цены кластера. Bid - агрессивные продажи в бид, Ask - агрессивные покупки в аск.
+type FootprintLevel struct {
+	Price         float64 `json:"price"`
+	Bid           int64   `json:"bid"`
+	Ask           int64   `json:"ask"`
+	Total         int64   `json:"total"`
+	Delta         int64   `json:"delta"`
+	BuyImbalance  bool    `json:"buy_imbalance"`  // Аск уровня перевешивает бид уровнем ниже
+	SellImbalance bool    `json:"sell_imbalance"` // Бид уровня перевешивает аск уровнем выше
+}
+
+// ImbalanceZone уровни подряд с дисбалансом одной стороны
+type ImbalanceZone struct {
+	Side   OrderSide `json:"side"`
+	Low    float64   `json:"low"`
+	High   float64   `json:"high"`
+	Levels int       `json:"levels"`
+}
+
+// Absorption большой объём без продвижения цены: агрессор давил в край бара, но цена от края ушла.
+// Side - сторона, которая поглотила агрессию лимитными заявками.
+type Absorption struct {
+	Side   OrderSide `json:"side"`
+	Price  float64   `json:"price"`
+	Volume int64     `json:"volume"` // Объём агрессора на уровне
+}
+
+// Footprint кластеры бара по профилю сделок
+type Footprint struct {
+	Levels            []FootprintLevel `json:"levels"` // По возрастанию цены
+	POCPrice          float64          `json:"poc_price"`
+	POCVolume         int64            `json:"poc_volume"`
+	ValueAreaHigh     float64          `json:"value_area_high"`
+	ValueAreaLow      float64          `json:"value_area_low"`
+	StackedImbalances []ImbalanceZone  `json:"stacked_imbalances"`
+	Absorptions       []Absorption     `json:"absorptions"`
+}
+
+// BuildFootprint кластеры бара по его профилю, nil если профиль не считался.
+// averageVolume - средний объём прошлых баров, 0 - поглощение ищется без сравнения с ними.
+func BuildFootprint(bar *Bar, config FootprintConfig, averageVolume float64) *Footprint {
+	if len(bar.MarketProfile.Values) == 0 {
+		return nil
+	}
+
+	config = config.withDefaults()
+
+	footprint := &Footprint{
+		Levels: make([]FootprintLevel, 0, len(bar.MarketProfile.Values)),
+	}
+
+	for key, unit := range bar.MarketProfile.Values {
+		price, err := strconv.ParseFloat(key, 64)
+		if err != nil {
+			continue
+		}
+
+		footprint.Levels = append(footprint.Levels, FootprintLevel{
+			Price: price,
+			Bid:   unit.Sell,
+			Ask:   unit.Buy,
+			Total: unit.Total,
+			Delta: unit.Buy - unit.Sell,
+		})
+	}
+
+	slices.SortFunc(footprint.Levels, func(a, b FootprintLevel) int {
+		return cmp.Compare(a.Price, b.Price)
+	})
+
+	step := config.PriceStep
+	if step <= 0 {
+		step = footprint.minStep()
+	}
+
+	footprint.markImbalances(config.ImbalanceRatio, step)
+	footprint.StackedImbalances = append(footprint.stackedZones(BuySide, config.StackedLevels, step), footprint.stackedZones(SellSide, config.StackedLevels, step)...)
+	footprint.valueArea(config.ValueArea)
+	footprint.Absorptions = footprint.absorptions(bar, config, averageVolume)
+
+	return footprint
+}
+
+// exceeds перевес volume над other в ratio раз, пустой уровень считается за лот
+func exceeds(volume, other int64, ratio float64) bool {
+	return volume > 0 && float64(volume) >= ratio*float64(max(other, 1))
+}
+
+// markImbalances сравнивает уровень с уровнями на шаг цены ниже и выше. Уровень внутри бара без сделок
+// считается нулевым, за краями бара цены не было, и там дисбаланс не ищется.
+func (f *Footprint) markImbalances(ratio, step float64) {
+	for i := range f.Levels {
+		if i > 0 {
+			var bid int64
+			if adjacentLevels(f.Levels[i-1], f.Levels[i], step) {
+				bid = f.Levels[i-1].Bid
+			}
+
+			f.Levels[i].BuyImbalance = exceeds(f.Levels[i].Ask, bid, ratio)
+		}
+
+		if i < len(f.Levels)-1 {
+			var ask int64
+			if adjacentLevels(f.Levels[i], f.Levels[i+1], step) {
+				ask = f.Levels[i+1].Ask
+			}
+
+			f.Levels[i].SellImbalance = exceeds(f.Levels[i].Bid, ask, ratio)
+		}
+	}
+}
+
+// adjacentLevels уровни на шаг цены друг от друга. Соседние по списку уровни дальше шага разделены пустыми.
+func adjacentLevels(low, high FootprintLevel, step float64) bool {
+	return high.Price-low.Price < 1.5*step
+}
+
+// minStep наименьшее расстояние между уровнями, когда шаг цены инструмента неизвестен
+func (f *Footprint) minStep() float64 {
+	var step float64
+	for i := 1; i < len(f.Levels); i++ {
+		if gap := f.Levels[i].Price - f.Levels[i-1].Price; gap > 0 && (step == 0 || gap < step) {
+			step = gap
+		}
+	}
+
+	return step
+}
+
+// stackedZones уровни с дисбалансом подряд по шагу цены, пустой уровень прерывает зону
+func (f *Footprint) stackedZones(side OrderSide, minLevels int, step float64) []ImbalanceZone {
+	var zones []ImbalanceZone
+
+	start := -1
+	for i := 0; i <= len(f.Levels); i++ {
+		imbalance := i < len(f.Levels) && (side == BuySide && f.Levels[i].BuyImbalance || side == SellSide && f.Levels[i].SellImbalance)
+
+		// Зона до пустого уровня закрывается, с уровня после него может начаться новая
+		if imbalance && start >= 0 && !adjacentLevels(f.Levels[i-1], f.Levels[i], step) {
+			if i-start >= minLevels {
+				zones = append(zones, ImbalanceZone{Side: side, Low: f.Levels[start].Price, High: f.Levels[i-1].Price, Levels: i - start})
+			}
+
+			start = -1
+		}
+
+		if imbalance {
+			if start < 0 {
+				start = i
+			}
+
+			continue
+		}
+
+		if start >= 0 && i-start >= minLevels {
+			zones = append(zones, ImbalanceZone{Side: side, Low: f.Levels[start].Price, High: f.Levels[i-1].Price, Levels: i - start})
+		}
+
+		start = -1
+	}
+
+	return zones
+}
+
+// valueArea POC и зона стоимости: от POC в сторону большего соседнего уровня, пока не наберётся доля share объёма
+func (f *Footprint) valueArea(share float64) {
+	var total int64
+
+	poc := 0
+	for i, level := range f.Levels {
+		total += level.Total
+
+		if level.Total > f.Levels[poc].Total {
+			poc = i
+		}
+	}
+
+	low, high := poc, poc
+	volume := f.Levels[poc].Total
+	target := share * float64(total)
+
+	for float64(volume) < target {
+		up, down := int64(-1), int64(-1)
+		if high+1 < len(f.Levels) {
+			up = f.Levels[high+1].Total
+		}
+
+		if low > 0 {
+			down = f.Levels[low-1].Total
+		}
+
+		if up < 0 && down < 0 {
+			break
+		}
+
+		if up >= down {
+			high++
+			volume += up
+		} else {
+			low--
+			volume += down
+		}
+	}
+
+	f.POCPrice = f.Levels[poc].Price
+	f.POCVolume = f.Levels[poc].Total
+	f.ValueAreaLow = f.Levels[low].Price
+	f.ValueAreaHigh = f.Levels[high].Price
+}
+
+func (f *Footprint) absorptions(bar *Bar, config FootprintConfig, averageVolume float64) []Absorption {
+	if len(f.Levels) < 2 {
+		return nil
+	}
+
+	if averageVolume > 0 && float64(bar.Volume) < config.AbsorptionVolume*averageVolume {
+		return nil
+	}
+
+	heavy := int64(config.AbsorptionShare * float64(bar.Volume))
+
+	var absorptions []Absorption
+
+	// Покупки в хай, а закрытие ниже: их поглотил продавец
+	if top := f.Levels[len(f.Levels)-1]; top.Ask > 0 && top.Ask >= heavy && bar.Close < top.Price {
+		absorptions = append(absorptions, Absorption{Side: SellSide, Price: top.Price, Volume: top.Ask})
+	}
+
+	// Продажи в лой, а закрытие выше: их поглотил покупатель
+	if bottom := f.Levels[0]; bottom.Bid > 0 && bottom.Bid >= heavy && bar.Close > bottom.Price {
+		absorptions = append(absorptions, Absorption{Side: BuySide, Price: bottom.Price, Volume: bottom.Bid})
+	}
+
+	return absorptions
+}
+
+// averageVolume средний объём до count баров с объёмом перед bar
+func averageVolume(bars *BarQueue, bar *Bar, count int) float64 {
+	var (
+		sum    int64
+		n      int
+		before bool
+	)
+
+	for i := len(bars.Elements) - 1; i >= 0 && n < count; i-- {
+		if !before {
+			before = bars.Elements[i] == bar
+			continue
+		}
+
+		if bars.Elements[i].Volume == 0 {
+			continue
+		}
+
+		sum += bars.Elements[i].Volume
+		n++
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return float64(sum) / float64(n)
+}
+
+// SetFootprint включает профиль и кластеры закрытых баров основного и старших таймфреймов,
+// в том числе таймфреймов, добавленных позже
+func (p *DataProcessor) SetFootprint(config FootprintConfig) {
+	config = config.withDefaults()
+
+	p.detailing.marketProfile = true
+	p.footprint = &config
+
+	for _, frame := range p.frames {
+		frame.footprint = &config
+	}
+}
+
+// BarFootprint бар с кластерами для графика
+type BarFootprint struct {
+	Time      time.Time  `json:"time"`
+	Open      float64    `json:"open"`
+	High      float64    `json:"high"`
+	Low       float64    `json:"low"`
+	Close     float64    `json:"close"`
+	Volume    int64      `json:"volume"`
+	Delta     Delta      `json:"delta"`
+	Footprint *Footprint `json:"footprint"`
+}
+
+// Footprints кластеры последних count баров таймфрейма, 0 - основного. У формирующегося бара и у подписчика
+// без WithFootprint кластеры считаются на лету по профилю с настройками по умолчанию.
+// Читает формирующийся бар, поэтому снаружи обработчика подписчика вызывается через его view.
+func (p *DataProcessor) Footprints(timeframe Timeframe, count int) ([]BarFootprint, error) {
+	if timeframe == 0 {
+		timeframe = p.timeframe
+	}
+
+	bars, err := p.GetBars(timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	config := DefaultFootprintConfig
+	if p.footprint != nil {
+		config = *p.footprint
+	}
+
+	elements := bars.Elements[max(len(bars.Elements)-count, 0):]
+	footprints := make([]BarFootprint, 0, len(elements))
+
+	for _, bar := range elements {
+		footprint := bar.Footprint
+		if footprint == nil {
+			footprint = BuildFootprint(bar, config, averageVolume(bars, bar, config.AbsorptionBars))
+		}
+
+		footprints = append(footprints, BarFootprint{
+			Time:      bar.Time,
+			Open:      bar.Open,
+			High:      bar.High,
+			Low:       bar.Low,
+			Close:     bar.Close,
+			Volume:    bar.Volume,
+			Delta:     bar.Delta,
+			Footprint: footprint,
+		})
+	}
+
+	return footprints, nil
+}
@@ -0,0 +1,171 @@
+package alor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func profileBar(close float64, levels map[float64][2]int64) *Bar {
+	bar := &Bar{Close: close, MarketProfile: MarketProfile{Values: make(map[string]MarketProfileUnit)}}
+
+	for price, volumes := range levels {
+		bar.MarketProfile.AddValue(price, volumes[0], SellSide)
+		bar.MarketProfile.AddValue(price, volumes[1], BuySide)
+		bar.Volume += volumes[0] + volumes[1]
+	}
+
+	return bar
+}
+
+func TestBuildFootprint(t *testing.T) {
+	t.Parallel()
+
+	// Цена: бид, аск
+	bar := profileBar(102, map[float64][2]int64{
+		100: {5, 1},
+		101: {1, 20},
+		102: {2, 9},
+		103: {1, 7},
+		104: {2, 30},
+	})
+
+	footprint := BuildFootprint(bar, FootprintConfig{}, 0)
+	require.NotNil(t, footprint)
+	require.Len(t, footprint.Levels, 5)
+	require.Equal(t, 100.0, footprint.Levels[0].Price)
+	require.Equal(t, int64(28), footprint.Levels[4].Delta)
+
+	require.Equal(t, 104.0, footprint.POCPrice)
+	require.Equal(t, int64(32), footprint.POCVolume)
+	require.Equal(t, 101.0, footprint.ValueAreaLow)
+	require.Equal(t, 104.0, footprint.ValueAreaHigh)
+
+	require.Equal(t, []ImbalanceZone{{Side: BuySide, Low: 101, High: 104, Levels: 4}}, footprint.StackedImbalances)
+
+	// Покупки в хай при закрытии ниже
+	require.Equal(t, []Absorption{{Side: SellSide, Price: 104, Volume: 30}}, footprint.Absorptions)
+
+	// Объём бара не выше среднего - поглощения нет
+	require.Empty(t, BuildFootprint(bar, FootprintConfig{}, 100).Absorptions)
+
+	sell := BuildFootprint(profileBar(10, map[float64][2]int64{10: {9, 0}, 11: {0, 3}}), FootprintConfig{}, 0)
+	require.True(t, sell.Levels[0].SellImbalance)
+	require.False(t, sell.Levels[1].BuyImbalance)
+
+	require.Nil(t, BuildFootprint(&Bar{}, FootprintConfig{}, 0))
+}
+
+func TestFootprintImbalancesByPriceStep(t *testing.T) {
+	t.Parallel()
+
+	// На 100.2 сделок не было: аск 100.3 сравнивается с пустым уровнем, а не с бидом 100.1
+	bar := profileBar(100.3, map[float64][2]int64{
+		100.0: {0, 1},
+		100.1: {9, 1},
+		100.3: {1, 4},
+		100.4: {1, 5},
+		100.5: {1, 6},
+	})
+
+	footprint := BuildFootprint(bar, FootprintConfig{PriceStep: 0.1}, 0)
+	require.True(t, footprint.Levels[2].BuyImbalance)
+	require.True(t, footprint.Levels[1].SellImbalance)
+	require.Equal(t, []ImbalanceZone{{Side: BuySide, Low: 100.3, High: 100.5, Levels: 3}}, footprint.StackedImbalances)
+
+	// С шагом 2 уровни 10 и 12 соседние, пропуска между ними нет
+	coarse := BuildFootprint(profileBar(10, map[float64][2]int64{10: {9, 0}, 12: {0, 3}}), FootprintConfig{PriceStep: 2}, 0)
+	require.True(t, coarse.Levels[0].SellImbalance)
+	require.False(t, coarse.Levels[1].BuyImbalance)
+
+	// Без шага цены уровни через одно расстояние считаются пропуском
+	unknown := BuildFootprint(profileBar(10, map[float64][2]int64{10: {9, 0}, 11: {0, 1}, 13: {0, 3}}), FootprintConfig{}, 0)
+	require.True(t, unknown.Levels[2].BuyImbalance)
+	require.True(t, unknown.Levels[0].SellImbalance)
+}
+
+func TestFootprintPriceStepFromSecurities(t *testing.T) {
+	t.Parallel()
+
+	securities := NewSecurities()
+	securities.Set(Security{Symbol: "SBER", Exchange: MOEXExchange, Board: "TQBR", PrimaryBoard: "TQBR", PriceStep: 0.01})
+
+	// Порядок опций не важен
+	before := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithSecurities(securities), WithFootprint(FootprintConfig{}))
+	require.Equal(t, 0.01, before.DataProcessor.footprint.PriceStep)
+
+	after := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithFootprint(FootprintConfig{}), WithSecurities(securities))
+	require.Equal(t, 0.01, after.DataProcessor.footprint.PriceStep)
+
+	explicit := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithSecurities(securities), WithFootprint(FootprintConfig{PriceStep: 0.1}))
+	require.Equal(t, 0.1, explicit.DataProcessor.footprint.PriceStep)
+}
+
+func TestSubscriberFootprint(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false, WithFootprint(FootprintConfig{}))
+	processor := subscriber.DataProcessor
+
+	start := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	trades := []AllTradesSlimData{
+		{ID: 1, Price: 100, Qty: 3, Timestamp: start.UnixMilli(), Side: BuySide},
+		{ID: 2, Price: 101, Qty: 5, Timestamp: start.Add(time.Second).UnixMilli(), Side: SellSide},
+		{ID: 3, Price: 102, Qty: 1, Timestamp: start.Add(time.Minute).UnixMilli(), Side: BuySide},
+	}
+
+	for _, trade := range trades {
+		require.NoError(t, processor.NewAllTrades(trade))
+	}
+
+	closed := processor.popClosed()
+	require.Len(t, closed, 1)
+	require.NotNil(t, closed[0].Footprint)
+	require.Equal(t, 101.0, closed[0].Footprint.POCPrice)
+
+	// Формирующийся бар без кластеров, они считаются по запросу
+	footprints, err := processor.Footprints(0, 10)
+	require.NoError(t, err)
+	require.Len(t, footprints, 2)
+	require.Same(t, closed[0].Footprint, footprints[0].Footprint)
+	require.Equal(t, 102.0, footprints[1].Footprint.POCPrice)
+
+	footprints, err = processor.Footprints(0, 1)
+	require.NoError(t, err)
+	require.Len(t, footprints, 1)
+
+	_, err = processor.Footprints(M5TF, 1)
+	require.ErrorIs(t, err, ErrUnknownTimeframe)
+}
+
+func TestSubscriberFootprintsDuringFeed(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, true, WithFootprint(FootprintConfig{}))
+	subscriber.Start(context.Background())
+	t.Cleanup(subscriber.Stop)
+
+	start := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	for i := range 200 {
+		trade := AllTradesSlimData{ID: int64(i + 1), Price: 100 + float64(i%7), Qty: 1, Timestamp: start.Add(time.Duration(i) * time.Second).UnixMilli(), Side: BuySide}
+		require.NoError(t, subscriber.HandleEvent(newAllTradesEvent(t, trade)))
+	}
+
+	// Кластеры формирующегося бара читаются между событиями воркера, а не посреди обновления профиля
+	require.Eventually(t, func() bool {
+		var footprints []BarFootprint
+		require.NoError(t, subscriber.view(func(p *DataProcessor) (err error) {
+			footprints, err = p.Footprints(0, 10)
+			return err
+		}))
+
+		var volume int64
+		for _, footprint := range footprints {
+			volume += footprint.Volume
+		}
+
+		return volume == 200
+	}, 5*time.Second, time.Millisecond)
+}
@@ -0,0 +1,172 @@
+package alor
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Внутренняя модель событий - Slim структуры. Simple и Heavy приводятся к ним при разборе,
+// поэтому стратегии и DataProcessor не зависят от формата подписки. Поля, которых в Slim нет,
+// у Slim структур тоже есть и заполняются только из своего формата.
+
+// IsValid формат поддерживается брокером
+func (f ResponseFormat) IsValid() bool {
+	switch f {
+	case SimpleResponseFormat, SlimResponseFormat, HeavyResponseFormat:
+		return true
+	}
+
+	return false
+}
+
+// minFrequency минимальная частота передачи данных для формата, мс
+func (f ResponseFormat) minFrequency() int {
+	switch f {
+	case SimpleResponseFormat:
+		return 25
+	case HeavyResponseFormat:
+		return 500
+	}
+
+	return 10
+}
+
+// responseFormat формат подписки, Slim если не задан
+func (s *Subscription) responseFormat() ResponseFormat {
+	if s.Format == "" {
+		return SlimResponseFormat
+	}
+
+	return s.Format
+}
+
+func (d BarsSimpleData) Slim() BarsSlimData {
+	return BarsSlimData{Time: d.Time, Close: d.Close, Open: d.Open, High: d.High, Low: d.Low, Volume: d.Volume}
+}
+
+func (d BarsHeavyData) Slim() BarsSlimData {
+	return BarsSlimData{Time: d.Time, Close: d.Close, Open: d.Open, High: d.High, Low: d.Low, Volume: d.Volume}
+}
+
+func (d AllTradesSimpleData) Slim() AllTradesSlimData {
+	return AllTradesSlimData{
+		ID:        d.ID,
+		Symbol:    d.Symbol,
+		Board:     d.Board,
+		Qty:       d.Qty,
+		Price:     d.Price,
+		Timestamp: d.Timestamp,
+		Oi:        d.Oi,
+		Existing:  d.Existing,
+		Side:      d.Side,
+		OrderNo:   d.Orderno,
+		Time:      d.Time,
+	}
+}
+
+func (d AllTradesHeavyData) Slim() AllTradesSlimData {
+	return AllTradesSlimData{
+		ID:        d.ID,
+		Symbol:    d.Symbol,
+		Board:     d.Board,
+		Qty:       d.Qty,
+		Price:     d.Price,
+		Timestamp: d.Timestamp,
+		Oi:        d.Oi,
+		Existing:  d.Existing,
+		Side:      d.Side,
+		OrderNo:   d.OrderNo,
+		Time:      d.Time,
+	}
+}
+
+func (d OrderBookSimpleData) Slim() OrderBookSlimData {
+	data := OrderBookSlimData{
+		Bids:        make([]OrderBookSlimQuote, 0, len(d.Bids)),
+		Asks:        make([]OrderBookSlimQuote, 0, len(d.Asks)),
+		MsTimestamp: d.MsTimestamp,
+		Existing:    d.Existing,
+	}
+
+	for _, quote := range d.Bids {
+		data.Bids = append(data.Bids, OrderBookSlimQuote{Price: quote.Price, Volume: quote.Volume})
+	}
+
+	for _, quote := range d.Asks {
+		data.Asks = append(data.Asks, OrderBookSlimQuote{Price: quote.Price, Volume: quote.Volume})
+	}
+
+	return data
+}
+
+func (d OrderBookHeavyData) Slim() OrderBookSlimData {
+	data := OrderBookSlimData{
+		Bids:        make([]OrderBookSlimQuote, 0, len(d.Bids)),
+		Asks:        make([]OrderBookSlimQuote, 0, len(d.Asks)),
+		MsTimestamp: d.MsTimestamp,
+		Existing:    d.Existing,
+		Depth:       d.Depth,
+	}
+
+	for _, quote := range d.Bids {
+		data.Bids = append(data.Bids, OrderBookSlimQuote{Price: quote.Price, Volume: quote.Volume, Yield: quote.Yield})
+	}
+
+	for _, quote := range d.Asks {
+		data.Asks = append(data.Asks, OrderBookSlimQuote{Price: quote.Price, Volume: quote.Volume, Yield: quote.Yield})
+	}
+
+	return data
+}
+
+func (d QuotesSimpleData) Slim() QuotesSlimData {
+	return QuotesSlimData(d)
+}
+
+func (d QuotesHeavyData) Slim() QuotesSlimData {
+	return QuotesSlimData(d)
+}
+
+// decodeData разбирает данные события в формате format и приводит их к Slim структуре
+func decodeData[Slim any, Simple interface{ Slim() Slim }, Heavy interface{ Slim() Slim }](format ResponseFormat, data []byte) (Slim, error) {
+	var result Slim
+
+	switch format {
+	case SimpleResponseFormat:
+		var simple Simple
+		if err := json.Unmarshal(data, &simple); err != nil {
+			return result, err
+		}
+
+		return simple.Slim(), nil
+	case HeavyResponseFormat:
+		var heavy Heavy
+		if err := json.Unmarshal(data, &heavy); err != nil {
+			return result, err
+		}
+
+		return heavy.Slim(), nil
+	case SlimResponseFormat, "":
+		err := json.Unmarshal(data, &result)
+
+		return result, err
+	}
+
+	return result, fmt.Errorf("unsupported response format %q", format)
+}
+
+func decodeBars(format ResponseFormat, data []byte) (BarsSlimData, error) {
+	return decodeData[BarsSlimData, BarsSimpleData, BarsHeavyData](format, data)
+}
+
+func decodeAllTrades(format ResponseFormat, data []byte) (AllTradesSlimData, error) {
+	return decodeData[AllTradesSlimData, AllTradesSimpleData, AllTradesHeavyData](format, data)
+}
+
+func decodeOrderBook(format ResponseFormat, data []byte) (OrderBookSlimData, error) {
+	return decodeData[OrderBookSlimData, OrderBookSimpleData, OrderBookHeavyData](format, data)
+}
+
+func decodeQuotes(format ResponseFormat, data []byte) (QuotesSlimData, error) {
+	return decodeData[QuotesSlimData, QuotesSimpleData, QuotesHeavyData](format, data)
+}
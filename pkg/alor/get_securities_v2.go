@@ -1,25 +1,81 @@
 package alor
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
-func (c *Client) GetAllSecurities(ticker string, limit int64, offset int64, sector string) {
-	method := "GET"
-	url := "https://apidev.alor.ru/md/v2/Securities"
+// NormalTradingStatus код tradingStatus нормального периода торгов
+const NormalTradingStatus = 17
 
-	req, err := http.NewRequest(method, url, nil)
+// Security справочные данные инструмента, формат Simple
+type Security struct {
+	Symbol            string   `json:"symbol"`            // Тикер
+	ShortName         string   `json:"shortname"`         // Короткое наименование
+	Description       string   `json:"description"`       // Полное наименование
+	Exchange          Exchange `json:"exchange"`          // Биржа
+	Board             string   `json:"board"`             // Код режима торгов
+	PrimaryBoard      string   `json:"primary_board"`     // Основной режим торгов инструмента
+	Type              string   `json:"type"`              // Тип инструмента
+	Currency          string   `json:"currency"`          // Валюта торгов
+	LotSize           float64  `json:"lotsize"`           // Размер лота
+	FaceValue         float64  `json:"facevalue"`         // Номинал
+	PriceStep         float64  `json:"minstep"`           // Минимальный шаг цены
+	PriceStepCost     float64  `json:"pricestep"`         // Стоимость шага цены в валюте
+	PriceMax          float64  `json:"priceMax"`          // Верхний лимит цены
+	PriceMin          float64  `json:"priceMin"`          // Нижний лимит цены
+	MarginBuy         float64  `json:"marginbuy"`         // Гарантийное обеспечение на покупку
+	MarginSell        float64  `json:"marginsell"`        // Гарантийное обеспечение на продажу
+	ISIN              string   `json:"ISIN"`              // ISIN
+	CfiCode           string   `json:"cfiCode"`           // CFI код
+	TradingStatus     int      `json:"tradingStatus"`     // Код состояния торгов
+	TradingStatusInfo string   `json:"tradingStatusInfo"` // Описание состояния торгов
+}
+
+// IsTrading инструмент в нормальном периоде торгов
+func (s Security) IsTrading() bool {
+	return s.TradingStatus == NormalTradingStatus
+}
+
+// InPriceLimits цена в пределах дневных лимитов, нулевые лимиты не ограничивают
+func (s Security) InPriceLimits(price float64) bool {
+	if s.PriceMin > 0 && price < s.PriceMin {
+		return false
+	}
+
+	if s.PriceMax > 0 && price > s.PriceMax {
+		return false
+	}
+
+	return true
+}
 
+// GetSecurities все инструменты биржи
+func (c *Client) GetSecurities(exchange Exchange) ([]Security, error) {
+	var data []Security
+
+	// GET https://apidev.alor.ru/md/v2/Securities/:exchange
+	url := fmt.Sprintf("%s/md/v2/Securities/%s", c.Hosts.Data, exchange)
+
+	ctx, cncl := context.WithTimeout(context.Background(), time.Second*30)
+	defer cncl()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return data, err
 	}
 
+	q := req.URL.Query()
+	q.Add("format", string(SimpleResponseFormat))
+	req.URL.RawQuery = q.Encode()
+
 	accessToken, err := c.Token.GetAccessToken()
 	if err != nil {
-		return
+		return data, err
 	}
 
 	req.Header.Add("Accept", "application/json")
@@ -27,17 +83,24 @@ func (c *Client) GetAllSecurities(ticker string, limit int64, offset int64, sect
 
 	res, err := c.Client.Do(req)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return data, err
 	}
 	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return data, err
 	}
-	fmt.Println(string(body))
+
+	if res.StatusCode != http.StatusOK {
+		return data, fmt.Errorf("get securities: %s: %s", res.Status, body)
+	}
+
+	if err := json.Unmarshal(body, &data); err != nil {
+		return data, err
+	}
+
+	return data, nil
 }
 
 // riskCategoryId можно узнать командой https://alor.dev/docs/api/http/md-v-2-clients-exchange-portfolio-risk-get
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type MdV2TimeResponse struct {
@@ -21,6 +22,9 @@ func (c *Client) GetUnixTimestamp() (int64, error) {
 	}
 
 	token, err := c.Token.GetAccessToken()
+	if err != nil {
+		return 0, err
+	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
@@ -34,9 +38,14 @@ func (c *Client) GetUnixTimestamp() (int64, error) {
 		_ = res.Body.Close()
 	}()
 
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%w: status %d", ErrCommandFailed, res.StatusCode)
+	}
+
 	body, _ := io.ReadAll(res.Body)
 
-	timestamp, err := strconv.ParseInt(string(body), 16, 8)
+	// Сервер отвечает количеством секунд с начала эпохи в десятичной записи
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
 	if err != nil {
 		return 0, err
 	}
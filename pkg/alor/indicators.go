@@ -1 +1,305 @@
 package alor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Indicator инкрементальный индикатор.
+// Update получает закрытый бар, меняет внутреннее состояние и возвращает итоговое значение,
+// nil - пока индикатору не хватает баров. Для формирующегося бара DataProcessor вызывает
+// Update у копии из Clone, поэтому состояние закрытых баров не портится.
+type Indicator interface {
+	Update(bar *Bar) IndicatorValue
+	Clone() Indicator
+}
+
+// IndicatorValue значения линий индикатора на баре.
+// У однолинейных индикаторов единственная линия называется "value".
+type IndicatorValue map[string]float64
+
+const IndicatorMainLine = "value"
+
+// Value значение основной линии
+func (v IndicatorValue) Value() float64 {
+	return v[IndicatorMainLine]
+}
+
+// Line значение линии по имени (например, "signal" у MACD)
+func (v IndicatorValue) Line(name string) (float64, bool) {
+	value, ok := v[name]
+	return value, ok
+}
+
+type PriceSource string
+
+var (
+	OpenPriceSource  PriceSource = "open"
+	HighPriceSource  PriceSource = "high"
+	LowPriceSource   PriceSource = "low"
+	ClosePriceSource PriceSource = "close"
+	HL2PriceSource   PriceSource = "hl2"   // (High + Low) / 2
+	HLC3PriceSource  PriceSource = "hlc3"  // (High + Low + Close) / 3
+	OHLC4PriceSource PriceSource = "ohlc4" // (Open + High + Low + Close) / 4
+)
+
+func (s PriceSource) Price(bar *Bar) float64 {
+	switch s {
+	case OpenPriceSource:
+		return bar.Open
+	case HighPriceSource:
+		return bar.High
+	case LowPriceSource:
+		return bar.Low
+	case HL2PriceSource:
+		return (bar.High + bar.Low) / 2
+	case HLC3PriceSource:
+		return (bar.High + bar.Low + bar.Close) / 3
+	case OHLC4PriceSource:
+		return (bar.Open + bar.High + bar.Low + bar.Close) / 4
+	default:
+		return bar.Close
+	}
+}
+
+// Настройки индикаторов для создания по имени типа (из HTTP)
+
+type MovingAverageSettings struct {
+	Period int         `json:"period" validate:"required,min=1,max=1000" description:"Период"`
+	Source PriceSource `json:"source" validate:"omitempty,oneof=open high low close hl2 hlc3 ohlc4" description:"Источник цены"`
+}
+
+type MACDSettings struct {
+	Fast   int         `json:"fast" validate:"required,min=1,max=1000" description:"Период быстрой EMA"`
+	Slow   int         `json:"slow" validate:"required,min=1,max=1000,gtfield=Fast" description:"Период медленной EMA"`
+	Signal int         `json:"signal" validate:"required,min=1,max=1000" description:"Период сигнальной линии"`
+	Source PriceSource `json:"source" validate:"omitempty,oneof=open high low close hl2 hlc3 ohlc4" description:"Источник цены"`
+}
+
+type BollingerBandsSettings struct {
+	Period    int         `json:"period" validate:"required,min=1,max=1000" description:"Период"`
+	Deviation float64     `json:"deviation" validate:"required,gt=0" description:"Ширина канала в стандартных отклонениях"`
+	Source    PriceSource `json:"source" validate:"omitempty,oneof=open high low close hl2 hlc3 ohlc4" description:"Источник цены"`
+}
+
+type ATRSettings struct {
+	Period int `json:"period" validate:"required,min=1,max=1000" description:"Период"`
+}
+
+type StochasticSettings struct {
+	KPeriod int `json:"kPeriod" validate:"required,min=1,max=1000" description:"Период %K"`
+	DPeriod int `json:"dPeriod" validate:"required,min=1,max=1000" description:"Период %D"`
+}
+
+type IndicatorSettingsError struct {
+	Indicator string               `json:"indicator"`
+	Fields    []SettingsFieldError `json:"fields"`
+}
+
+func (e *IndicatorSettingsError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+
+	return fmt.Sprintf("%s for %s: %s", ErrInvalidIndicatorSettings, e.Indicator, strings.Join(messages, "; "))
+}
+
+func (e *IndicatorSettingsError) Unwrap() error {
+	return ErrInvalidIndicatorSettings
+}
+
+// NewIndicator создаёт индикатор по типу и JSON настройкам, незаданные поля берутся по умолчанию
+func NewIndicator(kind string, settings json.RawMessage) (Indicator, error) {
+	switch strings.ToLower(kind) {
+	case "sma":
+		s, err := decodeIndicatorSettings(kind, settings, MovingAverageSettings{Period: 20})
+		if err != nil {
+			return nil, err
+		}
+		return NewSMA(s.Period, s.Source), nil
+	case "ema":
+		s, err := decodeIndicatorSettings(kind, settings, MovingAverageSettings{Period: 20})
+		if err != nil {
+			return nil, err
+		}
+		return NewEMA(s.Period, s.Source), nil
+	case "wma":
+		s, err := decodeIndicatorSettings(kind, settings, MovingAverageSettings{Period: 20})
+		if err != nil {
+			return nil, err
+		}
+		return NewWMA(s.Period, s.Source), nil
+	case "rsi":
+		s, err := decodeIndicatorSettings(kind, settings, MovingAverageSettings{Period: 14})
+		if err != nil {
+			return nil, err
+		}
+		return NewRSI(s.Period, s.Source), nil
+	case "macd":
+		s, err := decodeIndicatorSettings(kind, settings, MACDSettings{Fast: 12, Slow: 26, Signal: 9})
+		if err != nil {
+			return nil, err
+		}
+		return NewMACD(s.Fast, s.Slow, s.Signal, s.Source), nil
+	case "bollinger", "bb":
+		s, err := decodeIndicatorSettings(kind, settings, BollingerBandsSettings{Period: 20, Deviation: 2})
+		if err != nil {
+			return nil, err
+		}
+		return NewBollingerBands(s.Period, s.Deviation, s.Source), nil
+	case "atr":
+		s, err := decodeIndicatorSettings(kind, settings, ATRSettings{Period: 14})
+		if err != nil {
+			return nil, err
+		}
+		return NewATR(s.Period), nil
+	case "stochastic", "stoch":
+		s, err := decodeIndicatorSettings(kind, settings, StochasticSettings{KPeriod: 14, DPeriod: 3})
+		if err != nil {
+			return nil, err
+		}
+		return NewStochastic(s.KPeriod, s.DPeriod), nil
+	case "obv":
+		if _, err := decodeIndicatorSettings(kind, settings, struct{}{}); err != nil {
+			return nil, err
+		}
+		return NewOBV(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIndicator, kind)
+	}
+}
+
+func decodeIndicatorSettings[T any](kind string, rawSettings json.RawMessage, defaults T) (T, error) {
+	settings := defaults
+
+	fields, err := decodeSettings(rawSettings, &settings)
+	if err != nil {
+		return settings, err
+	}
+
+	if len(fields) != 0 {
+		return settings, &IndicatorSettingsError{Indicator: kind, Fields: fields}
+	}
+
+	return settings, nil
+}
+
+// Расчёт индикаторов в DataProcessor
+
+type indicatorSlot struct {
+	name      string
+	indicator Indicator
+}
+
+// AddIndicator подключает индикатор под именем, повторное имя заменяет индикатор
+func (p *DataProcessor) AddIndicator(name string, indicator Indicator) {
+	p.indicators = addIndicatorSlot(p.indicators, name, indicator)
+}
+
+func addIndicatorSlot(slots []indicatorSlot, name string, indicator Indicator) []indicatorSlot {
+	for i, slot := range slots {
+		if slot.name == name {
+			slots[i].indicator = indicator
+			return slots
+		}
+	}
+
+	return append(slots, indicatorSlot{name: name, indicator: indicator})
+}
+
+// IndicatorNames имена подключенных индикаторов в порядке добавления
+func (p *DataProcessor) IndicatorNames() []string {
+	names := make([]string, 0, len(p.indicators))
+	for _, slot := range p.indicators {
+		names = append(names, slot.name)
+	}
+
+	return names
+}
+
+// GetIndicatorValue значение индикатора на баре с конца: 0 - формирующийся бар, 1 - последний закрытый и т.д.
+func (p *DataProcessor) GetIndicatorValue(name string, index int64) (IndicatorValue, error) {
+	return indicatorValue(p.bars, name, index)
+}
+
+func indicatorValue(bars *BarQueue, name string, index int64) (IndicatorValue, error) {
+	bar, err := bars.GetBarFromEnd(index)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := bar.Indicators[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrIndicatorNotReady, name)
+	}
+
+	return value, nil
+}
+
+// previewIndicators пересчитывает значения на формирующемся баре по копиям индикаторов
+func (p *DataProcessor) previewIndicators(bar *Bar) {
+	previewIndicatorSlots(p.indicators, bar)
+}
+
+func previewIndicatorSlots(slots []indicatorSlot, bar *Bar) {
+	for _, slot := range slots {
+		setIndicatorValue(bar, slot.name, slot.indicator.Clone().Update(bar))
+	}
+}
+
+func commitIndicatorSlots(slots []indicatorSlot, bar *Bar) {
+	for _, slot := range slots {
+		setIndicatorValue(bar, slot.name, slot.indicator.Update(bar))
+	}
+}
+
+func setIndicatorValue(bar *Bar, name string, value IndicatorValue) {
+	if value == nil {
+		delete(bar.Indicators, name)
+		return
+	}
+
+	bar.Indicators[name] = value
+}
+
+// rollingWindow последние size значений с текущей суммой
+type rollingWindow struct {
+	values []float64
+	size   int
+	sum    float64
+}
+
+func newRollingWindow(size int) rollingWindow {
+	return rollingWindow{
+		values: make([]float64, 0, size),
+		size:   size,
+	}
+}
+
+func (w *rollingWindow) push(value float64) {
+	if len(w.values) == w.size {
+		w.sum -= w.values[0]
+		w.values = append(w.values[:0], w.values[1:]...)
+	}
+
+	w.values = append(w.values, value)
+	w.sum += value
+}
+
+func (w *rollingWindow) full() bool {
+	return len(w.values) == w.size
+}
+
+func (w *rollingWindow) mean() float64 {
+	return w.sum / float64(len(w.values))
+}
+
+func (w *rollingWindow) clone() rollingWindow {
+	clone := *w
+	clone.values = make([]float64, len(w.values), w.size)
+	copy(clone.values, w.values)
+
+	return clone
+}
@@ -0,0 +1,131 @@
+package alor
+
+// SMA простая скользящая средняя
+type SMA struct {
+	source PriceSource
+	window rollingWindow
+}
+
+func NewSMA(period int, source PriceSource) *SMA {
+	return &SMA{
+		source: source,
+		window: newRollingWindow(period),
+	}
+}
+
+func (i *SMA) Update(bar *Bar) IndicatorValue {
+	i.window.push(i.source.Price(bar))
+
+	if !i.window.full() {
+		return nil
+	}
+
+	return IndicatorValue{IndicatorMainLine: i.window.mean()}
+}
+
+func (i *SMA) Clone() Indicator {
+	clone := *i
+	clone.window = i.window.clone()
+
+	return &clone
+}
+
+// emaState экспоненциальное сглаживание, первое значение - SMA за период
+type emaState struct {
+	period  int
+	alpha   float64
+	count   int
+	seedSum float64
+	value   float64
+}
+
+func newEMAState(period int) emaState {
+	return emaState{
+		period: period,
+		alpha:  2 / float64(period+1),
+	}
+}
+
+func (s *emaState) push(value float64) (float64, bool) {
+	s.count++
+
+	if s.count < s.period {
+		s.seedSum += value
+		return 0, false
+	}
+
+	if s.count == s.period {
+		s.seedSum += value
+		s.value = s.seedSum / float64(s.period)
+		return s.value, true
+	}
+
+	s.value += s.alpha * (value - s.value)
+
+	return s.value, true
+}
+
+// EMA экспоненциальная скользящая средняя
+type EMA struct {
+	source PriceSource
+	ema    emaState
+}
+
+func NewEMA(period int, source PriceSource) *EMA {
+	return &EMA{
+		source: source,
+		ema:    newEMAState(period),
+	}
+}
+
+func (i *EMA) Update(bar *Bar) IndicatorValue {
+	value, ok := i.ema.push(i.source.Price(bar))
+	if !ok {
+		return nil
+	}
+
+	return IndicatorValue{IndicatorMainLine: value}
+}
+
+func (i *EMA) Clone() Indicator {
+	clone := *i
+
+	return &clone
+}
+
+// WMA линейно взвешенная скользящая средняя, у последнего бара наибольший вес
+type WMA struct {
+	source PriceSource
+	window rollingWindow
+}
+
+func NewWMA(period int, source PriceSource) *WMA {
+	return &WMA{
+		source: source,
+		window: newRollingWindow(period),
+	}
+}
+
+func (i *WMA) Update(bar *Bar) IndicatorValue {
+	i.window.push(i.source.Price(bar))
+
+	if !i.window.full() {
+		return nil
+	}
+
+	var weighted, weights float64
+	for index, value := range i.window.values {
+		weight := float64(index + 1)
+		weighted += value * weight
+		weights += weight
+	}
+
+	return IndicatorValue{IndicatorMainLine: weighted / weights}
+}
+
+func (i *WMA) Clone() Indicator {
+	clone := *i
+	clone.window = i.window.clone()
+
+	return &clone
+}
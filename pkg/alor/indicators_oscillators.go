@@ -0,0 +1,173 @@
+package alor
+
+// RSI индекс относительной силы со сглаживанием Уайлдера
+type RSI struct {
+	source  PriceSource
+	period  int
+	count   int
+	hasPrev bool
+	prev    float64
+	avgGain float64
+	avgLoss float64
+}
+
+func NewRSI(period int, source PriceSource) *RSI {
+	return &RSI{
+		source: source,
+		period: period,
+	}
+}
+
+func (i *RSI) Update(bar *Bar) IndicatorValue {
+	price := i.source.Price(bar)
+
+	if !i.hasPrev {
+		i.prev = price
+		i.hasPrev = true
+		return nil
+	}
+
+	change := price - i.prev
+	i.prev = price
+	gain, loss := max(change, 0), max(-change, 0)
+
+	i.count++
+	period := float64(i.period)
+
+	if i.count <= i.period {
+		// Первые period изменений - простое среднее
+		i.avgGain += gain / period
+		i.avgLoss += loss / period
+
+		if i.count < i.period {
+			return nil
+		}
+	} else {
+		i.avgGain = (i.avgGain*(period-1) + gain) / period
+		i.avgLoss = (i.avgLoss*(period-1) + loss) / period
+	}
+
+	var rsi float64
+
+	switch {
+	case i.avgLoss == 0 && i.avgGain == 0:
+		rsi = 50
+	case i.avgLoss == 0:
+		rsi = 100
+	default:
+		rsi = 100 - 100/(1+i.avgGain/i.avgLoss)
+	}
+
+	return IndicatorValue{IndicatorMainLine: rsi}
+}
+
+func (i *RSI) Clone() Indicator {
+	clone := *i
+
+	return &clone
+}
+
+// MACD схождение/расхождение скользящих средних.
+// Линии: macd, signal, histogram.
+type MACD struct {
+	source PriceSource
+	fast   emaState
+	slow   emaState
+	signal emaState
+}
+
+func NewMACD(fast, slow, signal int, source PriceSource) *MACD {
+	return &MACD{
+		source: source,
+		fast:   newEMAState(fast),
+		slow:   newEMAState(slow),
+		signal: newEMAState(signal),
+	}
+}
+
+func (i *MACD) Update(bar *Bar) IndicatorValue {
+	price := i.source.Price(bar)
+
+	fast, fastOk := i.fast.push(price)
+	slow, slowOk := i.slow.push(price)
+
+	if !fastOk || !slowOk {
+		return nil
+	}
+
+	macd := fast - slow
+
+	signal, ok := i.signal.push(macd)
+	if !ok {
+		return nil
+	}
+
+	return IndicatorValue{
+		"macd":      macd,
+		"signal":    signal,
+		"histogram": macd - signal,
+	}
+}
+
+func (i *MACD) Clone() Indicator {
+	clone := *i
+
+	return &clone
+}
+
+// Stochastic стохастический осциллятор.
+// Линии: k, d.
+type Stochastic struct {
+	highs rollingWindow
+	lows  rollingWindow
+	k     rollingWindow
+}
+
+func NewStochastic(kPeriod, dPeriod int) *Stochastic {
+	return &Stochastic{
+		highs: newRollingWindow(kPeriod),
+		lows:  newRollingWindow(kPeriod),
+		k:     newRollingWindow(dPeriod),
+	}
+}
+
+func (i *Stochastic) Update(bar *Bar) IndicatorValue {
+	i.highs.push(bar.High)
+	i.lows.push(bar.Low)
+
+	if !i.highs.full() {
+		return nil
+	}
+
+	highest, lowest := i.highs.values[0], i.lows.values[0]
+	for index := range i.highs.values {
+		highest = max(highest, i.highs.values[index])
+		lowest = min(lowest, i.lows.values[index])
+	}
+
+	// Без диапазона цена стоит на месте - считаем середину
+	k := 50.0
+	if highest != lowest {
+		k = (bar.Close - lowest) / (highest - lowest) * 100
+	}
+
+	i.k.push(k)
+
+	if !i.k.full() {
+		return nil
+	}
+
+	return IndicatorValue{
+		"k": k,
+		"d": i.k.mean(),
+	}
+}
+
+func (i *Stochastic) Clone() Indicator {
+	clone := *i
+	clone.highs = i.highs.clone()
+	clone.lows = i.lows.clone()
+	clone.k = i.k.clone()
+
+	return &clone
+}
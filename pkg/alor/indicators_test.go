@@ -0,0 +1,75 @@
+package alor
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMovingAverages(t *testing.T) {
+	t.Parallel()
+
+	sma := NewSMA(3, ClosePriceSource)
+	ema := NewEMA(3, ClosePriceSource)
+
+	var smaValue, emaValue IndicatorValue
+	for _, price := range []float64{1, 2, 3, 4} {
+		smaValue = sma.Update(&Bar{Close: price})
+		emaValue = ema.Update(&Bar{Close: price})
+	}
+
+	require.InDelta(t, 3.0, smaValue.Value(), 1e-9)
+	// Затравка SMA(1,2,3) = 2, далее 2 + 0.5*(4-2)
+	require.InDelta(t, 3.0, emaValue.Value(), 1e-9)
+
+	require.Nil(t, NewSMA(3, ClosePriceSource).Update(&Bar{Close: 1}))
+}
+
+func TestDataProcessorIndicatorPreviewAndCommit(t *testing.T) {
+	t.Parallel()
+
+	processor := NewDataProcessor(M1TF)
+	processor.AddIndicator("sma2", NewSMA(2, ClosePriceSource))
+
+	require.NoError(t, processor.NewBar(BarsSlimData{Time: 0, Open: 10, High: 10, Low: 10, Close: 10}))
+	_, err := processor.GetIndicatorValue("sma2", 0)
+	require.ErrorIs(t, err, ErrIndicatorNotReady)
+
+	// Формирующийся бар пересчитывается на каждое обновление, состояние не накапливается
+	require.NoError(t, processor.NewBar(BarsSlimData{Time: 60, Open: 12, High: 12, Low: 12, Close: 12}))
+	require.NoError(t, processor.NewBar(BarsSlimData{Time: 60, Open: 12, High: 14, Low: 12, Close: 14}))
+
+	value, err := processor.GetIndicatorValue("sma2", 0)
+	require.NoError(t, err)
+	require.InDelta(t, 12.0, value.Value(), 1e-9)
+
+	require.NoError(t, processor.NewBar(BarsSlimData{Time: 120, Open: 16, High: 16, Low: 16, Close: 16}))
+
+	value, err = processor.GetIndicatorValue("sma2", 1)
+	require.NoError(t, err)
+	require.InDelta(t, 12.0, value.Value(), 1e-9)
+
+	value, err = processor.GetIndicatorValue("sma2", 0)
+	require.NoError(t, err)
+	require.InDelta(t, 15.0, value.Value(), 1e-9)
+}
+
+func TestNewIndicatorSettings(t *testing.T) {
+	t.Parallel()
+
+	indicator, err := NewIndicator("MACD", nil)
+	require.NoError(t, err)
+	require.IsType(t, &MACD{}, indicator)
+
+	_, err = NewIndicator("macd", json.RawMessage(`{"fast":26,"slow":12}`))
+	require.ErrorIs(t, err, ErrInvalidIndicatorSettings)
+
+	var settingsErr *IndicatorSettingsError
+	require.True(t, errors.As(err, &settingsErr))
+	require.Len(t, settingsErr.Fields, 1)
+	require.Equal(t, "slow", settingsErr.Fields[0].Field)
+
+	_, err = NewIndicator("ichimoku", nil)
+	require.ErrorIs(t, err, ErrUnknownIndicator)
+}
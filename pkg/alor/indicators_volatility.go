@@ -0,0 +1,96 @@
+package alor
+
+import "math"
+
+// BollingerBands полосы Боллинджера.
+// Линии: upper, middle, lower.
+type BollingerBands struct {
+	source    PriceSource
+	deviation float64
+	window    rollingWindow
+}
+
+func NewBollingerBands(period int, deviation float64, source PriceSource) *BollingerBands {
+	return &BollingerBands{
+		source:    source,
+		deviation: deviation,
+		window:    newRollingWindow(period),
+	}
+}
+
+func (i *BollingerBands) Update(bar *Bar) IndicatorValue {
+	i.window.push(i.source.Price(bar))
+
+	if !i.window.full() {
+		return nil
+	}
+
+	middle := i.window.mean()
+
+	var variance float64
+	for _, value := range i.window.values {
+		variance += (value - middle) * (value - middle)
+	}
+
+	stdDev := math.Sqrt(variance / float64(len(i.window.values)))
+
+	return IndicatorValue{
+		"upper":  middle + i.deviation*stdDev,
+		"middle": middle,
+		"lower":  middle - i.deviation*stdDev,
+	}
+}
+
+func (i *BollingerBands) Clone() Indicator {
+	clone := *i
+	clone.window = i.window.clone()
+
+	return &clone
+}
+
+// ATR средний истинный диапазон со сглаживанием Уайлдера
+type ATR struct {
+	period    int
+	count     int
+	hasPrev   bool
+	prevClose float64
+	value     float64
+}
+
+func NewATR(period int) *ATR {
+	return &ATR{
+		period: period,
+	}
+}
+
+func (i *ATR) Update(bar *Bar) IndicatorValue {
+	trueRange := bar.High - bar.Low
+	if i.hasPrev {
+		trueRange = max(trueRange, math.Abs(bar.High-i.prevClose), math.Abs(bar.Low-i.prevClose))
+	}
+
+	i.prevClose = bar.Close
+	i.hasPrev = true
+	i.count++
+
+	period := float64(i.period)
+
+	if i.count <= i.period {
+		// Первые period баров - простое среднее
+		i.value += trueRange / period
+
+		if i.count < i.period {
+			return nil
+		}
+	} else {
+		i.value = (i.value*(period-1) + trueRange) / period
+	}
+
+	return IndicatorValue{IndicatorMainLine: i.value}
+}
+
+func (i *ATR) Clone() Indicator {
+	clone := *i
+
+	return &clone
+}
@@ -0,0 +1,34 @@
+package alor
+
+// OBV балансовый объём
+type OBV struct {
+	hasPrev   bool
+	prevClose float64
+	value     float64
+}
+
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+func (i *OBV) Update(bar *Bar) IndicatorValue {
+	if i.hasPrev {
+		switch {
+		case bar.Close > i.prevClose:
+			i.value += float64(bar.Volume)
+		case bar.Close < i.prevClose:
+			i.value -= float64(bar.Volume)
+		}
+	}
+
+	i.prevClose = bar.Close
+	i.hasPrev = true
+
+	return IndicatorValue{IndicatorMainLine: i.value}
+}
+
+func (i *OBV) Clone() Indicator {
+	clone := *i
+
+	return &clone
+}
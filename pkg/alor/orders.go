@@ -0,0 +1,238 @@
+package alor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// OrdersOpcodes потоки собственных заявок и сделок портфеля
+var OrdersOpcodes = []Opcode{OrdersOpcode, StopOrdersOpcode, TradesOpcode}
+
+func isOrdersOpcode(opcode Opcode) bool {
+	switch opcode {
+	case OrdersOpcode, StopOrdersOpcode, TradesOpcode:
+		return true
+	}
+
+	return false
+}
+
+// orderComment помечает комментарий заявки ID подписчика.
+// Событие заявки может прийти раньше ответа на команду, тогда владельца находим по комментарию.
+func orderComment(subscriberID SubscriberID, comment string) string {
+	if comment == "" {
+		return subscriberID.String()
+	}
+
+	return fmt.Sprintf("%s %s", subscriberID, comment)
+}
+
+// commentOwner ID подписчика из комментария, оставленного orderComment
+func commentOwner(comment string) (SubscriberID, bool) {
+	if len(comment) < len(uuid.Nil.String()) {
+		return SubscriberID(uuid.Nil), false
+	}
+
+	id, err := uuid.Parse(comment[:len(uuid.Nil.String())])
+	if err != nil {
+		return SubscriberID(uuid.Nil), false
+	}
+
+	return SubscriberID(id), true
+}
+
+func NewOrderRouter() *OrderRouter {
+	return &OrderRouter{
+		owners: make(map[OrderID]*orderOwner),
+	}
+}
+
+// OrderRouter помнит, какой подписчик выставил заявку, чтобы вернуть ему её события.
+// Заявка забывается, когда она в финальном статусе и все её сделки отданы владельцу.
+type OrderRouter struct {
+	owners map[OrderID]*orderOwner
+	mu     sync.RWMutex
+}
+
+type orderOwner struct {
+	subscriberID SubscriberID
+	final        bool  // Заявка исполнена, отменена или отклонена
+	filled       int64 // Исполнено лотов по последнему статусу
+	routed       int64 // Лотов в отданных владельцу сделках
+}
+
+func (r *OrderRouter) Track(subscriberID SubscriberID, orderID OrderID) {
+	if orderID == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if owner, ok := r.owners[orderID]; ok {
+		owner.subscriberID = subscriberID
+		return
+	}
+
+	r.owners[orderID] = &orderOwner{subscriberID: subscriberID}
+}
+
+// Owner ищет владельца по номеру заявки, затем по комментарию
+func (r *OrderRouter) Owner(orderID OrderID, comment string) (SubscriberID, bool) {
+	r.mu.RLock()
+	owner, ok := r.owners[orderID]
+	r.mu.RUnlock()
+
+	if ok {
+		return owner.subscriberID, true
+	}
+
+	subscriberID, ok := commentOwner(comment)
+	if !ok {
+		return subscriberID, false
+	}
+
+	// Сделки по заявке могут прийти без комментария
+	r.Track(subscriberID, orderID)
+
+	return subscriberID, true
+}
+
+// Forget забывает заявки удалённого подписчика
+func (r *OrderRouter) Forget(subscriberID SubscriberID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for orderID, owner := range r.owners {
+		if owner.subscriberID == subscriberID {
+			delete(r.owners, orderID)
+		}
+	}
+}
+
+// orderRouting поля события, по которым ищется владелец заявки и видно, что заявка отработала
+type orderRouting struct {
+	ID          string      `json:"id"`
+	OrderNumber string      `json:"ono"`
+	Comment     string      `json:"cmt"`
+	Status      OrderStatus `json:"st"`
+	Qty         int64       `json:"q"`
+	FilledQty   int64       `json:"fq"`
+}
+
+func (r *OrderRouter) route(event *ChainEvent) (SubscriberID, bool, error) {
+	var routing orderRouting
+	if err := json.Unmarshal(event.Data, &routing); err != nil {
+		return SubscriberID(uuid.Nil), false, err
+	}
+
+	orderID := OrderID(routing.ID)
+	if event.Opcode == TradesOpcode {
+		orderID = OrderID(routing.OrderNumber)
+	}
+
+	subscriberID, ok := r.Owner(orderID, routing.Comment)
+	if ok {
+		r.settle(orderID, event.Opcode, routing)
+	}
+
+	return subscriberID, ok, nil
+}
+
+// settle учитывает статус и сделки заявки и забывает её после финального статуса и последней сделки
+func (r *OrderRouter) settle(orderID OrderID, opcode Opcode, routing orderRouting) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owner, ok := r.owners[orderID]
+	if !ok {
+		return
+	}
+
+	switch opcode {
+	case OrdersOpcode:
+		owner.final = routing.Status.IsFinal()
+		owner.filled = routing.FilledQty
+	case StopOrdersOpcode:
+		// Сделки сработавшей стоп-заявки идут по номеру выставленной ею заявки
+		owner.final = routing.Status.IsFinal()
+	case TradesOpcode:
+		owner.routed += routing.Qty
+	}
+
+	if owner.final && owner.routed >= owner.filled {
+		delete(r.owners, orderID)
+	}
+}
+
+// trackingCommandBus запоминает заявки подписчика в OrderRouter и помечает их комментарием
+type trackingCommandBus struct {
+	CommandBus
+	router       *OrderRouter
+	subscriberID SubscriberID
+}
+
+func (b *trackingCommandBus) track(orderID OrderID, err error) (OrderID, error) {
+	if err == nil {
+		b.router.Track(b.subscriberID, orderID)
+	}
+
+	return orderID, err
+}
+
+func (b *trackingCommandBus) target(target OrderTarget) OrderTarget {
+	target.Comment = orderComment(b.subscriberID, target.Comment)
+
+	return target
+}
+
+func (b *trackingCommandBus) CreateMarketOrder(params MarketOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.CreateMarketOrder(params))
+}
+
+func (b *trackingCommandBus) CreateLimitOrder(params LimitOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.CreateLimitOrder(params))
+}
+
+func (b *trackingCommandBus) CreateStopOrder(params StopOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.CreateStopOrder(params))
+}
+
+func (b *trackingCommandBus) CreateStopLimitOrder(params StopLimitOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.CreateStopLimitOrder(params))
+}
+
+func (b *trackingCommandBus) ModifyMarketOrder(orderID OrderID, params MarketOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.ModifyMarketOrder(orderID, params))
+}
+
+func (b *trackingCommandBus) ModifyLimitOrder(orderID OrderID, params LimitOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.ModifyLimitOrder(orderID, params))
+}
+
+func (b *trackingCommandBus) ModifyStopOrder(orderID OrderID, params StopOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.ModifyStopOrder(orderID, params))
+}
+
+func (b *trackingCommandBus) ModifyStopLimitOrder(orderID OrderID, params StopLimitOrderParams) (OrderID, error) {
+	params.OrderTarget = b.target(params.OrderTarget)
+	return b.track(b.CommandBus.ModifyStopLimitOrder(orderID, params))
+}
+
+// SubscribeOrders подписывает клиента на собственные заявки, стоп-заявки и сделки портфеля.
+// События получает подписчик, выставивший заявку через свою шину команд.
+func (c *Client) SubscribeOrders(exchange Exchange, portfolio string) error {
+	_, err := c.SubscribePortfolio(exchange, portfolio, OrdersOpcodes...)
+
+	return err
+}
@@ -0,0 +1,209 @@
+package alor
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Данные собственных заявок и сделок приходят по подписке на портфель, формат запроса как у PortfolioRequest
+
+type OrderStatus string
+
+var (
+	WorkingOrderStatus  OrderStatus = "working"  // На исполнении
+	FilledOrderStatus   OrderStatus = "filled"   // Исполнена
+	CanceledOrderStatus OrderStatus = "canceled" // Отменена
+	RejectedOrderStatus OrderStatus = "rejected" // Отклонена
+)
+
+// IsFinal заявка больше не изменится
+func (s OrderStatus) IsFinal() bool {
+	return s == FilledOrderStatus || s == CanceledOrderStatus || s == RejectedOrderStatus
+}
+
+// OrderSlimData рыночная или лимитная заявка
+type OrderSlimData struct {
+	ID          string      `json:"id"`   // Уникальный идентификатор заявки
+	Symbol      string      `json:"sym"`  // Тикер
+	Portfolio   string      `json:"p"`    // Портфель
+	Exchange    Exchange    `json:"ex"`   // Биржа
+	Board       string      `json:"bg"`   // Код режима торгов
+	Comment     string      `json:"cmt"`  // Пользовательский комментарий к заявке
+	Type        string      `json:"t"`    // Тип заявки: market, limit
+	Side        OrderSide   `json:"s"`    // Направление сделки
+	Status      OrderStatus `json:"st"`   // Статус заявки
+	TransTime   time.Time   `json:"tt"`   // Время выставления
+	UpdateTime  time.Time   `json:"ut"`   // Время последнего изменения
+	Qty         int64       `json:"q"`    // Количество в лотах
+	FilledQty   int64       `json:"fq"`   // Исполненное количество в лотах
+	Price       float64     `json:"px"`   // Цена
+	TimeInForce TimeInForce `json:"tf"`   // Условие по времени действия заявки
+	Existing    bool        `json:"h"`    // Данные из истории, а не новое событие
+	Volume      float64     `json:"v"`    // Объём исполненной части
+	Iceberg     bool        `json:"iceb"` // Айсберг-заявка
+}
+
+// StopOrderSlimData стоп-заявка или стоп-лимит
+type StopOrderSlimData struct {
+	ID           string        `json:"id"`   // Уникальный идентификатор заявки
+	Symbol       string        `json:"sym"`  // Тикер
+	Portfolio    string        `json:"p"`    // Портфель
+	Exchange     Exchange      `json:"ex"`   // Биржа
+	Board        string        `json:"bg"`   // Код режима торгов
+	Comment      string        `json:"cmt"`  // Пользовательский комментарий к заявке
+	Type         string        `json:"t"`    // Тип заявки: stop, stoplimit
+	Side         OrderSide     `json:"s"`    // Направление сделки
+	Status       OrderStatus   `json:"st"`   // Статус заявки
+	TransTime    time.Time     `json:"tt"`   // Время выставления
+	UpdateTime   time.Time     `json:"ut"`   // Время последнего изменения
+	EndTime      time.Time     `json:"et"`   // Срок действия
+	Qty          int64         `json:"q"`    // Количество в лотах
+	FilledQty    int64         `json:"fq"`   // Исполненное количество в лотах
+	Price        float64       `json:"px"`   // Цена выставления лимитной заявки
+	TriggerPrice float64       `json:"sp"`   // Стоп-цена
+	Condition    StopCondition `json:"cnd"`  // Условие срабатывания
+	TimeInForce  TimeInForce   `json:"tf"`   // Условие по времени действия заявки
+	Existing     bool          `json:"h"`    // Данные из истории, а не новое событие
+	Volume       float64       `json:"v"`    // Объём исполненной части
+	Iceberg      bool          `json:"iceb"` // Айсберг-заявка
+}
+
+// TradeSlimData собственная сделка, исполнение заявки
+type TradeSlimData struct {
+	ID          string    `json:"id"`   // Уникальный идентификатор сделки
+	OrderNumber string    `json:"ono"`  // Номер заявки, по которой прошла сделка
+	Comment     string    `json:"cmt"`  // Пользовательский комментарий к заявке
+	Symbol      string    `json:"sym"`  // Тикер
+	Portfolio   string    `json:"p"`    // Портфель
+	Exchange    Exchange  `json:"ex"`   // Биржа
+	Board       string    `json:"bg"`   // Код режима торгов
+	Side        OrderSide `json:"s"`    // Направление сделки
+	Qty         int64     `json:"q"`    // Количество в лотах
+	QtyUnits    int64     `json:"qu"`   // Количество в штуках
+	Price       float64   `json:"px"`   // Цена сделки
+	Volume      float64   `json:"v"`    // Объём сделки
+	Commission  float64   `json:"fee"`  // Комиссия
+	Date        time.Time `json:"date"` // Время сделки
+	Existing    bool      `json:"h"`    // Данные из истории, а не новое событие
+}
+
+// OrderUpdate изменение состояния заявки, общее для обычных и стоп-заявок
+type OrderUpdate struct {
+	ID           OrderID
+	Stop         bool // Стоп-заявка
+	Symbol       string
+	Portfolio    string
+	Exchange     Exchange
+	Comment      string
+	Type         string
+	Side         OrderSide
+	Status       OrderStatus
+	Qty          int64
+	FilledQty    int64
+	Price        float64
+	TriggerPrice float64 // Только для стоп-заявок
+	UpdateTime   time.Time
+	Existing     bool // Заявка выставлена до подписки, пришла в истории
+}
+
+// Fill исполнение заявки
+type Fill struct {
+	ID         string
+	OrderID    OrderID
+	Symbol     string
+	Portfolio  string
+	Exchange   Exchange
+	Comment    string
+	Side       OrderSide
+	Qty        int64
+	Price      float64
+	Volume     float64
+	Commission float64
+	Date       time.Time
+	Existing   bool // Сделка прошла до подписки, пришла в истории
+}
+
+func (o OrderSlimData) Update() OrderUpdate {
+	return OrderUpdate{
+		ID:         OrderID(o.ID),
+		Symbol:     o.Symbol,
+		Portfolio:  o.Portfolio,
+		Exchange:   o.Exchange,
+		Comment:    o.Comment,
+		Type:       o.Type,
+		Side:       o.Side,
+		Status:     o.Status,
+		Qty:        o.Qty,
+		FilledQty:  o.FilledQty,
+		Price:      o.Price,
+		UpdateTime: o.UpdateTime,
+		Existing:   o.Existing,
+	}
+}
+
+func (o StopOrderSlimData) Update() OrderUpdate {
+	return OrderUpdate{
+		ID:           OrderID(o.ID),
+		Stop:         true,
+		Symbol:       o.Symbol,
+		Portfolio:    o.Portfolio,
+		Exchange:     o.Exchange,
+		Comment:      o.Comment,
+		Type:         o.Type,
+		Side:         o.Side,
+		Status:       o.Status,
+		Qty:          o.Qty,
+		FilledQty:    o.FilledQty,
+		Price:        o.Price,
+		TriggerPrice: o.TriggerPrice,
+		UpdateTime:   o.UpdateTime,
+		Existing:     o.Existing,
+	}
+}
+
+func (t TradeSlimData) Fill() Fill {
+	return Fill{
+		ID:         t.ID,
+		OrderID:    OrderID(t.OrderNumber),
+		Symbol:     t.Symbol,
+		Portfolio:  t.Portfolio,
+		Exchange:   t.Exchange,
+		Comment:    t.Comment,
+		Side:       t.Side,
+		Qty:        t.Qty,
+		Price:      t.Price,
+		Volume:     t.Volume,
+		Commission: t.Commission,
+		Date:       t.Date,
+		Existing:   t.Existing,
+	}
+}
+
+// parseOrderEvent разбирает событие заявки или сделки в OrderUpdate или Fill
+func parseOrderEvent(event *ChainEvent) (any, error) {
+	switch event.Opcode {
+	case OrdersOpcode:
+		var order OrderSlimData
+		if err := json.Unmarshal(event.Data, &order); err != nil {
+			return nil, err
+		}
+
+		return order.Update(), nil
+	case StopOrdersOpcode:
+		var stopOrder StopOrderSlimData
+		if err := json.Unmarshal(event.Data, &stopOrder); err != nil {
+			return nil, err
+		}
+
+		return stopOrder.Update(), nil
+	case TradesOpcode:
+		var trade TradeSlimData
+		if err := json.Unmarshal(event.Data, &trade); err != nil {
+			return nil, err
+		}
+
+		return trade.Fill(), nil
+	}
+
+	return nil, ErrInvalidOpcode
+}
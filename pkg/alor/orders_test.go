@@ -0,0 +1,48 @@
+package alor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderRouterForgetsSettledOrders(t *testing.T) {
+	t.Parallel()
+
+	router := NewOrderRouter()
+	subscriberID := SubscriberID(uuid.New())
+
+	route := func(opcode Opcode, data any) SubscriberID {
+		raw, err := json.Marshal(data)
+		require.NoError(t, err)
+
+		owner, ok, err := router.route(&ChainEvent{Opcode: opcode, Data: raw})
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		return owner
+	}
+
+	router.Track(subscriberID, "1")
+
+	// Статус исполнения пришёл раньше второй сделки
+	require.Equal(t, subscriberID, route(TradesOpcode, TradeSlimData{ID: "t1", OrderNumber: "1", Qty: 2}))
+	require.Equal(t, subscriberID, route(OrdersOpcode, OrderSlimData{ID: "1", Status: FilledOrderStatus, Qty: 5, FilledQty: 5}))
+	require.Len(t, router.owners, 1)
+
+	require.Equal(t, subscriberID, route(TradesOpcode, TradeSlimData{ID: "t2", OrderNumber: "1", Qty: 3}))
+	require.Empty(t, router.owners)
+
+	// Снятая заявка без сделок забывается сразу
+	router.Track(subscriberID, "2")
+	route(OrdersOpcode, OrderSlimData{ID: "2", Status: CanceledOrderStatus, Qty: 5})
+	require.Empty(t, router.owners)
+
+	router.Track(subscriberID, "3")
+	route(StopOrdersOpcode, StopOrderSlimData{ID: "3", Status: WorkingOrderStatus})
+	require.Len(t, router.owners, 1)
+	route(StopOrdersOpcode, StopOrderSlimData{ID: "3", Status: FilledOrderStatus, FilledQty: 1})
+	require.Empty(t, router.owners)
+}
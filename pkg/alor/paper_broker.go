@@ -0,0 +1,535 @@
+package alor
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+type SubscriberMode string
+
+var (
+	LiveSubscriberMode  SubscriberMode = "live"  // Заявки уходят брокеру
+	PaperSubscriberMode SubscriberMode = "paper" // Заявки исполняются виртуально по живым данным
+)
+
+type PaperTradingConfig struct {
+	InitialCash float64 // Стартовый виртуальный капитал
+	Commission  float64 // Комиссия, доля от оборота
+	Slippage    float64 // Дополнительное проскальзывание рыночных заявок сверх прохода по стакану, в пунктах цены
+	LotSize     float64 // Количество единиц инструмента в лоте, по умолчанию 1
+}
+
+type paperOrder struct {
+	simulatedOrder
+	filled     int64 // Исполнено лотов
+	queueAhead int64 // Лотов перед заявкой в очереди на её цене
+}
+
+func (o *paperOrder) remaining() int64 {
+	return o.quantity - o.filled
+}
+
+// PaperBroker реализует CommandBus для бумажной торговли одного подписчика.
+// Рыночные заявки проходят по последнему стакану, лимитные встают в очередь за объёмом на своей цене
+// и исполняются, когда сделки по этой цене съедают очередь или цена проходит через заявку.
+// Стоп заявки срабатывают по ленте сделок.
+type PaperBroker struct {
+	mu      sync.Mutex
+	clock   Clock
+	code    string
+	config  PaperTradingConfig
+	nextID  int64
+	orders  []*paperOrder
+	bids    []OrderBookSlimQuote // Лучшая цена первой
+	asks    []OrderBookSlimQuote // Лучшая цена первой
+	account virtualAccount
+}
+
+func NewPaperBroker(clock Clock, code string, config PaperTradingConfig) *PaperBroker {
+	return &PaperBroker{
+		clock:   clock,
+		code:    code,
+		config:  config,
+		account: newVirtualAccount(config.InitialCash, config.Commission, config.LotSize),
+	}
+}
+
+// CommandBus
+
+func (b *PaperBroker) CreateMarketOrder(params MarketOrderParams) (OrderID, error) {
+	return b.addOrder(params.OrderTarget, simulatedOrder{
+		orderType:   marketSimulatedOrder,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (b *PaperBroker) CreateLimitOrder(params LimitOrderParams) (OrderID, error) {
+	return b.addOrder(params.OrderTarget, simulatedOrder{
+		orderType:   limitSimulatedOrder,
+		price:       params.Price,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (b *PaperBroker) CreateStopOrder(params StopOrderParams) (OrderID, error) {
+	return b.addOrder(params.OrderTarget, simulatedOrder{
+		orderType:    stopSimulatedOrder,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+	})
+}
+
+func (b *PaperBroker) CreateStopLimitOrder(params StopLimitOrderParams) (OrderID, error) {
+	return b.addOrder(params.OrderTarget, simulatedOrder{
+		orderType:    stopLimitSimulatedOrder,
+		price:        params.Price,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+		timeInForce:  params.TimeInForce,
+	})
+}
+
+func (b *PaperBroker) ModifyMarketOrder(orderID OrderID, params MarketOrderParams) (OrderID, error) {
+	return b.replaceOrder(orderID, params.OrderTarget, simulatedOrder{
+		orderType:   marketSimulatedOrder,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (b *PaperBroker) ModifyLimitOrder(orderID OrderID, params LimitOrderParams) (OrderID, error) {
+	return b.replaceOrder(orderID, params.OrderTarget, simulatedOrder{
+		orderType:   limitSimulatedOrder,
+		price:       params.Price,
+		timeInForce: params.TimeInForce,
+	})
+}
+
+func (b *PaperBroker) ModifyStopOrder(orderID OrderID, params StopOrderParams) (OrderID, error) {
+	return b.replaceOrder(orderID, params.OrderTarget, simulatedOrder{
+		orderType:    stopSimulatedOrder,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+	})
+}
+
+func (b *PaperBroker) ModifyStopLimitOrder(orderID OrderID, params StopLimitOrderParams) (OrderID, error) {
+	return b.replaceOrder(orderID, params.OrderTarget, simulatedOrder{
+		orderType:    stopLimitSimulatedOrder,
+		price:        params.Price,
+		triggerPrice: params.TriggerPrice,
+		condition:    params.Condition,
+		timeInForce:  params.TimeInForce,
+	})
+}
+
+func (b *PaperBroker) CancelOrder(params CancelOrderParams) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	index := b.findOrder(params.OrderID)
+	if index < 0 {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, params.OrderID)
+	}
+
+	b.orders = slices.Delete(b.orders, index, index+1)
+
+	return nil
+}
+
+func (b *PaperBroker) addOrder(target OrderTarget, order simulatedOrder) (OrderID, error) {
+	if err := checkVirtualOrderTarget(b.code, target); err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.checkMarketPrice(order); err != nil {
+		return "", err
+	}
+
+	b.nextID++
+	order.id = OrderID(strconv.FormatInt(b.nextID, 10))
+	order.side = target.Side
+	order.quantity = target.Quantity
+	order.comment = target.Comment
+
+	b.place(&paperOrder{simulatedOrder: order})
+
+	return order.id, nil
+}
+
+// replaceOrder изменение заявки сохраняет номер, но место в очереди теряется, как и на бирже
+func (b *PaperBroker) replaceOrder(orderID OrderID, target OrderTarget, order simulatedOrder) (OrderID, error) {
+	if err := checkVirtualOrderTarget(b.code, target); err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	index := b.findOrder(orderID)
+	if index < 0 {
+		return "", fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+
+	if err := b.checkMarketPrice(order); err != nil {
+		return "", err
+	}
+
+	b.orders = slices.Delete(b.orders, index, index+1)
+
+	order.id = orderID
+	order.side = target.Side
+	order.quantity = target.Quantity
+	order.comment = target.Comment
+
+	b.place(&paperOrder{simulatedOrder: order})
+
+	return orderID, nil
+}
+
+func (b *PaperBroker) findOrder(orderID OrderID) int {
+	for index, order := range b.orders {
+		if order.id == orderID {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// checkMarketPrice рыночную заявку без стакана и последней сделки исполнить не по чему, она бы висела вечно
+func (b *PaperBroker) checkMarketPrice(order simulatedOrder) error {
+	if order.orderType != marketSimulatedOrder || len(b.oppositeSide(order.side)) != 0 || b.account.lastPrice > 0 {
+		return nil
+	}
+
+	return ErrNoMarketPrice
+}
+
+// place пытается исполнить новую заявку сразу, остаток ставит в очередь
+func (b *PaperBroker) place(order *paperOrder) {
+	switch order.orderType {
+	case marketSimulatedOrder:
+		b.executeMarket(order)
+	case limitSimulatedOrder:
+		// FOK исполняется только целиком
+		if order.timeInForce == FillOrKillTimeInForce && b.availableVolume(order) < order.remaining() {
+			return
+		}
+
+		b.executeAgainstBook(order, true)
+
+		if order.remaining() == 0 || order.timeInForce == ImmediateOrCancelTimeInForce || order.timeInForce == FillOrKillTimeInForce {
+			return
+		}
+
+		order.queueAhead = levelVolume(b.ownSide(order.side), order.price)
+	}
+
+	if order.remaining() > 0 {
+		b.orders = append(b.orders, order)
+	}
+}
+
+// executeMarket проходит по стакану, без стакана исполняет по последней сделке
+func (b *PaperBroker) executeMarket(order *paperOrder) {
+	if len(b.oppositeSide(order.side)) == 0 {
+		if b.account.lastPrice > 0 {
+			b.fill(order, order.remaining(), b.slipped(order.side, b.account.lastPrice))
+		}
+
+		return
+	}
+
+	b.executeAgainstBook(order, false)
+}
+
+// executeAgainstBook забирает объём встречной стороны стакана, limited - не хуже цены заявки
+func (b *PaperBroker) executeAgainstBook(order *paperOrder, limited bool) {
+	levels := b.oppositeSide(order.side)
+
+	for index := range levels {
+		if order.remaining() == 0 {
+			break
+		}
+
+		level := &levels[index]
+		if level.Volume <= 0 {
+			continue
+		}
+
+		if limited && !priceAcceptable(order.side, level.Price, order.price) {
+			break
+		}
+
+		quantity := min(level.Volume, order.remaining())
+		// Забранный объём убираем, чтобы следующие заявки не исполнились по нему же до нового стакана
+		level.Volume -= quantity
+
+		price := level.Price
+		if !limited {
+			price = b.slipped(order.side, price)
+		}
+
+		b.fill(order, quantity, price)
+	}
+}
+
+// executeResting исполняет стоявшую лимитную заявку по её цене против встречных уровней, дошедших до неё
+func (b *PaperBroker) executeResting(order *paperOrder) {
+	levels := b.oppositeSide(order.side)
+
+	for index := range levels {
+		if order.remaining() == 0 || !priceAcceptable(order.side, levels[index].Price, order.price) {
+			break
+		}
+
+		quantity := min(levels[index].Volume, order.remaining())
+		if quantity <= 0 {
+			continue
+		}
+
+		levels[index].Volume -= quantity
+		b.fill(order, quantity, order.price)
+	}
+}
+
+func (b *PaperBroker) availableVolume(order *paperOrder) int64 {
+	var volume int64
+
+	for _, level := range b.oppositeSide(order.side) {
+		if !priceAcceptable(order.side, level.Price, order.price) {
+			break
+		}
+
+		volume += level.Volume
+	}
+
+	return volume
+}
+
+func (b *PaperBroker) fill(order *paperOrder, quantity int64, price float64) {
+	order.filled += quantity
+	b.account.fill(order.id, order.side, quantity, price, order.comment, b.clock.Now())
+}
+
+func (b *PaperBroker) slipped(side OrderSide, price float64) float64 {
+	if side == BuySide {
+		return price + b.config.Slippage
+	}
+
+	return price - b.config.Slippage
+}
+
+func (b *PaperBroker) oppositeSide(side OrderSide) []OrderBookSlimQuote {
+	if side == BuySide {
+		return b.asks
+	}
+
+	return b.bids
+}
+
+func (b *PaperBroker) ownSide(side OrderSide) []OrderBookSlimQuote {
+	if side == BuySide {
+		return b.bids
+	}
+
+	return b.asks
+}
+
+// priceAcceptable цена встречного уровня не хуже цены заявки
+func priceAcceptable(side OrderSide, levelPrice, orderPrice float64) bool {
+	if side == BuySide {
+		return levelPrice <= orderPrice
+	}
+
+	return levelPrice >= orderPrice
+}
+
+func levelVolume(levels []OrderBookSlimQuote, price float64) int64 {
+	for _, level := range levels {
+		if level.Price == price {
+			return level.Volume
+		}
+	}
+
+	return 0
+}
+
+// Рыночные данные
+
+// OnOrderBook обновляет стакан и исполняет то, что стало возможно
+func (b *PaperBroker) OnOrderBook(data OrderBookSlimData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bids = slices.SortedFunc(slices.Values(data.Bids), func(x, y OrderBookSlimQuote) int {
+		return cmp.Compare(y.Price, x.Price)
+	})
+	b.asks = slices.SortedFunc(slices.Values(data.Asks), func(x, y OrderBookSlimQuote) int {
+		return cmp.Compare(x.Price, y.Price)
+	})
+
+	active := b.orders[:0]
+
+	for _, order := range b.orders {
+		switch order.orderType {
+		case marketSimulatedOrder:
+			b.executeMarket(order)
+		case limitSimulatedOrder:
+			// Встречная сторона дошла до заявки - значит, с ней бы и сторговались, но не больше выставленного объёма
+			if best := b.oppositeSide(order.side); len(best) != 0 && priceAcceptable(order.side, best[0].Price, order.price) {
+				b.executeResting(order)
+				// Всё, что стояло перед нами, встречная сторона уже прошла
+				order.queueAhead = 0
+				break
+			}
+
+			// Объём перед нами может только уменьшиться: снятые заявки, встающие позже - за нами
+			order.queueAhead = min(order.queueAhead, levelVolume(b.ownSide(order.side), order.price))
+		}
+
+		if order.remaining() > 0 {
+			active = append(active, order)
+		}
+	}
+
+	b.orders = active
+}
+
+// OnTrade двигает очередь лимитных заявок и запускает стоп заявки
+func (b *PaperBroker) OnTrade(data AllTradesSlimData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.account.lastPrice = data.Price
+	prices := priceRange{open: data.Price, high: data.Price, low: data.Price, close: data.Price}
+
+	orders := b.orders
+	b.orders = nil
+
+	for _, order := range orders {
+		switch order.orderType {
+		case stopSimulatedOrder, stopLimitSimulatedOrder:
+			if !stopTriggered(&order.simulatedOrder, prices) {
+				b.orders = append(b.orders, order)
+				continue
+			}
+
+			// Сработавший стоп становится рыночной заявкой, стоп-лимит - лимитной
+			if order.orderType == stopSimulatedOrder {
+				order.orderType = marketSimulatedOrder
+			} else {
+				order.orderType = limitSimulatedOrder
+			}
+
+			b.place(order)
+		case limitSimulatedOrder:
+			b.matchTrade(order, data)
+
+			if order.remaining() > 0 {
+				b.orders = append(b.orders, order)
+			}
+		default:
+			b.orders = append(b.orders, order)
+		}
+	}
+}
+
+// matchTrade исполнение стоящей лимитной заявки по сделке из ленты
+func (b *PaperBroker) matchTrade(order *paperOrder, data AllTradesSlimData) {
+	// Цена прошла через заявку: очередь на нашей цене съедена, исполняемся по своей цене
+	// не больше объёма сделки, как при пересечении стакана не больше видимого объёма
+	if priceAcceptable(order.side, data.Price, order.price) && data.Price != order.price {
+		order.queueAhead = 0
+
+		if quantity := min(data.Qty, order.remaining()); quantity > 0 {
+			b.fill(order, quantity, order.price)
+		}
+
+		return
+	}
+
+	// По нашей цене исполняются только сделки встречного агрессора
+	if data.Price != order.price || data.Side == order.side {
+		return
+	}
+
+	volume := data.Qty
+
+	consumed := min(volume, order.queueAhead)
+	order.queueAhead -= consumed
+	volume -= consumed
+
+	if quantity := min(volume, order.remaining()); quantity > 0 {
+		b.fill(order, quantity, order.price)
+	}
+}
+
+// Состояние счёта
+
+type PaperOrderState struct {
+	ID           OrderID   `json:"id"`
+	Side         OrderSide `json:"side"`
+	Quantity     int64     `json:"quantity"`
+	Filled       int64     `json:"filled"`
+	Price        float64   `json:"price"`
+	TriggerPrice float64   `json:"trigger_price"`
+	QueueAhead   int64     `json:"queue_ahead"`
+}
+
+type PaperAccount struct {
+	Cash        float64           `json:"cash"`
+	Equity      float64           `json:"equity"`
+	Position    int64             `json:"position"`
+	AvgPrice    float64           `json:"avg_price"`
+	LastPrice   float64           `json:"last_price"`
+	RealizedPnL float64           `json:"realized_pnl"`
+	Commission  float64           `json:"commission"`
+	Orders      []PaperOrderState `json:"orders"`
+	Fills       []BacktestFill    `json:"fills"`
+	Trades      []BacktestTrade   `json:"trades"`
+}
+
+// Account снимок виртуального счёта
+func (b *PaperBroker) Account() PaperAccount {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	orders := make([]PaperOrderState, 0, len(b.orders))
+	for _, order := range b.orders {
+		orders = append(orders, PaperOrderState{
+			ID:           order.id,
+			Side:         order.side,
+			Quantity:     order.quantity,
+			Filled:       order.filled,
+			Price:        order.price,
+			TriggerPrice: order.triggerPrice,
+			QueueAhead:   order.queueAhead,
+		})
+	}
+
+	return PaperAccount{
+		Cash:        b.account.cash,
+		Equity:      b.account.equity(),
+		Position:    b.account.position,
+		AvgPrice:    b.account.avgPrice,
+		LastPrice:   b.account.lastPrice,
+		RealizedPnL: b.account.realizedPnL,
+		Commission:  b.account.commission,
+		Orders:      orders,
+		Fills:       slices.Clone(b.account.fills),
+		Trades:      slices.Clone(b.account.trades),
+	}
+}
+
+// MarshalJSON отдаёт снимок счёта в карточке подписчика
+func (b *PaperBroker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Account())
+}
@@ -0,0 +1,145 @@
+package alor
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPaperBrokerMarketWalksOrderBook(t *testing.T) {
+	t.Parallel()
+
+	broker := NewPaperBroker(SystemClock{}, "SBER", PaperTradingConfig{InitialCash: 10_000, Slippage: 0.5})
+	broker.OnOrderBook(OrderBookSlimData{
+		Asks: []OrderBookSlimQuote{{Price: 101, Volume: 5}, {Price: 100, Volume: 2}},
+		Bids: []OrderBookSlimQuote{{Price: 99, Volume: 3}},
+	})
+
+	_, err := broker.CreateMarketOrder(MarketOrderParams{OrderTarget: OrderTarget{Code: "SBER", Side: BuySide, Quantity: 4}})
+	require.NoError(t, err)
+
+	account := broker.Account()
+	require.Len(t, account.Fills, 2)
+	require.Equal(t, 100.5, account.Fills[0].Price)
+	require.Equal(t, int64(2), account.Fills[0].Quantity)
+	require.Equal(t, 101.5, account.Fills[1].Price)
+	require.Equal(t, int64(4), account.Position)
+	require.Equal(t, 10_000-2*100.5-2*101.5, account.Cash)
+}
+
+func TestPaperBrokerLimitQueuePosition(t *testing.T) {
+	t.Parallel()
+
+	broker := NewPaperBroker(SystemClock{}, "SBER", PaperTradingConfig{InitialCash: 10_000})
+	broker.OnOrderBook(OrderBookSlimData{
+		Asks: []OrderBookSlimQuote{{Price: 101, Volume: 5}},
+		Bids: []OrderBookSlimQuote{{Price: 100, Volume: 3}},
+	})
+
+	_, err := broker.CreateLimitOrder(LimitOrderParams{OrderTarget: OrderTarget{Code: "SBER", Side: BuySide, Quantity: 2}, Price: 100})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), broker.Account().Orders[0].QueueAhead)
+
+	// Покупки по нашей цене очередь не двигают
+	broker.OnTrade(AllTradesSlimData{Price: 100, Qty: 5, Side: BuySide})
+	require.Empty(t, broker.Account().Fills)
+
+	// Продажа съедает очередь и часть заявки
+	broker.OnTrade(AllTradesSlimData{Price: 100, Qty: 4, Side: SellSide})
+	account := broker.Account()
+	require.Equal(t, int64(1), account.Position)
+	require.Equal(t, int64(1), account.Orders[0].Filled)
+
+	// Цена прошла через заявку - исполняется остаток
+	broker.OnTrade(AllTradesSlimData{Price: 99, Qty: 1, Side: SellSide})
+	account = broker.Account()
+	require.Equal(t, int64(2), account.Position)
+	require.Empty(t, account.Orders)
+}
+
+func TestPaperBrokerStopOrder(t *testing.T) {
+	t.Parallel()
+
+	broker := NewPaperBroker(SystemClock{}, "SBER", PaperTradingConfig{InitialCash: 10_000})
+	broker.OnTrade(AllTradesSlimData{Price: 100, Qty: 1})
+
+	_, err := broker.CreateStopOrder(StopOrderParams{
+		OrderTarget:  OrderTarget{Code: "SBER", Side: BuySide, Quantity: 1},
+		Condition:    MoreOrEqualStopCondition,
+		TriggerPrice: 102,
+	})
+	require.NoError(t, err)
+
+	broker.OnTrade(AllTradesSlimData{Price: 101, Qty: 1})
+	require.Zero(t, broker.Account().Position)
+
+	broker.OnTrade(AllTradesSlimData{Price: 102, Qty: 1})
+	account := broker.Account()
+	require.Equal(t, int64(1), account.Position)
+	require.Equal(t, 102.0, account.AvgPrice)
+}
+
+func TestPaperBrokerCrossedLimitFillsDisplayedVolume(t *testing.T) {
+	t.Parallel()
+
+	broker := NewPaperBroker(SystemClock{}, "SBER", PaperTradingConfig{InitialCash: 10_000})
+	broker.OnOrderBook(OrderBookSlimData{
+		Asks: []OrderBookSlimQuote{{Price: 102, Volume: 5}},
+		Bids: []OrderBookSlimQuote{{Price: 100, Volume: 3}},
+	})
+
+	_, err := broker.CreateLimitOrder(LimitOrderParams{OrderTarget: OrderTarget{Code: "SBER", Side: BuySide, Quantity: 5}, Price: 101})
+	require.NoError(t, err)
+
+	// Продавцы дошли до заявки, но выставили только 2 лота
+	broker.OnOrderBook(OrderBookSlimData{
+		Asks: []OrderBookSlimQuote{{Price: 101, Volume: 2}, {Price: 103, Volume: 10}},
+		Bids: []OrderBookSlimQuote{{Price: 100, Volume: 3}},
+	})
+
+	account := broker.Account()
+	require.Equal(t, int64(2), account.Position)
+	require.Equal(t, 101.0, account.Fills[0].Price)
+	require.Equal(t, int64(3), account.Orders[0].Quantity-account.Orders[0].Filled)
+	require.Zero(t, account.Orders[0].QueueAhead)
+}
+
+func TestPaperBrokerThroughTradeFillsTradeVolume(t *testing.T) {
+	t.Parallel()
+
+	broker := NewPaperBroker(SystemClock{}, "SBER", PaperTradingConfig{InitialCash: 1_000_000})
+	broker.OnOrderBook(OrderBookSlimData{
+		Asks: []OrderBookSlimQuote{{Price: 101, Volume: 5}},
+		Bids: []OrderBookSlimQuote{{Price: 100, Volume: 30}},
+	})
+
+	_, err := broker.CreateLimitOrder(LimitOrderParams{OrderTarget: OrderTarget{Code: "SBER", Side: BuySide, Quantity: 1000}, Price: 100})
+	require.NoError(t, err)
+
+	// Лот прошёл ниже заявки: исполняется лот, а не вся заявка
+	broker.OnTrade(AllTradesSlimData{Price: 99, Qty: 1, Side: SellSide})
+	account := broker.Account()
+	require.Equal(t, int64(1), account.Position)
+	require.Equal(t, 100.0, account.Fills[0].Price)
+	require.Equal(t, int64(999), account.Orders[0].Quantity-account.Orders[0].Filled)
+
+	// Очередь на нашей цене уже съедена
+	require.Zero(t, account.Orders[0].QueueAhead)
+	broker.OnTrade(AllTradesSlimData{Price: 100, Qty: 4, Side: SellSide})
+	require.Equal(t, int64(5), broker.Account().Position)
+}
+
+func TestPaperBrokerMarketWithoutPrice(t *testing.T) {
+	t.Parallel()
+
+	broker := NewPaperBroker(SystemClock{}, "SBER", PaperTradingConfig{InitialCash: 10_000})
+
+	_, err := broker.CreateMarketOrder(MarketOrderParams{OrderTarget: OrderTarget{Code: "SBER", Side: BuySide, Quantity: 1}})
+	require.ErrorIs(t, err, ErrNoMarketPrice)
+	require.Empty(t, broker.Account().Orders)
+
+	broker.OnTrade(AllTradesSlimData{Price: 100, Qty: 1})
+
+	_, err = broker.CreateMarketOrder(MarketOrderParams{OrderTarget: OrderTarget{Code: "SBER", Side: BuySide, Quantity: 1}})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), broker.Account().Position)
+}
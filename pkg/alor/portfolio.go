@@ -0,0 +1,331 @@
+package alor
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// PortfolioOpcodes потоки портфеля по умолчанию, SpectralRisksOpcode нужен только для портфелей срочного рынка
+var PortfolioOpcodes = []Opcode{PositionsOpcode, SummariesOpcode, RisksOpcode}
+
+// clientOwner владелец подписок портфеля и справочника в Subscriptions. Такие подписки принадлежат клиенту, а не подписчику.
+var clientOwner = SubscriberID(uuid.Nil)
+
+// isPortfolioOpcode поток подписывается на портфель, а не на инструмент
+func isPortfolioOpcode(opcode Opcode) bool {
+	switch opcode {
+	case PositionsOpcode, SummariesOpcode, RisksOpcode, SpectralRisksOpcode:
+		return true
+	}
+
+	return isOrdersOpcode(opcode)
+}
+
+// PortfolioState состояние портфеля по данным из вебсокета. Пишет разбор очереди, читают стратегии и http.
+type PortfolioState struct {
+	exchange     Exchange
+	portfolio    string
+	positions    map[string]PositionSlimData
+	summary      *SummarySlimData
+	risks        *RisksSlimData
+	spectraRisks *SpectraRisksSlimData
+	mu           sync.RWMutex
+}
+
+// PortfolioSnapshot копия состояния портфеля на момент запроса
+type PortfolioSnapshot struct {
+	Exchange     Exchange              `json:"exchange"`
+	Portfolio    string                `json:"portfolio"`
+	Positions    []PositionSlimData    `json:"positions"`
+	Summary      *SummarySlimData      `json:"summary,omitempty"`
+	Risks        *RisksSlimData        `json:"risks,omitempty"`
+	SpectraRisks *SpectraRisksSlimData `json:"spectra_risks,omitempty"`
+}
+
+func NewPortfolioState(exchange Exchange, portfolio string) *PortfolioState {
+	return &PortfolioState{
+		exchange:  exchange,
+		portfolio: portfolio,
+		positions: make(map[string]PositionSlimData),
+	}
+}
+
+func (p *PortfolioState) HandleEvent(event *ChainEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch event.Opcode {
+	case PositionsOpcode:
+		var position PositionSlimData
+		if err := json.Unmarshal(event.Data, &position); err != nil {
+			return err
+		}
+
+		p.positions[position.Symbol] = position
+	case SummariesOpcode:
+		var summary SummarySlimData
+		if err := json.Unmarshal(event.Data, &summary); err != nil {
+			return err
+		}
+
+		p.summary = &summary
+	case RisksOpcode:
+		var risks RisksSlimData
+		if err := json.Unmarshal(event.Data, &risks); err != nil {
+			return err
+		}
+
+		p.risks = &risks
+	case SpectralRisksOpcode:
+		var spectraRisks SpectraRisksSlimData
+		if err := json.Unmarshal(event.Data, &spectraRisks); err != nil {
+			return err
+		}
+
+		p.spectraRisks = &spectraRisks
+	}
+
+	return nil
+}
+
+// GetPosition позиция по тикеру, false - если позиции не было
+func (p *PortfolioState) GetPosition(symbol string) (PositionSlimData, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	position, ok := p.positions[symbol]
+
+	return position, ok
+}
+
+// GetBuyingPower свободные средства из сводки портфеля
+func (p *PortfolioState) GetBuyingPower() (float64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.summary == nil {
+		return 0, fmt.Errorf("%w: summary", ErrNoPortfolioData)
+	}
+
+	return p.summary.BuyingPower, nil
+}
+
+// GetInitialMargin начальная маржа из рисков портфеля
+func (p *PortfolioState) GetInitialMargin() (float64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.risks == nil {
+		return 0, fmt.Errorf("%w: risks", ErrNoPortfolioData)
+	}
+
+	return p.risks.InitialMargin, nil
+}
+
+// GetSpectraRisks риски срочного рынка
+func (p *PortfolioState) GetSpectraRisks() (SpectraRisksSlimData, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.spectraRisks == nil {
+		return SpectraRisksSlimData{}, fmt.Errorf("%w: spectra risks", ErrNoPortfolioData)
+	}
+
+	return *p.spectraRisks, nil
+}
+
+func (p *PortfolioState) Snapshot() PortfolioSnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	snapshot := PortfolioSnapshot{
+		Exchange:  p.exchange,
+		Portfolio: p.portfolio,
+		Positions: make([]PositionSlimData, 0, len(p.positions)),
+	}
+
+	for _, symbol := range slices.Sorted(maps.Keys(p.positions)) {
+		snapshot.Positions = append(snapshot.Positions, p.positions[symbol])
+	}
+
+	if p.summary != nil {
+		summary := *p.summary
+		snapshot.Summary = &summary
+	}
+
+	if p.risks != nil {
+		risks := *p.risks
+		snapshot.Risks = &risks
+	}
+
+	if p.spectraRisks != nil {
+		spectraRisks := *p.spectraRisks
+		snapshot.SpectraRisks = &spectraRisks
+	}
+
+	return snapshot
+}
+
+func (p *PortfolioState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Snapshot())
+}
+
+func NewPortfolios() *Portfolios {
+	return &Portfolios{
+		list:  make(map[string]*PortfolioState),
+		guids: make(map[GUID]*PortfolioState),
+	}
+}
+
+// Portfolios портфели, на которые подписан клиент
+type Portfolios struct {
+	list  map[string]*PortfolioState // Ключ - биржа и портфель
+	guids map[GUID]*PortfolioState   // По GUID подписки разбор очереди находит портфель
+	mu    sync.RWMutex
+}
+
+func portfolioKey(exchange Exchange, portfolio string) string {
+	return fmt.Sprintf("%s-%s", exchange, portfolio)
+}
+
+func portfolioGUID(opcode Opcode, exchange Exchange, portfolio string) GUID {
+	return GUID(fmt.Sprintf("%s-%s-%s-%s", opcode, exchange, portfolio, SlimResponseFormat))
+}
+
+func (p *Portfolios) Get(exchange Exchange, portfolio string) (*PortfolioState, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	state, ok := p.list[portfolioKey(exchange, portfolio)]
+	if !ok {
+		return nil, ErrPortfolioNotFound
+	}
+
+	return state, nil
+}
+
+func (p *Portfolios) GetByGUID(guid GUID) (*PortfolioState, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	state, ok := p.guids[guid]
+
+	return state, ok
+}
+
+// getOrCreate возвращает состояние портфеля, создавая его при первом обращении
+func (p *Portfolios) getOrCreate(exchange Exchange, portfolio string) *PortfolioState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := portfolioKey(exchange, portfolio)
+	if state, ok := p.list[key]; ok {
+		return state
+	}
+
+	state := NewPortfolioState(exchange, portfolio)
+	p.list[key] = state
+
+	return state
+}
+
+func (p *Portfolios) addGUID(guid GUID, state *PortfolioState) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.guids[guid]; ok {
+		return false
+	}
+
+	p.guids[guid] = state
+
+	return true
+}
+
+func (p *Portfolios) removeGUID(guid GUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.guids, guid)
+}
+
+func (p *Portfolios) delete(exchange Exchange, portfolio string) []GUID {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.list[portfolioKey(exchange, portfolio)]
+	if !ok {
+		return nil
+	}
+
+	delete(p.list, portfolioKey(exchange, portfolio))
+
+	guids := make([]GUID, 0)
+	for guid, item := range p.guids {
+		if item == state {
+			guids = append(guids, guid)
+			delete(p.guids, guid)
+		}
+	}
+
+	return guids
+}
+
+// SubscribePortfolio подписывает клиента на потоки портфеля, по умолчанию PortfolioOpcodes.
+// Повторный вызов досылает только новые потоки и возвращает то же состояние.
+func (c *Client) SubscribePortfolio(exchange Exchange, portfolio string, opcodes ...Opcode) (*PortfolioState, error) {
+	if len(opcodes) == 0 {
+		opcodes = PortfolioOpcodes
+	}
+
+	state := c.Pool.portfolios.getOrCreate(exchange, portfolio)
+
+	for _, opcode := range opcodes {
+		if !isPortfolioOpcode(opcode) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidOpcode, opcode)
+		}
+
+		subscription := &Subscription{
+			GUID:      portfolioGUID(opcode, exchange, portfolio),
+			Opcode:    opcode,
+			Exchange:  exchange,
+			Portfolio: portfolio,
+		}
+
+		if !c.Pool.portfolios.addGUID(subscription.GUID, state) {
+			continue
+		}
+
+		if err := c.Pool.subscribeClient(c.Token, subscription); err != nil {
+			c.Pool.portfolios.removeGUID(subscription.GUID)
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// UnsubscribePortfolio отписывает клиента от всех потоков портфеля и забывает его состояние
+func (c *Client) UnsubscribePortfolio(exchange Exchange, portfolio string) error {
+	token, err := c.Token.GetAccessToken()
+	if err != nil {
+		return err
+	}
+
+	for _, guid := range c.Pool.portfolios.delete(exchange, portfolio) {
+		if err := c.Pool.unsubscribeClient(token, guid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) GetPortfolio(exchange Exchange, portfolio string) (*PortfolioState, error) {
+	return c.Pool.portfolios.Get(exchange, portfolio)
+}
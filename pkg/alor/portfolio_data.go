@@ -0,0 +1,109 @@
+package alor
+
+import (
+	"encoding/json"
+)
+
+// PortfolioRequest подписка на данные портфеля: позиции, сводку, риски.
+// В отличие от рыночных данных ключ подписки - портфель, а не инструмент.
+type PortfolioRequest struct {
+	Opcode      Opcode         `json:"opcode"`                // Код выполняемой операции
+	Portfolio   string         `json:"portfolio"`             // Идентификатор клиентского портфеля
+	Exchange    Exchange       `json:"exchange"`              // Биржа
+	SkipHistory bool           `json:"skipHistory,omitempty"` // Флаг отсеивания исторических данных, только для позиций
+	Format      ResponseFormat `json:"format"`                // Формат представления возвращаемых данных
+	Guid        GUID           `json:"guid"`                  // Не более 50 символов. Уникальный идентификатор сообщений создаваемой подписки
+	Token       string         `json:"token"`                 // Access Токен для авторизации запроса
+}
+
+func (ws *Websocket) preparePortfolioRequest(token *Token, subscription *Subscription) ([]byte, error) {
+	accessToken, err := token.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	request := PortfolioRequest{
+		Opcode:    subscription.Opcode,
+		Portfolio: subscription.Portfolio,
+		Exchange:  subscription.Exchange,
+		Format:    SlimResponseFormat,
+		Guid:      subscription.GUID,
+		Token:     accessToken,
+	}
+
+	return json.Marshal(request)
+}
+
+// PositionSlimData позиция по инструменту или деньгам
+type PositionSlimData struct {
+	Volume            float64 `json:"v"`     // Объём, рассчитанный по средней цене
+	CurrentVolume     float64 `json:"cv"`    // Объём, рассчитанный по текущей цене
+	Portfolio         string  `json:"p"`     // Портфель
+	Symbol            string  `json:"sym"`   // Тикер
+	BrokerSymbol      string  `json:"tic"`   // Тикер с биржей, например MOEX:SBER
+	Exchange          string  `json:"ex"`    // Биржа
+	AvgPrice          float64 `json:"pxavg"` // Средняя цена
+	Qty               int64   `json:"q"`     // Количество в лотах
+	OpenQty           int64   `json:"o"`     // Количество на начало сессии в лотах
+	LotSize           float64 `json:"lot"`   // Размер лота
+	ShortName         string  `json:"n"`     // Короткое имя инструмента
+	QtyT0             float64 `json:"q0"`    // Количество T0 в штуках
+	QtyT1             float64 `json:"q1"`    // Количество T1 в штуках
+	QtyT2             float64 `json:"q2"`    // Количество T2 в штуках
+	QtyTFuture        float64 `json:"qf"`    // Количество с учётом всех заявок в штуках
+	DailyUnrealisedPl float64 `json:"upd"`   // Нереализованная прибыль за день
+	UnrealisedPl      float64 `json:"up"`    // Нереализованная прибыль
+	IsCurrency        bool    `json:"cur"`   // Денежная позиция
+	Existing          bool    `json:"h"`     // Данные из истории, а не новое событие
+}
+
+// SummarySlimData сводная информация по портфелю
+type SummarySlimData struct {
+	BuyingPowerAtMorning           float64 `json:"bpm"`   // Покупательская способность на утро
+	BuyingPower                    float64 `json:"bp"`    // Свободные средства
+	Profit                         float64 `json:"pr"`    // Прибыль за сегодня
+	ProfitRate                     float64 `json:"prr"`   // Норма прибыли, %
+	PortfolioEvaluation            float64 `json:"pe"`    // Ликвидный портфель
+	PortfolioLiquidationValue      float64 `json:"plv"`   // Оценка портфеля
+	InitialMargin                  float64 `json:"im"`    // Маржа
+	RiskBeforeForcePositionClosing float64 `json:"rbfpc"` // Риск до закрытия
+	Commission                     float64 `json:"cms"`   // Суммарная комиссия
+}
+
+// RisksSlimData риски портфеля фондового и валютного рынков
+type RisksSlimData struct {
+	Portfolio                 string  `json:"p"`    // Портфель
+	Exchange                  string  `json:"ex"`   // Биржа
+	PortfolioEvaluation       float64 `json:"pe"`   // Ликвидный портфель
+	PortfolioLiquidationValue float64 `json:"plv"`  // Оценка портфеля
+	InitialMargin             float64 `json:"im"`   // Начальная маржа
+	MinimalMargin             float64 `json:"mm"`   // Минимальная маржа
+	CorrectedMargin           float64 `json:"cm"`   // Скорректированная маржа
+	RiskCoverageRatioOne      float64 `json:"rcr1"` // НПР1
+	RiskCoverageRatioTwo      float64 `json:"rcr2"` // НПР2
+	RiskCategoryID            int64   `json:"rcid"` // Категория риска
+	ClientType                string  `json:"clt"`  // Тип клиента
+	HasForbiddenPositions     bool    `json:"hfp"`  // Есть запрещённые позиции
+	HasNegativeQuantity       bool    `json:"hnq"`  // Есть отрицательные позиции
+	RiskStatus                string  `json:"rs"`   // Статус риска: Ok, Demand, Closing
+	CalculationTime           string  `json:"ct"`   // Время расчёта
+}
+
+// SpectraRisksSlimData риски срочного рынка (FORTS)
+type SpectraRisksSlimData struct {
+	Portfolio           string  `json:"p"`    // Портфель
+	Exchange            string  `json:"ex"`   // Биржа
+	MoneyFree           float64 `json:"mf"`   // Свободные средства
+	MoneyBlocked        float64 `json:"mb"`   // Заблокированное гарантийное обеспечение
+	Fee                 float64 `json:"fee"`  // Списанный сбор
+	MoneyOld            float64 `json:"mo"`   // Средства на начало сессии
+	MoneyAmount         float64 `json:"ma"`   // Общее количество рублей и залогов
+	MoneyPledgeAmount   float64 `json:"mpa"`  // Сумма залогов
+	VmInterCl           float64 `json:"vmic"` // Вариационная маржа, списанная в промклиринг
+	VmCurrentPositions  float64 `json:"vmcp"` // Вариационная маржа по текущим позициям
+	VarMargin           float64 `json:"vm"`   // Общая вариационная маржа
+	IsLimitsSet         bool    `json:"ils"`  // Лимиты установлены
+	IndicativeVarMargin float64 `json:"ivm"`  // Индикативная вариационная маржа
+	NetOptionValue      float64 `json:"nov"`  // Стоимость опционов
+	PosRisk             float64 `json:"pr"`   // Риск по позициям
+}
@@ -0,0 +1,227 @@
+package alor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"time"
+)
+
+const ordersActionsPath = "/commandapi/warptrans/TRADE/v2/client/orders/actions"
+
+type OrderInstrument struct {
+	Symbol          string   `json:"symbol"`          // Тикер (Код финансового инструмента)
+	Exchange        Exchange `json:"exchange"`        // Биржа
+	InstrumentGroup string   `json:"instrumentGroup"` // Код режима торгов (Борд)
+}
+
+type OrderUser struct {
+	Portfolio string `json:"portfolio"` // Идентификатор клиентского портфеля
+}
+
+type MarketOrderRequest struct {
+	Side        OrderSide       `json:"side"`                  // Направление сделки
+	Quantity    int64           `json:"quantity"`              // Количество (лоты)
+	Instrument  OrderInstrument `json:"instrument"`            // Информация по инструменту
+	Comment     string          `json:"comment,omitempty"`     // Пользовательский комментарий к заявке
+	User        OrderUser       `json:"user"`                  // Данные о пользователе
+	TimeInForce TimeInForce     `json:"timeInForce,omitempty"` // Условие по времени действия заявки
+	AllowMargin bool            `json:"allowMargin"`           // Разрешить маржинальную торговлю
+}
+
+type LimitOrderRequest struct {
+	Side            OrderSide       `json:"side"`                      // Направление сделки
+	Quantity        int64           `json:"quantity"`                  // Количество (лоты)
+	Price           float64         `json:"price"`                     // Цена
+	Instrument      OrderInstrument `json:"instrument"`                // Информация по инструменту
+	Comment         string          `json:"comment,omitempty"`         // Пользовательский комментарий к заявке
+	User            OrderUser       `json:"user"`                      // Данные о пользователе
+	TimeInForce     TimeInForce     `json:"timeInForce,omitempty"`     // Условие по времени действия заявки
+	IcebergFixed    int64           `json:"icebergFixed,omitempty"`    // Видимая постоянная часть айсберг-заявки в лотах
+	IcebergVariance float64         `json:"icebergVariance,omitempty"` // Амплитуда отклонения случайной надбавки к видимой части айсберг-заявки
+	AllowMargin     bool            `json:"allowMargin"`               // Разрешить маржинальную торговлю
+}
+
+type StopOrderRequest struct {
+	Side              OrderSide       `json:"side"`                        // Направление сделки
+	Condition         StopCondition   `json:"condition"`                   // Условие срабатывания стоп-заявки
+	TriggerPrice      float64         `json:"triggerPrice"`                // Стоп-цена
+	StopEndUnixTime   int64           `json:"stopEndUnixTime,omitempty"`   // Срок действия (UTC) в формате Unix Time seconds
+	Quantity          int64           `json:"quantity"`                    // Количество (лоты)
+	Instrument        OrderInstrument `json:"instrument"`                  // Информация по инструменту
+	Comment           string          `json:"comment,omitempty"`           // Пользовательский комментарий к заявке
+	User              OrderUser       `json:"user"`                        // Данные о пользователе
+	ProtectingSeconds int64           `json:"protectingSeconds,omitempty"` // Защитное время
+	Activate          bool            `json:"activate"`                    // Флаг указывает, создать активную заявку, или не активную
+	AllowMargin       bool            `json:"allowMargin"`                 // Разрешить маржинальную торговлю
+}
+
+type StopLimitOrderRequest struct {
+	StopOrderRequest
+	Price           float64     `json:"price"`                     // Цена выставления лимитной заявки
+	TimeInForce     TimeInForce `json:"timeInForce,omitempty"`     // Условие по времени действия заявки
+	IcebergFixed    int64       `json:"icebergFixed,omitempty"`    // Видимая постоянная часть айсберг-заявки в лотах
+	IcebergVariance float64     `json:"icebergVariance,omitempty"` // Амплитуда отклонения случайной надбавки к видимой части айсберг-заявки
+}
+
+// {
+// "message": "success",
+// "orderNumber": "18995978560"
+// }
+// {
+// "code": "OrderToWrongBoard",
+// "message": "Instrument SBER not found on board TQBR"
+// }
+
+type OrderResponse struct {
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	OrderNumber string `json:"orderNumber"`
+}
+
+func (c *Client) CreateMarketOrder(params MarketOrderParams) (OrderID, error) {
+	return c.sendOrderCommand(http.MethodPost, ordersActionsPath+"/market", params.Portfolio, newMarketOrderRequest(params))
+}
+
+func (c *Client) CreateLimitOrder(params LimitOrderParams) (OrderID, error) {
+	return c.sendOrderCommand(http.MethodPost, ordersActionsPath+"/limit", params.Portfolio, newLimitOrderRequest(params))
+}
+
+func (c *Client) CreateStopOrder(params StopOrderParams) (OrderID, error) {
+	return c.sendOrderCommand(http.MethodPost, ordersActionsPath+"/stop", params.Portfolio, newStopOrderRequest(params))
+}
+
+func (c *Client) CreateStopLimitOrder(params StopLimitOrderParams) (OrderID, error) {
+	return c.sendOrderCommand(http.MethodPost, ordersActionsPath+"/stopLimit", params.Portfolio, newStopLimitOrderRequest(params))
+}
+
+func newOrderInstrument(target OrderTarget) OrderInstrument {
+	return OrderInstrument{
+		Symbol:          target.Code,
+		Exchange:        target.Exchange,
+		InstrumentGroup: target.Board,
+	}
+}
+
+func newMarketOrderRequest(params MarketOrderParams) MarketOrderRequest {
+	return MarketOrderRequest{
+		Side:        params.Side,
+		Quantity:    params.Quantity,
+		Instrument:  newOrderInstrument(params.OrderTarget),
+		Comment:     params.Comment,
+		User:        OrderUser{Portfolio: params.Portfolio},
+		TimeInForce: params.TimeInForce,
+		AllowMargin: params.AllowMargin,
+	}
+}
+
+func newLimitOrderRequest(params LimitOrderParams) LimitOrderRequest {
+	return LimitOrderRequest{
+		Side:            params.Side,
+		Quantity:        params.Quantity,
+		Price:           params.Price,
+		Instrument:      newOrderInstrument(params.OrderTarget),
+		Comment:         params.Comment,
+		User:            OrderUser{Portfolio: params.Portfolio},
+		TimeInForce:     params.TimeInForce,
+		IcebergFixed:    params.IcebergFixed,
+		IcebergVariance: params.IcebergVariance,
+		AllowMargin:     params.AllowMargin,
+	}
+}
+
+func newStopOrderRequest(params StopOrderParams) StopOrderRequest {
+	return StopOrderRequest{
+		Side:              params.Side,
+		Condition:         params.Condition,
+		TriggerPrice:      params.TriggerPrice,
+		StopEndUnixTime:   params.StopEndUnixTime,
+		Quantity:          params.Quantity,
+		Instrument:        newOrderInstrument(params.OrderTarget),
+		Comment:           params.Comment,
+		User:              OrderUser{Portfolio: params.Portfolio},
+		ProtectingSeconds: params.ProtectingSeconds,
+		Activate:          params.Activate,
+		AllowMargin:       params.AllowMargin,
+	}
+}
+
+func newStopLimitOrderRequest(params StopLimitOrderParams) StopLimitOrderRequest {
+	return StopLimitOrderRequest{
+		StopOrderRequest: newStopOrderRequest(params.StopOrderParams),
+		Price:            params.Price,
+		TimeInForce:      params.TimeInForce,
+		IcebergFixed:     params.IcebergFixed,
+		IcebergVariance:  params.IcebergVariance,
+	}
+}
+
+// sendOrderCommand отправляет команду в Commands API и возвращает номер заявки
+func (c *Client) sendOrderCommand(method string, path string, portfolio string, payload any) (OrderID, error) {
+	var body io.Reader
+
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return "", err
+		}
+
+		body = bytes.NewReader(payloadBytes)
+	}
+
+	ctx, cncl := context.WithTimeout(context.Background(), time.Second*10)
+	defer cncl()
+
+	req, err := http.NewRequestWithContext(ctx, method, c.Hosts.Data+path, body)
+	if err != nil {
+		return "", err
+	}
+
+	accessToken, err := c.Token.GetAccessToken()
+	if err != nil {
+		return "", err
+	}
+
+	// X-REQID должен быть уникальным для каждой команды, иначе брокер вернёт результат предыдущей
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	req.Header.Add("X-REQID", fmt.Sprintf("%s;%s", portfolio, uuid.New()))
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var response OrderResponse
+	// Тело ответа может быть не JSON (например, при 401), поэтому ошибку разбора учитываем только при успехе
+	unmarshalErr := json.Unmarshal(resBody, &response)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		message := response.Message
+		if unmarshalErr != nil || message == "" {
+			message = string(resBody)
+		}
+
+		return "", fmt.Errorf("%w: %d %s", ErrCommandFailed, res.StatusCode, message)
+	}
+
+	if unmarshalErr != nil {
+		return "", unmarshalErr
+	}
+
+	return OrderID(response.OrderNumber), nil
+}
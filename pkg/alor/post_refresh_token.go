@@ -1,6 +1,7 @@
 package alor
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,20 +11,25 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type RefreshResponse struct {
 	AccessToken string `json:"AccessToken"`
 }
 
+// RefreshToken получает новый access токен по refresh токену
 func (c *Client) RefreshToken() error {
-	c.Client.Timeout = 2 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTokenTimeout)
+	defer cancel()
 
-	url := fmt.Sprintf("%s/refresh?token=%s", c.Hosts.Authorization, c.Config.RefreshToken)
+	return c.refreshToken(ctx)
+}
+
+func (c *Client) refreshToken(ctx context.Context) error {
+	url := fmt.Sprintf("%s/refresh?token=%s", c.Hosts.Authorization, c.Token.GetRefreshToken())
 
-	// Now that you have a form, you can submit it to your handler.
-	req, err := http.NewRequest("POST", url, nil)
+	// Таймаут задаём контекстом, общий http.Client не трогаем
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return err
 	}
@@ -39,7 +45,11 @@ func (c *Client) RefreshToken() error {
 	}()
 
 	if res.StatusCode == http.StatusForbidden {
-		return errors.New("broker answered forbidden")
+		return ErrRefreshTokenRejected
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: refresh token status %d", ErrCommandFailed, res.StatusCode)
 	}
 
 	body, _ := io.ReadAll(res.Body)
@@ -51,14 +61,12 @@ func (c *Client) RefreshToken() error {
 		return err
 	}
 
-	c.Token.SetAccessToken(r.AccessToken)
-
-	tokenData, err := c.ParseTokenData()
+	tokenData, err := parseTokenData(r.AccessToken)
 	if err != nil {
 		return err
 	}
 
-	c.Token.SetData(tokenData)
+	c.Token.SetAccessToken(r.AccessToken, tokenData)
 
 	return nil
 }
@@ -88,13 +96,21 @@ func extractClaims(tokenStr string) (jwt.MapClaims, bool) {
 }
 
 func (c *Client) ParseTokenData() (TokenData, error) {
+	c.Token.mu.RLock()
+	access := c.Token.Access
+	c.Token.mu.RUnlock()
+
+	return parseTokenData(access)
+}
+
+func parseTokenData(access string) (TokenData, error) {
 	var (
 		data   TokenData
 		claims jwt.MapClaims
 	)
 
 	// Headers map[alg:ES256 typ:JWT] without kid
-	_, _, err := jwt.NewParser().ParseUnverified(c.Token.Access, &claims)
+	_, _, err := jwt.NewParser().ParseUnverified(access, &claims)
 	// _, err := jwt.ParseWithClaims(a.Token.Acccess, claims, nil, jwt.WithoutClaimsValidation())
 	if err != nil {
 
@@ -109,10 +125,6 @@ func (c *Client) ParseTokenData() (TokenData, error) {
 
 	// claims, ok := extractClaims(a.Token.Access)
 
-	for key, val := range claims {
-		fmt.Printf("Key: %v, value: %v\n", key, val)
-	}
-
 	ent, ok := claims["ent"].(string)
 	if !ok {
 		return data, errors.New("token payload: ent undefined")
@@ -176,15 +188,15 @@ func (c *Client) ParseTokenData() (TokenData, error) {
 	return TokenData{
 		Ent:        ent,
 		ClientId:   *clientId,
-		Portfolios: strings.Split(" ", portfolios),
+		Portfolios: strings.Fields(portfolios),
 		Exp:        exp.Time,
 		Iat:        iat.Time,
-		Aud:        strings.Split(" ", aud),
+		Aud:        strings.Fields(aud),
 		Sub:        sub,
 		Ein:        *ein,
 		Azp:        azp,
 		Agreements: *agreements,
-		Scope:      strings.Split(" ", scope),
+		Scope:      strings.Fields(scope),
 		Iss:        iss,
 	}, nil
 }
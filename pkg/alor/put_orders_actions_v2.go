@@ -0,0 +1,32 @@
+package alor
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Изменение заявки - это снятие старой и выставление новой, поэтому брокер возвращает новый номер заявки
+
+func (c *Client) ModifyMarketOrder(orderID OrderID, params MarketOrderParams) (OrderID, error) {
+	path := fmt.Sprintf("%s/market/%s", ordersActionsPath, orderID)
+
+	return c.sendOrderCommand(http.MethodPut, path, params.Portfolio, newMarketOrderRequest(params))
+}
+
+func (c *Client) ModifyLimitOrder(orderID OrderID, params LimitOrderParams) (OrderID, error) {
+	path := fmt.Sprintf("%s/limit/%s", ordersActionsPath, orderID)
+
+	return c.sendOrderCommand(http.MethodPut, path, params.Portfolio, newLimitOrderRequest(params))
+}
+
+func (c *Client) ModifyStopOrder(orderID OrderID, params StopOrderParams) (OrderID, error) {
+	path := fmt.Sprintf("%s/stop/%s", ordersActionsPath, orderID)
+
+	return c.sendOrderCommand(http.MethodPut, path, params.Portfolio, newStopOrderRequest(params))
+}
+
+func (c *Client) ModifyStopLimitOrder(orderID OrderID, params StopLimitOrderParams) (OrderID, error) {
+	path := fmt.Sprintf("%s/stopLimit/%s", ordersActionsPath, orderID)
+
+	return c.sendOrderCommand(http.MethodPut, path, params.Portfolio, newStopLimitOrderRequest(params))
+}
@@ -0,0 +1,271 @@
+package alor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func NewSecurities() *Securities {
+	return &Securities{
+		list:   make(map[string]Security),
+		loaded: make(map[Exchange]bool),
+		guids:  make(map[GUID]string),
+	}
+}
+
+// Securities справочник инструментов: загружается по REST, обновляется подпиской InstrumentsOpcode
+type Securities struct {
+	list   map[string]Security // Ключ - биржа, тикер и режим торгов
+	loaded map[Exchange]bool   // Биржи, загруженные целиком
+	guids  map[GUID]string     // По GUID подписки разбор очереди находит инструмент
+	mu     sync.RWMutex
+}
+
+func securityKey(exchange Exchange, code string, board string) string {
+	return fmt.Sprintf("%s-%s-%s", exchange, code, board)
+}
+
+func securityGUID(exchange Exchange, code string, board string) GUID {
+	return GUID(fmt.Sprintf("%s-%s-%s-%s-%s", InstrumentsOpcode, exchange, code, board, SimpleResponseFormat))
+}
+
+// Set добавляет или заменяет инструмент
+func (s *Securities) Set(security Security) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(security)
+}
+
+func (s *Securities) set(security Security) {
+	s.list[securityKey(security.Exchange, security.Symbol, security.Board)] = security
+
+	// Без режима торгов инструмент ищется по основному
+	if security.Board == security.PrimaryBoard {
+		s.list[securityKey(security.Exchange, security.Symbol, "")] = security
+	}
+}
+
+// Get инструмент по тикеру и режиму торгов, пустой board - основной режим
+func (s *Securities) Get(exchange Exchange, code string, board string) (Security, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	security, ok := s.list[securityKey(exchange, code, board)]
+	if !ok {
+		return Security{}, fmt.Errorf("%w: %s:%s %s", ErrSecurityNotFound, exchange, code, board)
+	}
+
+	return security, nil
+}
+
+func (s *Securities) Loaded(exchange Exchange) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.loaded[exchange]
+}
+
+// load заменяет инструменты биржи загруженными по REST
+func (s *Securities) load(exchange Exchange, securities []Security) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, security := range securities {
+		s.set(security)
+	}
+
+	s.loaded[exchange] = true
+}
+
+func (s *Securities) addGUID(guid GUID, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guids[guid]; ok {
+		return false
+	}
+
+	s.guids[guid] = key
+
+	return true
+}
+
+func (s *Securities) removeGUID(guid GUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.guids, guid)
+}
+
+// HandleEvent обновляет инструмент. Не пришедшие в событии поля сохраняют прежние значения.
+func (s *Securities) HandleEvent(event *ChainEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.guids[event.Guid]
+	if !ok {
+		return nil
+	}
+
+	security := s.list[key]
+	if err := json.Unmarshal(event.Data, &security); err != nil {
+		return err
+	}
+
+	s.set(security)
+
+	return nil
+}
+
+// RoundPrice округляет цену до ближайшего шага цены инструмента
+func (s Security) RoundPrice(price float64) float64 {
+	if s.PriceStep <= 0 {
+		return price
+	}
+
+	rounded := math.Round(price/s.PriceStep) * s.PriceStep
+
+	// Убираем хвосты вида 250.12000000000001, точность - как у шага цены
+	decimals := 0
+	formatted := strconv.FormatFloat(s.PriceStep, 'f', -1, 64)
+	if dot := strings.IndexByte(formatted, '.'); dot >= 0 {
+		decimals = len(formatted) - dot - 1
+	}
+
+	pow := math.Pow10(decimals)
+
+	return math.Round(rounded*pow) / pow
+}
+
+// InstrumentsRequest подписка на изменения справочной информации инструмента
+type InstrumentsRequest struct {
+	Opcode          Opcode         `json:"opcode"`          // Код выполняемой операции
+	Code            string         `json:"code"`            // Код финансового инструмента (Тикер)
+	Exchange        Exchange       `json:"exchange"`        // Биржа
+	InstrumentGroup string         `json:"instrumentGroup"` // Код режима торгов (Борд)
+	Format          ResponseFormat `json:"format"`          // Формат представления возвращаемых данных
+	Guid            GUID           `json:"guid"`            // Не более 50 символов. Уникальный идентификатор сообщений создаваемой подписки
+	Token           string         `json:"token"`           // Access Токен для авторизации запроса
+}
+
+func (ws *Websocket) prepareInstrumentsRequest(token *Token, subscription *Subscription) ([]byte, error) {
+	accessToken, err := token.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Slim для справочника не документирован, берём Simple - он совпадает с REST
+	request := InstrumentsRequest{
+		Opcode:          subscription.Opcode,
+		Code:            subscription.Code,
+		Exchange:        subscription.Exchange,
+		InstrumentGroup: subscription.InstrumentGroup,
+		Format:          SimpleResponseFormat,
+		Guid:            subscription.GUID,
+		Token:           accessToken,
+	}
+
+	return json.Marshal(request)
+}
+
+// LoadSecurities загружает в справочник все инструменты биржи
+func (c *Client) LoadSecurities(exchange Exchange) error {
+	securities, err := c.GetSecurities(exchange)
+	if err != nil {
+		return err
+	}
+
+	c.Securities.load(exchange, securities)
+
+	return nil
+}
+
+// SubscribeSecurity проверяет, что инструмент существует, и подписывает справочник на его изменения.
+// Биржа загружается при первом обращении, повторная подписка не создаётся.
+func (c *Client) SubscribeSecurity(exchange Exchange, code string, board string) (Security, error) {
+	if !c.Securities.Loaded(exchange) {
+		if err := c.LoadSecurities(exchange); err != nil {
+			return Security{}, err
+		}
+	}
+
+	security, err := c.Securities.Get(exchange, code, board)
+	if err != nil {
+		return Security{}, err
+	}
+
+	subscription := &Subscription{
+		GUID:            securityGUID(security.Exchange, security.Symbol, security.Board),
+		Opcode:          InstrumentsOpcode,
+		Exchange:        security.Exchange,
+		Code:            security.Symbol,
+		InstrumentGroup: security.Board,
+	}
+
+	if !c.Securities.addGUID(subscription.GUID, securityKey(security.Exchange, security.Symbol, security.Board)) {
+		return security, nil
+	}
+
+	if err := c.Pool.subscribeClient(c.Token, subscription); err != nil {
+		c.Securities.removeGUID(subscription.GUID)
+		return Security{}, err
+	}
+
+	return security, nil
+}
+
+func (c *Client) SecuritiesDirectory() *Securities {
+	return c.Securities
+}
+
+// securityCommandBus округляет цены заявок до шага цены инструмента
+type securityCommandBus struct {
+	CommandBus
+	securities *Securities
+}
+
+func (b *securityCommandBus) round(target OrderTarget, prices ...*float64) {
+	security, err := b.securities.Get(target.Exchange, target.Code, target.Board)
+	if err != nil {
+		return
+	}
+
+	for _, price := range prices {
+		*price = security.RoundPrice(*price)
+	}
+}
+
+func (b *securityCommandBus) CreateLimitOrder(params LimitOrderParams) (OrderID, error) {
+	b.round(params.OrderTarget, &params.Price)
+	return b.CommandBus.CreateLimitOrder(params)
+}
+
+func (b *securityCommandBus) CreateStopOrder(params StopOrderParams) (OrderID, error) {
+	b.round(params.OrderTarget, &params.TriggerPrice)
+	return b.CommandBus.CreateStopOrder(params)
+}
+
+func (b *securityCommandBus) CreateStopLimitOrder(params StopLimitOrderParams) (OrderID, error) {
+	b.round(params.OrderTarget, &params.Price, &params.TriggerPrice)
+	return b.CommandBus.CreateStopLimitOrder(params)
+}
+
+func (b *securityCommandBus) ModifyLimitOrder(orderID OrderID, params LimitOrderParams) (OrderID, error) {
+	b.round(params.OrderTarget, &params.Price)
+	return b.CommandBus.ModifyLimitOrder(orderID, params)
+}
+
+func (b *securityCommandBus) ModifyStopOrder(orderID OrderID, params StopOrderParams) (OrderID, error) {
+	b.round(params.OrderTarget, &params.TriggerPrice)
+	return b.CommandBus.ModifyStopOrder(orderID, params)
+}
+
+func (b *securityCommandBus) ModifyStopLimitOrder(orderID OrderID, params StopLimitOrderParams) (OrderID, error) {
+	b.round(params.OrderTarget, &params.Price, &params.TriggerPrice)
+	return b.CommandBus.ModifyStopLimitOrder(orderID, params)
+}
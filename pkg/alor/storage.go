@@ -1,7 +1,9 @@
 package alor
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
 	"sync"
 )
 
@@ -22,6 +24,51 @@ func newStorage() *Storage {
 	}
 }
 
+// storageState содержимое Storage без блокировки, для сохранения и восстановления
+type storageState struct {
+	FlagStorage map[string]bool    `json:"flag_storage"`
+	TextStorage map[string]string  `json:"text_storage"`
+	IntStorage  map[string]float64 `json:"int_storage"`
+	DecStorage  map[string]float64 `json:"dec_storage"`
+}
+
+// MarshalJSON снимок хранилища под блокировкой, стратегия может писать в него параллельно
+func (s *Storage) MarshalJSON() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return json.Marshal(storageState{
+		FlagStorage: s.FlagStorage,
+		TextStorage: s.TextStorage,
+		IntStorage:  s.IntStorage,
+		DecStorage:  s.DecStorage,
+	})
+}
+
+// UnmarshalJSON восстанавливает сохранённое состояние, отсутствующие разделы создаются пустыми
+func (s *Storage) UnmarshalJSON(data []byte) error {
+	var state storageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	restored := newStorage()
+	maps.Copy(restored.FlagStorage, state.FlagStorage)
+	maps.Copy(restored.TextStorage, state.TextStorage)
+	maps.Copy(restored.IntStorage, state.IntStorage)
+	maps.Copy(restored.DecStorage, state.DecStorage)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.FlagStorage = restored.FlagStorage
+	s.TextStorage = restored.TextStorage
+	s.IntStorage = restored.IntStorage
+	s.DecStorage = restored.DecStorage
+
+	return nil
+}
+
 func (s *Storage) SetFlag(name string, value bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -31,7 +78,7 @@ func (s *Storage) SetFlag(name string, value bool) {
 
 func (s *Storage) GetFlag(name string) (bool, error) {
 	s.mu.RLock()
-	defer s.mu.Unlock()
+	defer s.mu.RUnlock()
 
 	flag, ok := s.FlagStorage[name]
 	if !ok {
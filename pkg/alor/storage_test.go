@@ -0,0 +1,45 @@
+package alor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStorageJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	storage := newStorage()
+	storage.SetFlag("in_position", true)
+	storage.TextStorage["order"] = "42"
+
+	raw, err := json.Marshal(storage)
+	require.NoError(t, err)
+
+	restored := &Storage{}
+	require.NoError(t, json.Unmarshal(raw, restored))
+	require.Equal(t, map[string]bool{"in_position": true}, restored.FlagStorage)
+	require.Equal(t, "42", restored.TextStorage["order"])
+
+	// Разделы, которых не было в сохранении, создаются пустыми и готовы к записи
+	require.NoError(t, json.Unmarshal([]byte(`{"flag_storage":{"a":true}}`), restored))
+	require.NotNil(t, restored.DecStorage)
+	restored.SetFlag("b", false)
+}
+
+func TestStorageGetFlag(t *testing.T) {
+	t.Parallel()
+
+	storage := newStorage()
+	storage.SetFlag("in_position", true)
+
+	flag, err := storage.GetFlag("in_position")
+	require.NoError(t, err)
+	require.True(t, flag)
+
+	_, err = storage.GetFlag("missing")
+	require.Error(t, err)
+
+	// Блокировка чтения отпущена, запись не зависает
+	storage.SetFlag("in_position", false)
+}
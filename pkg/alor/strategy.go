@@ -1,38 +1,42 @@
 package alor
 
-import (
-	"encoding/json"
-	"errors"
-	"fmt"
-	"strings"
-)
-
+// Strategy торговая логика подписчика.
+// OnStart и OnStop вызываются при добавлении и удалении подписчика из вебсокета,
+// остальные колбэки - на каждое событие соответствующей подписки.
 type Strategy interface {
-	Handle(opcode Opcode, data interface{}) error
+	OnStart() error
+	OnStop() error
+	OnBar(data BarsSlimData) error
+	OnBarClosed(bar *Bar) error
+	OnTimeframeBarClosed(timeframe Timeframe, bar *Bar) error // Бар старшего таймфрейма из WithTimeframes
+	OnTrade(data AllTradesSlimData) error
+	OnOrderBook(data OrderBookSlimData) error
+	OnQuote(data QuotesSlimData) error
+	OnOrderUpdate(update OrderUpdate) error
+	OnFill(fill Fill) error
 	SetDataProcessor(processor *DataProcessor)
 	SetStorage(storage *Storage)
+	SetCommandBus(commandBus CommandBus)
+	SetPortfolio(portfolio *PortfolioState)
 }
 
-func NewStrategy(name string, settings json.RawMessage) (Strategy, error) {
-	if name != "" {
-		name = "base"
-	}
-
-	switch strings.ToLower(name) {
-	case "base":
+func init() {
+	RegisterStrategy("base", "Стратегия без торговой логики, только сбор данных", struct{}{}, func(struct{}) (Strategy, error) {
 		return &BaseStrategy{}, nil
-	default:
-		return nil, fmt.Errorf("unknown strategy: %s", name)
-	}
+	})
 }
 
-// !!! интерфейс для dataProcessor, commandBus и messageBus
+type StrategyHandler func(opcode Opcode, data interface{}, processor *DataProcessor, storage *Storage, commandBus CommandBus, messageBus int64) error
+
+// BaseStrategy пустая реализация всех колбэков, встраивается в пользовательские стратегии.
+// Для совместимости события без переопределённого колбэка уходят в Handlers по опкоду.
 type BaseStrategy struct {
 	Storage    *Storage
 	Processor  *DataProcessor
-	CommandBus int64
+	CommandBus CommandBus
+	Portfolio  *PortfolioState // Позиции и риски счёта, nil если подписчик не привязан к портфелю
 	MessageBus int64
-	Handlers   map[Opcode]func(opcode Opcode, data interface{}, processor *DataProcessor, storage *Storage, commandBus, messageBus int64) error
+	Handlers   map[Opcode]StrategyHandler
 }
 
 func (s *BaseStrategy) SetDataProcessor(processor *DataProcessor) {
@@ -43,77 +47,137 @@ func (s *BaseStrategy) SetStorage(storage *Storage) {
 	s.Storage = storage
 }
 
+func (s *BaseStrategy) SetCommandBus(commandBus CommandBus) {
+	s.CommandBus = commandBus
+}
+
+func (s *BaseStrategy) SetPortfolio(portfolio *PortfolioState) {
+	s.Portfolio = portfolio
+}
+
+func (s *BaseStrategy) OnStart() error { return nil }
+
+func (s *BaseStrategy) OnStop() error { return nil }
+
+func (s *BaseStrategy) OnBar(data BarsSlimData) error {
+	return s.handleOptional(BarsOpcode, data)
+}
+
+func (s *BaseStrategy) OnBarClosed(bar *Bar) error { return nil }
+
+func (s *BaseStrategy) OnTimeframeBarClosed(timeframe Timeframe, bar *Bar) error { return nil }
+
+func (s *BaseStrategy) OnTrade(data AllTradesSlimData) error {
+	return s.handleOptional(AllTradesOpcode, data)
+}
+
+func (s *BaseStrategy) OnOrderBook(data OrderBookSlimData) error {
+	return s.handleOptional(OrderBookOpcode, data)
+}
+
+func (s *BaseStrategy) OnQuote(data QuotesSlimData) error {
+	return s.handleOptional(QuotesOpcode, data)
+}
+
+// OnOrderUpdate изменение статуса заявки, выставленной этим подписчиком
+func (s *BaseStrategy) OnOrderUpdate(update OrderUpdate) error {
+	if update.Stop {
+		return s.handleOptional(StopOrdersOpcode, update)
+	}
+
+	return s.handleOptional(OrdersOpcode, update)
+}
+
+// OnFill исполнение заявки, выставленной этим подписчиком
+func (s *BaseStrategy) OnFill(fill Fill) error {
+	return s.handleOptional(TradesOpcode, fill)
+}
+
+// Handle вызывает обработчик из Handlers по опкоду
 func (s *BaseStrategy) Handle(opcode Opcode, data interface{}) error {
-	_, ok := s.Handlers[opcode]
+	handler, ok := s.Handlers[opcode]
 	if !ok {
 		return ErrNoAvailableHandler
 	}
 
-	return s.Handlers[opcode](opcode, data, s.Processor, s.Storage, s.CommandBus, s.MessageBus)
+	return handler(opcode, data, s.Processor, s.Storage, s.CommandBus, s.MessageBus)
 }
 
+// handleOptional как Handle, но отсутствие обработчика не считается ошибкой
+func (s *BaseStrategy) handleOptional(opcode Opcode, data interface{}) error {
+	if _, ok := s.Handlers[opcode]; !ok {
+		return nil
+	}
+
+	return s.Handle(opcode, data)
+}
+
+// Адаптеры для стратегий в виде одной функции
+
 type OrderBookStrategy struct {
 	BaseStrategy
 	Opcode     Opcode
-	HandleFunc func(data OrderBookSlimData, processor *DataProcessor, commandBus int64, messageBus int64) error
+	HandleFunc func(data OrderBookSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error
 }
 
-func NewOrderBookStrategy(handleFunc func(data OrderBookSlimData, processor *DataProcessor, commandBus int64, messageBus int64) error) *OrderBookStrategy {
+func NewOrderBookStrategy(handleFunc func(data OrderBookSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error) *OrderBookStrategy {
 	return &OrderBookStrategy{
 		Opcode:     OrderBookOpcode,
 		HandleFunc: handleFunc,
 	}
 }
 
-func (h *OrderBookStrategy) Handle(data interface{}, processor *DataProcessor, commandBus int64, messageBus int64) error {
-	switch v := data.(type) {
-	case OrderBookSlimData:
-		return h.HandleFunc(v, processor, commandBus, messageBus)
-	default:
-		return errors.New("invalid data type")
-	}
+func (h *OrderBookStrategy) OnOrderBook(data OrderBookSlimData) error {
+	return h.HandleFunc(data, h.Processor, h.CommandBus, h.MessageBus)
 }
 
 type AllTradesStrategy struct {
 	BaseStrategy
 	Opcode     Opcode
-	HandleFunc func(data AllTradesSlimData, processor *DataProcessor, commandBus int64, messageBus int64) error
+	HandleFunc func(data AllTradesSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error
 }
 
-func NewAllTradesStrategy(handleFunc func(data AllTradesSlimData, processor *DataProcessor, commandBus int64, messageBus int64) error) *AllTradesStrategy {
+func NewAllTradesStrategy(handleFunc func(data AllTradesSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error) *AllTradesStrategy {
 	return &AllTradesStrategy{
 		Opcode:     AllTradesOpcode,
 		HandleFunc: handleFunc,
 	}
 }
 
-func (h *AllTradesStrategy) Handle(data interface{}, processor *DataProcessor, commandBus int64, messageBus int64) error {
-	switch v := data.(type) {
-	case AllTradesSlimData:
-		return h.HandleFunc(v, processor, commandBus, messageBus)
-	default:
-		return errors.New("invalid data type")
-	}
+func (h *AllTradesStrategy) OnTrade(data AllTradesSlimData) error {
+	return h.HandleFunc(data, h.Processor, h.CommandBus, h.MessageBus)
 }
 
 type BarsStrategy struct {
 	BaseStrategy
 	Opcode     Opcode
-	HandleFunc func(data BarsSlimData, processor *DataProcessor, commandBus int64, messageBus int64) error
+	HandleFunc func(data BarsSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error
 }
 
-func NewBarsStrategy(handleFunc func(data BarsSlimData, processor *DataProcessor, commandBus int64, messageBus int64) error) *BarsStrategy {
+func NewBarsStrategy(handleFunc func(data BarsSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error) *BarsStrategy {
 	return &BarsStrategy{
 		Opcode:     BarsOpcode,
 		HandleFunc: handleFunc,
 	}
 }
 
-func (h *BarsStrategy) Handle(data interface{}, processor *DataProcessor, commandBus int64, messageBus int64) error {
-	switch v := data.(type) {
-	case BarsSlimData:
-		return h.HandleFunc(v, processor, commandBus, messageBus)
-	default:
-		return errors.New("invalid data type")
+func (h *BarsStrategy) OnBar(data BarsSlimData) error {
+	return h.HandleFunc(data, h.Processor, h.CommandBus, h.MessageBus)
+}
+
+type QuotesStrategy struct {
+	BaseStrategy
+	Opcode     Opcode
+	HandleFunc func(data QuotesSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error
+}
+
+func NewQuotesStrategy(handleFunc func(data QuotesSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error) *QuotesStrategy {
+	return &QuotesStrategy{
+		Opcode:     QuotesOpcode,
+		HandleFunc: handleFunc,
 	}
 }
+
+func (h *QuotesStrategy) OnQuote(data QuotesSlimData) error {
+	return h.HandleFunc(data, h.Processor, h.CommandBus, h.MessageBus)
+}
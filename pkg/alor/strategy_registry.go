@@ -0,0 +1,385 @@
+package alor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var strategyRegistry = NewStrategyRegistry()
+
+// Strategies реестр стратегий по умолчанию, в него регистрируются пакеты стратегий
+func Strategies() *StrategyRegistry {
+	return strategyRegistry
+}
+
+// RegisterStrategy регистрирует стратегию в реестре по умолчанию.
+// defaults - структура настроек со значениями по умолчанию, по ней же строится схема для UI.
+// Каждая стратегия получает свою копию defaults через JSON, поля без json в копию не попадают.
+// Вызывается из init() пакета стратегии, повторная регистрация имени - паника.
+func RegisterStrategy[T any](name string, description string, defaults T, constructor func(settings T) (Strategy, error)) {
+	strategyRegistry.Register(newStrategyDefinition(name, description, defaults, constructor))
+}
+
+func NewStrategy(name string, settings json.RawMessage) (Strategy, error) {
+	return strategyRegistry.New(name, settings)
+}
+
+type (
+	StrategyDefinition struct {
+		Name        string
+		Description string
+		schema      SettingsSchema
+		build       func(settings json.RawMessage) (Strategy, error)
+	}
+
+	StrategyRegistry struct {
+		definitions map[string]StrategyDefinition
+		mu          sync.RWMutex
+	}
+)
+
+func NewStrategyRegistry() *StrategyRegistry {
+	return &StrategyRegistry{
+		definitions: make(map[string]StrategyDefinition),
+	}
+}
+
+func newStrategyDefinition[T any](name string, description string, defaults T, constructor func(settings T) (Strategy, error)) StrategyDefinition {
+	settingsType := reflect.TypeOf(defaults)
+	if settingsType == nil || settingsType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("strategy %s: settings must be a struct, got %T", name, defaults))
+	}
+
+	// Значения по умолчанию хранятся в JSON: срезы и карты настроек не делятся между стратегиями
+	defaultsJSON, err := json.Marshal(defaults)
+	if err != nil {
+		panic(fmt.Sprintf("strategy %s: encode default settings: %s", name, err))
+	}
+
+	return StrategyDefinition{
+		Name:        name,
+		Description: description,
+		schema:      newSettingsSchema(settingsType, reflect.ValueOf(defaults)),
+		build: func(rawSettings json.RawMessage) (Strategy, error) {
+			// Свежая копия значений по умолчанию, поверх которой разбираются пришедшие настройки
+			var settings T
+			if err := json.Unmarshal(defaultsJSON, &settings); err != nil {
+				return nil, fmt.Errorf("strategy %s: decode default settings: %w", name, err)
+			}
+
+			if err := decodeStrategySettings(name, rawSettings, &settings); err != nil {
+				return nil, err
+			}
+
+			return constructor(settings)
+		},
+	}
+}
+
+func (r *StrategyRegistry) Register(definition StrategyDefinition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strings.ToLower(definition.Name)
+	if _, ok := r.definitions[key]; ok {
+		panic(fmt.Sprintf("strategy %s already registered", definition.Name))
+	}
+
+	r.definitions[key] = definition
+}
+
+// New создаёт стратегию по имени, проверяя настройки по зарегистрированной схеме
+func (r *StrategyRegistry) New(name string, settings json.RawMessage) (Strategy, error) {
+	if name == "" {
+		name = "base"
+	}
+
+	r.mu.RLock()
+	definition, ok := r.definitions[strings.ToLower(name)]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+
+	return definition.build(settings)
+}
+
+// Schemas список зарегистрированных стратегий со схемами настроек, отсортированный по имени
+func (r *StrategyRegistry) Schemas() []StrategySchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemas := make([]StrategySchema, 0, len(r.definitions))
+	for _, definition := range r.definitions {
+		schemas = append(schemas, StrategySchema{
+			Name:        definition.Name,
+			Description: definition.Description,
+			Settings:    definition.schema,
+		})
+	}
+
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Name < schemas[j].Name
+	})
+
+	return schemas
+}
+
+// Ошибки настроек
+
+type SettingsFieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type StrategySettingsError struct {
+	Strategy string               `json:"strategy"`
+	Fields   []SettingsFieldError `json:"fields"`
+}
+
+func (e *StrategySettingsError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+
+	return fmt.Sprintf("%s for %s: %s", ErrInvalidStrategySettings, e.Strategy, strings.Join(messages, "; "))
+}
+
+func (e *StrategySettingsError) Unwrap() error {
+	return ErrInvalidStrategySettings
+}
+
+var settingsValidator = newSettingsValidator()
+
+func newSettingsValidator() *validator.Validate {
+	v := validator.New()
+
+	// В ошибках используем имена полей из json, их видит пользователь
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return settingsFieldName(field)
+	})
+
+	return v
+}
+
+func decodeStrategySettings(strategyName string, rawSettings json.RawMessage, settings any) error {
+	fields, err := decodeSettings(rawSettings, settings)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) != 0 {
+		return &StrategySettingsError{Strategy: strategyName, Fields: fields}
+	}
+
+	return nil
+}
+
+// decodeSettings разбирает настройки поверх значений по умолчанию и проверяет их по тегам validate.
+// Ошибки пользовательского ввода возвращаются списком по полям, err - только внутренние ошибки.
+func decodeSettings(rawSettings json.RawMessage, settings any) ([]SettingsFieldError, error) {
+	rawSettings = bytes.TrimSpace(rawSettings)
+
+	if len(rawSettings) != 0 && !bytes.Equal(rawSettings, []byte("null")) {
+		decoder := json.NewDecoder(bytes.NewReader(rawSettings))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(settings); err != nil {
+			return []SettingsFieldError{newDecodeFieldError(err)}, nil
+		}
+	}
+
+	if err := settingsValidator.Struct(settings); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return nil, err
+		}
+
+		fields := make([]SettingsFieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			fields = append(fields, SettingsFieldError{
+				Field:   settingsFieldPath(fieldErr.Namespace()),
+				Rule:    fieldErr.Tag(),
+				Message: newValidationMessage(fieldErr),
+			})
+		}
+
+		return fields, nil
+	}
+
+	return nil, nil
+}
+
+func newDecodeFieldError(err error) SettingsFieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return SettingsFieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+
+	// json: unknown field "name"
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return SettingsFieldError{
+			Field:   strings.Trim(field, `"`),
+			Rule:    "unknown",
+			Message: "unknown field",
+		}
+	}
+
+	return SettingsFieldError{
+		Rule:    "json",
+		Message: err.Error(),
+	}
+}
+
+func newValidationMessage(fieldErr validator.FieldError) string {
+	if fieldErr.Param() == "" {
+		return fmt.Sprintf("failed on %s", fieldErr.Tag())
+	}
+
+	return fmt.Sprintf("failed on %s=%s", fieldErr.Tag(), fieldErr.Param())
+}
+
+// settingsFieldPath убирает имя корневой структуры из пути поля
+func settingsFieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+
+	return namespace
+}
+
+func settingsFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+// Схема настроек в формате JSON Schema, по ней UI строит формы
+
+type StrategySchema struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Settings    SettingsSchema `json:"settings"`
+}
+
+type SettingsSchema struct {
+	Type        string                    `json:"type"`
+	Description string                    `json:"description,omitempty"`
+	Default     any                       `json:"default,omitempty"`
+	Enum        []string                  `json:"enum,omitempty"`
+	Minimum     *float64                  `json:"minimum,omitempty"`
+	Maximum     *float64                  `json:"maximum,omitempty"`
+	Items       *SettingsSchema           `json:"items,omitempty"`
+	Properties  map[string]SettingsSchema `json:"properties,omitempty"`
+	Required    []string                  `json:"required,omitempty"`
+}
+
+func newSettingsSchema(settingsType reflect.Type, defaults reflect.Value) SettingsSchema {
+	for settingsType.Kind() == reflect.Pointer {
+		settingsType = settingsType.Elem()
+		if defaults.IsValid() && !defaults.IsNil() {
+			defaults = defaults.Elem()
+		} else {
+			defaults = reflect.Value{}
+		}
+	}
+
+	schema := SettingsSchema{}
+
+	switch settingsType.Kind() {
+	case reflect.Struct:
+		schema.Type = "object"
+		schema.Properties = make(map[string]SettingsSchema)
+
+		for i := 0; i < settingsType.NumField(); i++ {
+			field := settingsType.Field(i)
+			name := settingsFieldName(field)
+			if !field.IsExported() || name == "" {
+				continue
+			}
+
+			var fieldDefaults reflect.Value
+			if defaults.IsValid() {
+				fieldDefaults = defaults.Field(i)
+			}
+
+			property := newSettingsSchema(field.Type, fieldDefaults)
+			property.Description = field.Tag.Get("description")
+
+			if applyValidateRules(&property, field.Tag.Get("validate")) {
+				schema.Required = append(schema.Required, name)
+			}
+
+			schema.Properties[name] = property
+		}
+
+		return schema
+	case reflect.Slice, reflect.Array:
+		schema.Type = "array"
+		items := newSettingsSchema(settingsType.Elem(), reflect.Value{})
+		schema.Items = &items
+	case reflect.Map:
+		schema.Type = "object"
+	case reflect.Bool:
+		schema.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		schema.Type = "number"
+	default:
+		schema.Type = "string"
+	}
+
+	if defaults.IsValid() && !defaults.IsZero() {
+		schema.Default = defaults.Interface()
+	}
+
+	return schema
+}
+
+// applyValidateRules переносит правила validator в схему, возвращает признак обязательного поля
+func applyValidateRules(schema *SettingsSchema, rules string) (required bool) {
+	for _, rule := range strings.Split(rules, ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+
+		switch tag {
+		case "required":
+			required = true
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "gte":
+			if value, err := strconv.ParseFloat(param, 64); err == nil && schema.Type != "string" && schema.Type != "array" {
+				schema.Minimum = &value
+			}
+		case "max", "lte":
+			if value, err := strconv.ParseFloat(param, 64); err == nil && schema.Type != "string" && schema.Type != "array" {
+				schema.Maximum = &value
+			}
+		}
+	}
+
+	return required
+}
@@ -0,0 +1,140 @@
+package alor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type testStrategySettings struct {
+	Period int     `json:"period" validate:"required,min=2,max=200" description:"Период"`
+	Mode   string  `json:"mode" validate:"oneof=fast slow"`
+	Ratio  float64 `json:"ratio"`
+}
+
+func newTestStrategyRegistry() (*StrategyRegistry, *testStrategySettings) {
+	got := &testStrategySettings{}
+
+	registry := NewStrategyRegistry()
+	registry.Register(newStrategyDefinition("test", "test strategy", testStrategySettings{Period: 14, Mode: "fast"}, func(settings testStrategySettings) (Strategy, error) {
+		*got = settings
+		return &BaseStrategy{}, nil
+	}))
+
+	return registry, got
+}
+
+func TestStrategyRegistryNew(t *testing.T) {
+	t.Parallel()
+
+	registry, got := newTestStrategyRegistry()
+
+	strategy, err := registry.New("TEST", json.RawMessage(`{"period": 20}`))
+
+	require.NoError(t, err)
+	require.NotNil(t, strategy)
+	require.Equal(t, testStrategySettings{Period: 20, Mode: "fast"}, *got)
+}
+
+func TestStrategyRegistryDefaultsNotShared(t *testing.T) {
+	t.Parallel()
+
+	type settings struct {
+		Levels []float64 `json:"levels"`
+	}
+
+	var got []settings
+
+	registry := NewStrategyRegistry()
+	registry.Register(newStrategyDefinition("levels", "levels strategy", settings{Levels: []float64{1, 2, 3}}, func(settings settings) (Strategy, error) {
+		got = append(got, settings)
+		return &BaseStrategy{}, nil
+	}))
+
+	_, err := registry.New("levels", json.RawMessage(`{"levels": [10, 20]}`))
+	require.NoError(t, err)
+
+	// Разбор первых настроек не переписал значения по умолчанию для следующей стратегии
+	_, err = registry.New("levels", nil)
+	require.NoError(t, err)
+
+	require.Equal(t, []float64{10, 20}, got[0].Levels)
+	require.Equal(t, []float64{1, 2, 3}, got[1].Levels)
+
+	got[1].Levels[0] = 100
+
+	_, err = registry.New("levels", nil)
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 2, 3}, got[2].Levels)
+}
+
+func TestStrategyRegistryUnknown(t *testing.T) {
+	t.Parallel()
+
+	registry, _ := newTestStrategyRegistry()
+
+	_, err := registry.New("unknown", nil)
+
+	require.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestStrategyRegistryFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	registry, _ := newTestStrategyRegistry()
+
+	testCases := []struct {
+		name      string
+		settings  string
+		wantField string
+		wantRule  string
+	}{
+		{name: "min", settings: `{"period": 1}`, wantField: "period", wantRule: "min"},
+		{name: "oneof", settings: `{"mode": "medium"}`, wantField: "mode", wantRule: "oneof"},
+		{name: "type", settings: `{"period": "ten"}`, wantField: "period", wantRule: "type"},
+		{name: "unknown", settings: `{"size": 1}`, wantField: "size", wantRule: "unknown"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := registry.New("test", json.RawMessage(tc.settings))
+
+			var settingsErr *StrategySettingsError
+			require.ErrorAs(t, err, &settingsErr)
+			require.ErrorIs(t, err, ErrInvalidStrategySettings)
+			require.Len(t, settingsErr.Fields, 1)
+			require.Equal(t, tc.wantField, settingsErr.Fields[0].Field)
+			require.Equal(t, tc.wantRule, settingsErr.Fields[0].Rule)
+		})
+	}
+}
+
+func TestStrategyRegistrySchemas(t *testing.T) {
+	t.Parallel()
+
+	registry, _ := newTestStrategyRegistry()
+
+	schemas := registry.Schemas()
+
+	require.Len(t, schemas, 1)
+	settings := schemas[0].Settings
+	require.Equal(t, "object", settings.Type)
+	require.Equal(t, []string{"period"}, settings.Required)
+	require.Equal(t, "integer", settings.Properties["period"].Type)
+	require.Equal(t, 14, settings.Properties["period"].Default)
+	require.Equal(t, 2.0, *settings.Properties["period"].Minimum)
+	require.Equal(t, 200.0, *settings.Properties["period"].Maximum)
+	require.Equal(t, []string{"fast", "slow"}, settings.Properties["mode"].Enum)
+	require.Equal(t, "number", settings.Properties["ratio"].Type)
+}
+
+func TestDefaultRegistryHasBaseStrategy(t *testing.T) {
+	t.Parallel()
+
+	strategy, err := NewStrategy("", nil)
+
+	require.NoError(t, err)
+	require.IsType(t, &BaseStrategy{}, strategy)
+}
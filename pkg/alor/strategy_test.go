@@ -0,0 +1,93 @@
+package alor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type recordingStrategy struct {
+	BaseStrategy
+	started, stopped bool
+	trades           []AllTradesSlimData
+	closedBars       []*Bar
+}
+
+func (s *recordingStrategy) OnStart() error {
+	s.started = true
+	return nil
+}
+
+func (s *recordingStrategy) OnStop() error {
+	s.stopped = true
+	return nil
+}
+
+func (s *recordingStrategy) OnTrade(data AllTradesSlimData) error {
+	s.trades = append(s.trades, data)
+	return nil
+}
+
+func (s *recordingStrategy) OnBarClosed(bar *Bar) error {
+	s.closedBars = append(s.closedBars, bar)
+	return nil
+}
+
+func newAllTradesEvent(t *testing.T, data AllTradesSlimData) *ChainEvent {
+	t.Helper()
+
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+
+	return &ChainEvent{Type: DataType, Opcode: AllTradesOpcode, Data: raw}
+}
+
+func TestSubscriberTypedCallbacks(t *testing.T) {
+	t.Parallel()
+
+	strategy := &recordingStrategy{}
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false)
+	subscriber.SetStrategy(strategy)
+
+	require.NoError(t, subscriber.Init())
+	require.True(t, strategy.started)
+
+	subscriber.Ready = true
+
+	require.NoError(t, subscriber.HandleEventSync(newAllTradesEvent(t, AllTradesSlimData{ID: 1, Price: 100, Qty: 1, Timestamp: 1_000, Side: BuySide})))
+	require.NoError(t, subscriber.HandleEventSync(newAllTradesEvent(t, AllTradesSlimData{ID: 2, Price: 101, Qty: 2, Timestamp: 30_000, Side: SellSide})))
+	require.Empty(t, strategy.closedBars)
+
+	// Сделка из следующей минуты закрывает предыдущий бар
+	require.NoError(t, subscriber.HandleEventSync(newAllTradesEvent(t, AllTradesSlimData{ID: 3, Price: 99, Qty: 1, Timestamp: 61_000, Side: SellSide})))
+
+	require.Len(t, strategy.trades, 3)
+	require.Len(t, strategy.closedBars, 1)
+	require.Equal(t, 100.0, strategy.closedBars[0].Open)
+	require.Equal(t, 101.0, strategy.closedBars[0].Close)
+	require.Equal(t, int64(3), strategy.closedBars[0].Volume)
+
+	require.NoError(t, subscriber.DeInit())
+	require.True(t, strategy.stopped)
+}
+
+func TestFunctionStrategyAdapter(t *testing.T) {
+	t.Parallel()
+
+	var got []AllTradesSlimData
+
+	strategy := NewAllTradesStrategy(func(data AllTradesSlimData, processor *DataProcessor, commandBus CommandBus, messageBus int64) error {
+		require.NotNil(t, processor)
+		got = append(got, data)
+		return nil
+	})
+
+	subscriber := NewSubscriber("test", MOEXExchange, "SBER", "TQBR", M1TF, false)
+	subscriber.SetStrategy(strategy)
+	subscriber.Ready = true
+
+	require.NoError(t, subscriber.HandleEventSync(newAllTradesEvent(t, AllTradesSlimData{ID: 1, Price: 100, Qty: 1, Timestamp: 1_000})))
+	require.NoError(t, subscriber.HandleEventSync(&ChainEvent{Type: DataType, Opcode: OrderBookOpcode, Data: json.RawMessage(`{"b":[],"a":[],"t":1000}`)}))
+
+	require.Len(t, got, 1)
+}
@@ -1,8 +1,8 @@
 package alor
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"log"
@@ -25,8 +25,11 @@ func NewSubscriber(description string, exchange Exchange, code string, board str
 		Strategy:      nil,
 		Ready:         false,
 		Async:         async,
+		Mode:          LiveSubscriberMode,
 		Queue:         NewChainQueue(10000),
+		QueueLimits:   DefaultQueueLimits,
 		Done:          false,
+		wake:          make(chan struct{}, 1),
 	}
 
 	for _, opt := range opts {
@@ -77,20 +80,55 @@ type Subscriber struct {
 	Timeframe     Timeframe                `json:"timeframe"`
 	Subscriptions map[Opcode]*Subscription `json:"subscriptions"` // Подписки на инструменты
 	Ready         bool                     `json:"ready"`
-	Storage       *Storage                 `json:"storage"` // Для передачи пользовательских состояний между обработчиками
-	Strategy      Strategy                 `json:"-"`       // Стратегия
-	DataProcessor *DataProcessor           `json:"-"`       // Бары, индикаторы, читает стратегию и добавляет индикаторы, можно добавлять пользовательские индикаторы
-	Async         bool                     `json:"async"`   // Асинхронный режим
-	Queue         *ChainQueue              `json:"queue"`   // Очередь для асинхронной обработки
+	Storage       *Storage                 `json:"storage"`         // Для передачи пользовательских состояний между обработчиками
+	Strategy      Strategy                 `json:"-"`               // Стратегия
+	DataProcessor *DataProcessor           `json:"-"`               // Бары, индикаторы, читает стратегию и добавляет индикаторы, можно добавлять пользовательские индикаторы
+	Async         bool                     `json:"async"`           // Асинхронный режим
+	Mode          SubscriberMode           `json:"mode"`            // Живая или бумажная торговля
+	PaperBroker   *PaperBroker             `json:"paper,omitempty"` // Виртуальный счёт в бумажном режиме
+	Queue         *ChainQueue              `json:"queue"`           // Очередь для асинхронной обработки
+	QueueLimits   QueueLimits              `json:"queue_limits"`    // Пороги очереди, медленный подписчик отключается
 	Done          bool                     `json:"done"`
-	commandBus    *int
+	DoneReason    string                   `json:"done_reason,omitempty"` // Почему подписчик отключён
+	commandBus    CommandBus
+	commandBusSet bool // Шина команд уже обёрнута prepareCommandBus
+	portfolio     *PortfolioState
+	securities    *Securities
+	tradesHistory AllTradesHistory    // Откуда догружать сделки, пропущенные при переподключении
+	tradesResumed bool                // Была переподписка на сделки, новой сделки после неё ещё не было
+	backfilling   bool                // Пропуск сделок загружается в фоне
+	pendingTrades []AllTradesSlimData // Живые сделки, пришедшие во время загрузки пропуска
+	barTimer      *BarTimerConfig     // Закрытие баров по часам биржи, nil - только сделками
+	clock         Clock               // Часы биржи для таймера баров
+	err           error               // Ошибка опций, AddSubscriber не добавит такого подписчика
 	messageBus    *int
+	barListeners  []func(BarClosedEvent)
+	mu            sync.RWMutex // Защищает Done, DoneReason и загруженность очереди, их меняют воркер и разбор очередей вебсокета
+	handleMu      sync.Mutex   // Обработка событий и чтение данных подписчика снаружи: синхронный подписчик получает события из нескольких соединений пула
+	wake          chan struct{}
+	cancel        context.CancelFunc
+	overloadedAt  time.Time // С какого момента очередь выше QueueLimits.Limit
+	queueWarned   bool
 	wg            sync.WaitGroup
 }
 
-func (s *Subscriber) Init() error { return nil }
+// Init запускает стратегию перед получением живых данных
+func (s *Subscriber) Init() error {
+	if s.Strategy == nil {
+		return nil
+	}
+
+	return s.Strategy.OnStart()
+}
+
+// DeInit останавливает стратегию после отписки
+func (s *Subscriber) DeInit() error {
+	if s.Strategy == nil {
+		return nil
+	}
 
-func (s *Subscriber) DeInit() error { return nil }
+	return s.Strategy.OnStop()
+}
 
 // Options
 
@@ -119,27 +157,54 @@ func WithOrderBookProfile() SubscriberOption {
 	}
 }
 
-// WithIndicator добавляем расчёт индикатора
-func WithIndicator(name string, opts SubscriberOption) SubscriberOption {
+// WithIndicator добавляем расчёт индикатора, значения доступны по имени через DataProcessor.GetIndicatorValue
+func WithIndicator(name string, indicator Indicator) SubscriberOption {
 	return func(s *Subscriber) {
-		// indicator := ...
-		// if !ok "нет такого индикатора"
-		// s.DataProcessor.Indicators = append(s.DataProcessor.Indicators, indicator)
+		s.DataProcessor.AddIndicator(name, indicator)
 	}
 }
 
 // Subscriptions
 
-func WithAllTradesSubscription(frequency int, depth int, includeVirtualTrades bool) SubscriberOption {
+// SubscriptionOption дополнительные параметры подписки
+type SubscriptionOption func(subscription *Subscription)
+
+// WithResponseFormat формат данных подписки, по умолчанию Slim.
+// Подписчик приводит все форматы к Slim структурам, стратегии и DataProcessor формат не видят.
+func WithResponseFormat(format ResponseFormat) SubscriptionOption {
+	return func(subscription *Subscription) {
+		subscription.Format = format
+	}
+}
+
+func newSubscription(opcode Opcode, s *Subscriber, opts []SubscriptionOption) *Subscription {
+	subscription := &Subscription{
+		Exchange:        s.Exchange,
+		Code:            s.Code,
+		InstrumentGroup: s.Board,
+		Opcode:          opcode,
+		Format:          SlimResponseFormat,
+	}
+
+	for _, opt := range opts {
+		opt(subscription)
+	}
+
+	return subscription
+}
+
+func WithAllTradesSubscription(frequency int, depth int, includeVirtualTrades bool, opts ...SubscriptionOption) SubscriberOption {
 	return func(s *Subscriber) {
+		subscription := newSubscription(AllTradesOpcode, s, opts)
+
 		// GUID не меняется за всё время существования подписки
-		guid := fmt.Sprintf("%s-%s-%s-%s-%s",
+		subscription.GUID = GUID(fmt.Sprintf("%s-%s-%s-%s-%s",
 			AllTradesOpcode,
 			s.Exchange,
 			s.Code,
 			s.Board,
-			SlimResponseFormat,
-		)
+			subscription.Format,
+		))
 
 		if depth > 50 {
 			depth = 50
@@ -149,38 +214,30 @@ func WithAllTradesSubscription(frequency int, depth int, includeVirtualTrades bo
 		// Simple — 25 миллисекунд
 		// Slim — 10 миллисекунд
 		// Heavy — 500 миллисекунд
-		if frequency < 10 {
-			frequency = 10
+		subscription.AllTradesParams = AllTradesParams{
+			Frequency:            max(frequency, subscription.Format.minFrequency()),
+			Depth:                depth, // Если указать, то перед актуальными данными придут данные о последних N сделках.
+			IncludeVirtualTrades: includeVirtualTrades,
 		}
 
-		s.Subscriptions[AllTradesOpcode] = &Subscription{
-			GUID:            GUID(guid),
-			Exchange:        s.Exchange,
-			Code:            s.Code,
-			InstrumentGroup: s.Board,
-			Opcode:          AllTradesOpcode,
-
-			AllTradesParams: AllTradesParams{
-				Frequency:            frequency,
-				Depth:                depth, // Если указать, то перед актуальными данными придут данные о последних N сделках.
-				IncludeVirtualTrades: includeVirtualTrades,
-			},
-		}
+		s.Subscriptions[AllTradesOpcode] = subscription
 	}
 }
 
-func WithOrderBookSubscription(frequency int, depth int) SubscriberOption {
+func WithOrderBookSubscription(frequency int, depth int, opts ...SubscriptionOption) SubscriberOption {
 	return func(s *Subscriber) {
+		subscription := newSubscription(OrderBookOpcode, s, opts)
+
 		// GUID не меняется за всё время существования подписки
-		guid := fmt.Sprintf(
+		subscription.GUID = GUID(fmt.Sprintf(
 			"%s-%s-%s-%s-%d-%s",
 			OrderBookOpcode,
 			s.Exchange,
 			s.Code,
 			s.Board,
 			depth,
-			SlimResponseFormat,
-		)
+			subscription.Format,
+		))
 
 		if depth > 50 || depth == 0 {
 			depth = 10
@@ -190,60 +247,71 @@ func WithOrderBookSubscription(frequency int, depth int) SubscriberOption {
 		// Simple — 25 миллисекунд
 		// Slim — 10 миллисекунд
 		// Heavy — 500 миллисекунд
-		if frequency < 10 {
-			frequency = 10
+		subscription.OrderBookParams = OrderBookParams{
+			Depth:     depth,
+			Frequency: max(frequency, subscription.Format.minFrequency()),
 		}
 
-		s.Subscriptions[OrderBookOpcode] = &Subscription{
-			GUID:            GUID(guid),
-			Exchange:        s.Exchange,
-			Code:            s.Code,
-			InstrumentGroup: s.Board,
-			Opcode:          OrderBookOpcode,
-			OrderBookParams: OrderBookParams{
-				Depth:     depth,
-				Frequency: frequency,
-			},
-		}
+		s.Subscriptions[OrderBookOpcode] = subscription
 	}
 }
 
-func WithBarsSubscription(frequency int, from int64, skipHistory, splitAdjust bool) SubscriberOption {
+func WithBarsSubscription(frequency int, from int64, skipHistory, splitAdjust bool, opts ...SubscriptionOption) SubscriberOption {
 	// from := int(time.Now().Add(time.Hour*-24).Unix()))
 	return func(s *Subscriber) {
+		subscription := newSubscription(BarsOpcode, s, opts)
+
 		// GUID не меняется за всё время существования подписки
-		guid := fmt.Sprintf(
+		subscription.GUID = GUID(fmt.Sprintf(
 			"%s-%s-%s-%s-%d-%s",
 			BarsOpcode,
 			s.Exchange,
 			s.Code,
 			s.Board,
 			s.Timeframe,
-			SlimResponseFormat,
-		)
+			subscription.Format,
+		))
 
 		// Минимальное значение параметра Frequency зависит от выбранного формата возвращаемого JSON-объекта:
 		// Simple — 25 миллисекунд
 		// Slim — 10 миллисекунд
 		// Heavy — 500 миллисекунд
-		if frequency < 10 {
-			frequency = 10
+		subscription.BarsParams = BarsParams{
+			Timeframe:   s.Timeframe,
+			From:        from,
+			SkipHistory: skipHistory,
+			SplitAdjust: splitAdjust,
+			Frequency:   max(frequency, subscription.Format.minFrequency()),
 		}
 
-		s.Subscriptions[BarsOpcode] = &Subscription{
-			GUID:            GUID(guid),
-			Exchange:        s.Exchange,
-			Code:            s.Code,
-			InstrumentGroup: s.Board,
-			Opcode:          BarsOpcode,
-			BarsParams: BarsParams{
-				Timeframe:   s.Timeframe,
-				From:        from,
-				SkipHistory: skipHistory,
-				SplitAdjust: splitAdjust,
-				Frequency:   frequency,
-			},
+		s.Subscriptions[BarsOpcode] = subscription
+	}
+}
+
+// WithQuotesSubscription котировки: последняя цена, лучшие bid/ask и дневная сводка без полного стакана
+func WithQuotesSubscription(frequency int, opts ...SubscriptionOption) SubscriberOption {
+	return func(s *Subscriber) {
+		subscription := newSubscription(QuotesOpcode, s, opts)
+
+		// GUID не меняется за всё время существования подписки
+		subscription.GUID = GUID(fmt.Sprintf(
+			"%s-%s-%s-%s-%s",
+			QuotesOpcode,
+			s.Exchange,
+			s.Code,
+			s.Board,
+			subscription.Format,
+		))
+
+		// Минимальное значение параметра Frequency зависит от выбранного формата возвращаемого JSON-объекта:
+		// Simple — 25 миллисекунд
+		// Slim — 10 миллисекунд
+		// Heavy — 500 миллисекунд
+		subscription.QuotesParams = QuotesParams{
+			Frequency: max(frequency, subscription.Format.minFrequency()),
 		}
+
+		s.Subscriptions[QuotesOpcode] = subscription
 	}
 }
 
@@ -259,6 +327,49 @@ func WithStrategy(strategy Strategy) SubscriberOption {
 	}
 }
 
+// WithCommandBus шина команд брокеру, передаётся в стратегию через SetStrategy
+func WithCommandBus(commandBus CommandBus) SubscriberOption {
+	return func(s *Subscriber) {
+		s.commandBus = commandBus
+	}
+}
+
+// WithPaperTrading заявки стратегии исполняются виртуально по живым стакану и ленте сделок
+func WithPaperTrading(config PaperTradingConfig) SubscriberOption {
+	return func(s *Subscriber) {
+		s.Mode = PaperSubscriberMode
+		s.PaperBroker = NewPaperBroker(SystemClock{}, s.Code, config)
+		s.commandBus = s.PaperBroker
+	}
+}
+
+// WithPortfolio даёт стратегии доступ к позициям и рискам счёта, передаётся через SetStrategy
+func WithPortfolio(portfolio *PortfolioState) SubscriberOption {
+	return func(s *Subscriber) {
+		s.portfolio = portfolio
+	}
+}
+
+// WithSecurities проверяет тикер и режим торгов по справочнику, цены заявок округляются до шага цены инструмента
+func WithSecurities(securities *Securities) SubscriberOption {
+	return func(s *Subscriber) {
+		if _, err := securities.Get(s.Exchange, s.Code, s.Board); err != nil {
+			s.err = err
+			return
+		}
+
+		s.securities = securities
+		s.setFootprintPriceStep()
+	}
+}
+
+// WithSubscriberID задаёт ID вместо случайного, например, при восстановлении после рестарта
+func WithSubscriberID(id SubscriberID) SubscriberOption {
+	return func(s *Subscriber) {
+		s.ID = id
+	}
+}
+
 func WithStorage(storage *Storage) SubscriberOption {
 	return func(s *Subscriber) {
 		s.Storage = storage
@@ -270,8 +381,7 @@ func (s *Subscriber) HandleEventSync(event *ChainEvent) error {
 
 	switch event.Opcode {
 	case BarsOpcode:
-		var barsData BarsSlimData
-		err := json.Unmarshal(event.Data, &barsData)
+		barsData, err := decodeBars(s.responseFormat(event.Opcode), event.Data)
 		if err != nil {
 			return err
 		}
@@ -281,41 +391,56 @@ func (s *Subscriber) HandleEventSync(event *ChainEvent) error {
 			return err
 		}
 
-		if s.Ready && s.Strategy != nil {
-			if err := s.Strategy.Handle(BarsOpcode, barsData); err != nil {
-				if errors.Is(err, ErrNoAvailableHandler) {
-					// log.Println(fmt.Errorf("%w for Opcode: %s", err, BarsOpcode))
-					return nil
-				}
+		if err := s.handleClosedBar(TradeBarClose); err != nil {
+			return err
+		}
 
-				return err
-			}
+		if s.Ready && s.Strategy != nil {
+			return s.Strategy.OnBar(barsData)
 		}
 	case AllTradesOpcode:
-		var allTradesData AllTradesSlimData
-		err := json.Unmarshal(event.Data, &allTradesData)
+		allTradesData, err := decodeAllTrades(s.responseFormat(event.Opcode), event.Data)
 		if err != nil {
 			log.Println("cannot unmarshal Alltrades", string(event.Data))
 			return err
 		}
 
-		if err := s.DataProcessor.NewAllTrades(allTradesData); err != nil {
-			return err
+		// Пропуск ищем по первой новой сделке: перед ней может прийти глубина истории подписки
+		if event.Resumed {
+			s.tradesResumed = true
 		}
 
-		if s.Ready && s.Strategy != nil {
-			if err := s.Strategy.Handle(AllTradesOpcode, allTradesData); err != nil {
-				if errors.Is(err, ErrNoAvailableHandler) {
-					// log.Println(fmt.Errorf("%w for Opcode: %s", err, AllTradesOpcode))
-					return nil
-				}
+		// Пока догружается пропуск, живые сделки ждут его в порядке прихода
+		if s.backfilling {
+			s.pendingTrades = append(s.pendingTrades, allTradesData)
+			return nil
+		}
+
+		// Повтор уже обработанной сделки, например, глубина истории при переподписке
+		if s.DataProcessor.seenTrade(allTradesData.ID) {
+			return nil
+		}
 
-				return err
+		if s.tradesResumed {
+			s.tradesResumed = false
+
+			if s.startBackfill(allTradesData) {
+				return nil
 			}
 		}
+
+		return s.handleAllTrades(allTradesData)
+	case AllTradesBackfillOpcode:
+		return s.handleAllTradesBackfill(event)
+	case BarTimerOpcode:
+		// Бары пропуска ещё не построены, их закроет следующий тик
+		if s.backfilling {
+			return nil
+		}
+
+		return s.handleBarTimer(event)
 	case OrderBookOpcode:
-		var orderBookData OrderBookSlimData
-		err := json.Unmarshal(event.Data, &orderBookData)
+		orderBookData, err := decodeOrderBook(s.responseFormat(event.Opcode), event.Data)
 		if err != nil {
 			return err
 		}
@@ -324,15 +449,41 @@ func (s *Subscriber) HandleEventSync(event *ChainEvent) error {
 			return err
 		}
 
+		if s.PaperBroker != nil {
+			s.PaperBroker.OnOrderBook(orderBookData)
+		}
+
 		if s.Ready && s.Strategy != nil {
-			if err := s.Strategy.Handle(OrderBookOpcode, orderBookData); err != nil {
-				if errors.Is(err, ErrNoAvailableHandler) {
-					// log.Println(fmt.Errorf("%w for Opcode: %s", err, OrderBookOpcode))
-					return nil
-				}
+			return s.Strategy.OnOrderBook(orderBookData)
+		}
+	case OrdersOpcode, StopOrdersOpcode, TradesOpcode:
+		orderEvent, err := parseOrderEvent(event)
+		if err != nil {
+			return err
+		}
 
-				return err
-			}
+		if !s.Ready || s.Strategy == nil {
+			return nil
+		}
+
+		switch orderEvent := orderEvent.(type) {
+		case OrderUpdate:
+			return s.Strategy.OnOrderUpdate(orderEvent)
+		case Fill:
+			return s.Strategy.OnFill(orderEvent)
+		}
+	case QuotesOpcode:
+		quotesData, err := decodeQuotes(s.responseFormat(event.Opcode), event.Data)
+		if err != nil {
+			return err
+		}
+
+		if err := s.DataProcessor.NewQuote(quotesData); err != nil {
+			return err
+		}
+
+		if s.Ready && s.Strategy != nil {
+			return s.Strategy.OnQuote(quotesData)
 		}
 	}
 
@@ -340,12 +491,85 @@ func (s *Subscriber) HandleEventSync(event *ChainEvent) error {
 	return nil
 }
 
+// responseFormat формат подписки подписчика по опкоду события
+func (s *Subscriber) responseFormat(opcode Opcode) ResponseFormat {
+	subscription, ok := s.Subscriptions[opcode]
+	if !ok {
+		return SlimResponseFormat
+	}
+
+	return subscription.responseFormat()
+}
+
+// handleClosedBar передаёт слушателям и стратегии бары, закрытые последним событием: сначала основной, затем старшие таймфреймы
+func (s *Subscriber) handleClosedBar(reason BarCloseReason) error {
+	bars := s.DataProcessor.popClosed()
+	closed := s.DataProcessor.popClosedTimeframeBars()
+
+	for _, bar := range bars {
+		s.emitBarClosed(BarClosedEvent{Timeframe: s.DataProcessor.timeframe, Reason: reason, Bar: bar})
+	}
+
+	for _, item := range closed {
+		s.emitBarClosed(BarClosedEvent{Timeframe: item.Timeframe, Reason: reason, Bar: item.Bar})
+	}
+
+	if !s.Ready || s.Strategy == nil {
+		return nil
+	}
+
+	for _, bar := range bars {
+		if err := s.Strategy.OnBarClosed(bar); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range closed {
+		if err := s.Strategy.OnTimeframeBarClosed(item.Timeframe, item.Bar); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *Subscriber) SetStrategy(strategy Strategy) {
 	strategy.SetDataProcessor(s.DataProcessor)
 	strategy.SetStorage(s.Storage)
+	strategy.SetCommandBus(s.commandBus)
+	strategy.SetPortfolio(s.portfolio)
 	s.Strategy = strategy
 }
 
+// prepareCommandBus оборачивает шину команд: цены округляются по справочнику,
+// а события заявок живого подписчика возвращаются к нему. Виртуальные заявки бумажного режима не отслеживаются.
+func (s *Subscriber) prepareCommandBus(router *OrderRouter) {
+	if s.commandBus == nil || s.commandBusSet {
+		return
+	}
+
+	s.commandBusSet = true
+
+	if s.Mode == LiveSubscriberMode {
+		s.commandBus = &trackingCommandBus{
+			CommandBus:   s.commandBus,
+			router:       router,
+			subscriberID: s.ID,
+		}
+	}
+
+	if s.securities != nil {
+		s.commandBus = &securityCommandBus{
+			CommandBus: s.commandBus,
+			securities: s.securities,
+		}
+	}
+
+	if s.Strategy != nil {
+		s.Strategy.SetCommandBus(s.commandBus)
+	}
+}
+
 func (s *Subscriber) SetID(id uuid.UUID) {
 	s.ID = SubscriberID(id)
 }
@@ -357,9 +581,21 @@ func (s *Subscriber) setReady() {
 
 // setDone Выставляет флаг завершения работы
 func (s *Subscriber) setDone() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.Done = true
 }
 
+// fail завершает работу подписчика с указанием причины
+func (s *Subscriber) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Done = true
+	s.DoneReason = err.Error()
+}
+
 //func (s *Subscriber) SetWait() {
 //	s.wg.Add(1)
 //}
@@ -375,6 +611,9 @@ func (s *Subscriber) GetID() SubscriberID {
 }
 
 func (s *Subscriber) IsDone() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	return s.Done
 }
 
@@ -386,15 +625,40 @@ func (s *Subscriber) GetBarsCloak() *BarQueue {
 
 func (s *Subscriber) HandleEvent(event *ChainEvent) error {
 	// Если подписчик завершён, то не обрабатываем новые данные
-	if s.Done {
+	if s.IsDone() {
 		return nil
 	}
 
 	if s.Async {
-		return s.Queue.Enqueue(event)
-	} else {
-		return s.HandleEventSync(event)
+		return s.enqueue(event)
+	}
+
+	// События заявок могут прийти из другого соединения пула
+	s.handleMu.Lock()
+	defer s.handleMu.Unlock()
+
+	return s.HandleEventSync(event)
+}
+
+// handleAllTrades живая сделка: бары, бумажный брокер и стратегия
+func (s *Subscriber) handleAllTrades(data AllTradesSlimData) error {
+	if err := s.DataProcessor.NewAllTrades(data); err != nil {
+		return err
+	}
+
+	if s.PaperBroker != nil {
+		s.PaperBroker.OnTrade(data)
+	}
+
+	if err := s.handleClosedBar(TradeBarClose); err != nil {
+		return err
 	}
+
+	if s.Ready && s.Strategy != nil {
+		return s.Strategy.OnTrade(data)
+	}
+
+	return nil
 }
 
 //func (s *Subscriber) HandleHistoryEvent(event *ChainEvent) error {
@@ -412,15 +676,19 @@ func (s *Subscriber) HandleEvent(event *ChainEvent) error {
 
 func (s *Subscriber) HandleHistoryAlltrades(data AllTradesSlimData) error {
 	// Если подписчик завершён, то не обрабатываем новые данные
-	if s.Done {
+	if s.IsDone() {
 		return nil
 	}
 
-	if s.Async {
-		return s.DataProcessor.NewAllTrades(data)
-	} else {
-		return s.DataProcessor.NewAllTrades(data)
+	if err := s.DataProcessor.NewAllTrades(data); err != nil {
+		return err
 	}
+
+	// История уже в прошлом, закрытые на ней бары стратегии не отдаём
+	s.DataProcessor.popClosed()
+	s.DataProcessor.popClosedTimeframeBars()
+
+	return nil
 }
 
 //func (s Subscriber) Run(ctx context.Context) error {
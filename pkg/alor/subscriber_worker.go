@@ -0,0 +1,191 @@
+package alor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// QueueLimits пороги загруженности очереди асинхронного подписчика.
+// Warning - только пишем в лог, Limit - если очередь держится выше него дольше Grace, подписчик отключается.
+type QueueLimits struct {
+	Warning int           `json:"warning"`
+	Limit   int           `json:"limit"`
+	Grace   time.Duration `json:"grace"`
+}
+
+// DefaultQueueLimits пороги по умолчанию для очереди на 10000 событий
+var DefaultQueueLimits = QueueLimits{
+	Warning: 1000,
+	Limit:   5000,
+	Grace:   5 * time.Second,
+}
+
+// WithQueueLimits задаёт пороги очереди асинхронного подписчика
+func WithQueueLimits(limits QueueLimits) SubscriberOption {
+	return func(s *Subscriber) {
+		s.QueueLimits = limits
+	}
+}
+
+// WithQueuePolicy задаёт политику переполнения очереди асинхронного подписчика.
+// По умолчанию OverflowReject: переполненная очередь отключает подписчика как медленного.
+func WithQueuePolicy(policy OverflowPolicy) SubscriberOption {
+	return func(s *Subscriber) {
+		s.Queue.SetPolicy(policy)
+	}
+}
+
+// Start запускает воркер асинхронного подписчика, который разбирает его очередь, и таймер баров.
+// Они живут до Stop или отмены родительского контекста.
+func (s *Subscriber) Start(ctx context.Context) {
+	timer := s.barTimer != nil && s.DataProcessor.timeBars()
+	if (!s.Async && !timer) || s.cancel != nil {
+		return
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	if s.Async {
+		s.wg.Add(1)
+		go s.run(ctx)
+	}
+
+	if timer {
+		s.wg.Add(1)
+		go s.runBarTimer(ctx)
+	}
+}
+
+// Stop останавливает воркер и таймер и ждёт завершения обработки текущего события и загрузки пропуска сделок
+func (s *Subscriber) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.wg.Wait()
+}
+
+// injectEvent передаёт событие таймера или загрузки так же, как события вебсокета: синхронному подписчику
+// под handleMu, асинхронному через очередь, поэтому бары не меняются параллельно со сделками
+func (s *Subscriber) injectEvent(event *ChainEvent) error {
+	if s.IsDone() {
+		return nil
+	}
+
+	if !s.Async {
+		s.handleMu.Lock()
+		defer s.handleMu.Unlock()
+
+		return s.HandleEventSync(event)
+	}
+
+	// Загруженность очереди проверяет разбор вебсокета, здесь только добавляем событие
+	if err := s.Queue.Enqueue(event); err != nil {
+		return fmt.Errorf("%w: %w", ErrSlowSubscriber, err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// handleQueued событие из очереди под handleMu, чтобы читатели данных подписчика не видели бар посреди обновления
+func (s *Subscriber) handleQueued(event *ChainEvent) error {
+	s.handleMu.Lock()
+	defer s.handleMu.Unlock()
+
+	return s.HandleEventSync(event)
+}
+
+// view даёт read данные подписчика между событиями: пока он работает, бары и профиль не меняются.
+// Ссылки на бары после view не держат, формирующийся бар дальше меняет обработчик.
+func (s *Subscriber) view(read func(p *DataProcessor) error) error {
+	s.handleMu.Lock()
+	defer s.handleMu.Unlock()
+
+	return read(s.DataProcessor)
+}
+
+func (s *Subscriber) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		if ctx.Err() != nil || s.IsDone() {
+			return
+		}
+
+		event, err := s.Queue.Dequeue()
+		if err == nil {
+			if err := s.handleQueued(event); err != nil {
+				s.fail(err)
+				log.Println(s.ID, "error in handle:", err)
+				return
+			}
+
+			continue
+		}
+
+		// Очередь пуста, ждём новых событий
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		}
+	}
+}
+
+// enqueue кладёт событие в очередь воркера и проверяет её загруженность
+func (s *Subscriber) enqueue(event *ChainEvent) error {
+	// Одно событие уходит в очереди нескольких подписчиков, а очередь связывает элементы через Next
+	eventCopy := *event
+	eventCopy.Next = nil
+
+	if err := s.Queue.Enqueue(&eventCopy); err != nil {
+		return fmt.Errorf("%w: %w", ErrSlowSubscriber, err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return s.checkQueue(time.Now())
+}
+
+// checkQueue отключает подписчика, если очередь не разгружается дольше Grace
+func (s *Subscriber) checkQueue(now time.Time) error {
+	length := s.Queue.Length()
+
+	// В очередь пишут читатели нескольких соединений пула
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.QueueLimits.Warning > 0 && length > s.QueueLimits.Warning {
+		if !s.queueWarned {
+			s.queueWarned = true
+			log.Printf("subscriber %s queue is %d events behind", s.ID, length)
+		}
+	} else {
+		s.queueWarned = false
+	}
+
+	if s.QueueLimits.Limit <= 0 || length <= s.QueueLimits.Limit {
+		s.overloadedAt = time.Time{}
+		return nil
+	}
+
+	if s.overloadedAt.IsZero() {
+		s.overloadedAt = now
+	}
+
+	if now.Sub(s.overloadedAt) < s.QueueLimits.Grace {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d events in queue for %s", ErrSlowSubscriber, length, now.Sub(s.overloadedAt))
+}
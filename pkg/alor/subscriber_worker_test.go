@@ -0,0 +1,74 @@
+package alor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingStrategy считает сделки, полученные из воркера
+type countingStrategy struct {
+	BaseStrategy
+	trades atomic.Int64
+}
+
+func (s *countingStrategy) OnTrade(data AllTradesSlimData) error {
+	s.trades.Add(1)
+	return nil
+}
+
+func newTradeEvent(t *testing.T, price float64) *ChainEvent {
+	event, err := NewAllTradesBacktestEvent(AllTradesSlimData{Price: price, Qty: 1})
+	require.NoError(t, err)
+
+	return event.Event
+}
+
+func TestSubscriberWorker(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("worker", MOEXExchange, "SBER", "TQBR", M1TF, true)
+	strategy := &countingStrategy{}
+	subscriber.SetStrategy(strategy)
+	subscriber.Ready = true
+
+	subscriber.Start(context.Background())
+	defer subscriber.Stop()
+
+	// Одно и то же событие уходит в очередь несколько раз, как при раздаче нескольким подписчикам
+	event := newTradeEvent(t, 100)
+	for range 5 {
+		require.NoError(t, subscriber.HandleEvent(event))
+	}
+
+	require.Eventually(t, func() bool {
+		return strategy.trades.Load() == 5
+	}, time.Second, time.Millisecond)
+	require.False(t, subscriber.IsDone())
+}
+
+func TestSubscriberSlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("slow", MOEXExchange, "SBER", "TQBR", M1TF, true,
+		WithQueueLimits(QueueLimits{Limit: 2, Grace: time.Minute}),
+	)
+
+	// Воркер не запущен, очередь только растёт
+	for index := range 3 {
+		require.NoError(t, subscriber.HandleEvent(newTradeEvent(t, 100)), index)
+	}
+
+	now := time.Now()
+	require.NoError(t, subscriber.checkQueue(now))
+	require.ErrorIs(t, subscriber.checkQueue(now.Add(time.Minute)), ErrSlowSubscriber)
+
+	// Очередь разгрузилась - отсчёт сбрасывается
+	_, err := subscriber.Queue.Dequeue()
+	require.NoError(t, err)
+	require.NoError(t, subscriber.checkQueue(now.Add(2*time.Minute)))
+	require.True(t, subscriber.overloadedAt.IsZero())
+}
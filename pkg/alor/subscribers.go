@@ -1,6 +1,7 @@
 package alor
 
 import (
+	"maps"
 	"sync"
 )
 
@@ -44,6 +45,8 @@ func (s *Subscribers) Delete(subscriberID SubscriberID) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Ещё не перенесённого в список подписчика убираем сразу
+	delete(s.toAdd, subscriberID)
 	s.toDelete = append(s.toDelete, subscriberID)
 }
 
@@ -52,6 +55,11 @@ func (s *Subscribers) Get(subscriberID SubscriberID) (*Subscriber, error) {
 	defer s.mu.RUnlock()
 
 	subscriber, ok := s.list[subscriberID]
+	if !ok {
+		// Подписчик ещё не перенесён в список на Rebalancing
+		subscriber, ok = s.toAdd[subscriberID]
+	}
+
 	if !ok {
 		return nil, ErrSubscriberNotFound
 	}
@@ -59,11 +67,12 @@ func (s *Subscribers) Get(subscriberID SubscriberID) (*Subscriber, error) {
 	return subscriber, nil
 }
 
+// All копия списка подписчиков, её можно обходить параллельно с Rebalancing
 func (s *Subscribers) All() map[SubscriberID]*Subscriber {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.list
+	return maps.Clone(s.list)
 }
 
 func (s *Subscribers) Rebalancing() {
@@ -81,4 +90,7 @@ func (s *Subscribers) Rebalancing() {
 	for _, key := range s.toDelete {
 		delete(s.list, key)
 	}
+
+	clear(s.toAdd)
+	s.toDelete = s.toDelete[:0]
 }
@@ -10,9 +10,12 @@ type Subscription struct {
 	Exchange        Exchange        // Биржа MOEX SPBX
 	Code            string          // Тикер (Код финансового инструмента)
 	InstrumentGroup string          // Борд
+	Portfolio       string          // Портфель, только для подписок на данные портфеля
+	Format          ResponseFormat  // Формат данных, пустой - Slim
 	AllTradesParams AllTradesParams // Параметры для обезличенных сделок
 	OrderBookParams OrderBookParams // Параметры для стакана котировок
 	BarsParams      BarsParams      // Параметры для баров
+	QuotesParams    QuotesParams    // Параметры для котировок
 }
 
 type AllTradesParams struct {
@@ -34,6 +37,10 @@ type BarsParams struct {
 	Frequency   int       // Частота (интервал) передачи данных сервером. Сервер вернёт последние данные по запросу за тот временной интервал, который указан в качестве значения параметра. Пример: биржа передаёт данные каждые 2 мс, но, при значении параметра 10 мс, сервер вернёт только последнее значение, отбросив предыдущие.
 }
 
+type QuotesParams struct {
+	Frequency int // Частота (интервал) передачи данных сервером. Сервер вернёт последние данные по запросу за тот временной интервал, который указан в качестве значения параметра.
+}
+
 type OrderSide string
 
 var (
@@ -46,7 +53,7 @@ type Opcode string
 var (
 	OrderBookOpcode     Opcode = "OrderBookGetAndSubscribe"     // Подписка на биржевой стакан
 	BarsOpcode          Opcode = "BarsGetAndSubscribe"          // Подписка на историю цен (свечи)
-	Quotes              Opcode = "QuotesSubscribe"              // Подписка на информацию о котировках
+	QuotesOpcode        Opcode = "QuotesSubscribe"              // Подписка на информацию о котировках
 	InstrumentsOpcode   Opcode = "InstrumentsGetAndSubscribeV2" // Подписка на изменение информации о финансовых инструментах на выбранной бирже
 	AllTradesOpcode     Opcode = "AllTradesGetAndSubscribe"     // Подписка на все сделки
 	PositionsOpcode     Opcode = "PositionsGetAndSubscribeV2"   // Подписка на информацию о текущих позициях по торговым инструментам и деньгам
@@ -107,7 +114,7 @@ type OrderBookRequest struct {
 	Guid      GUID           `json:"guid"`                // Не более 50 символов. Уникальный идентификатор сообщений создаваемой подписки. Все входящие сообщения, соответствующие этой подписке, будут иметь такое значение поля guid
 }
 
-func (ws *Websocket) prepareOrderBooksRequest(token Token, subscription *Subscription) ([]byte, error) {
+func (ws *Websocket) prepareOrderBooksRequest(token *Token, subscription *Subscription) ([]byte, error) {
 	accessToken, err := token.GetAccessToken()
 	if err != nil {
 		return nil, err
@@ -117,7 +124,7 @@ func (ws *Websocket) prepareOrderBooksRequest(token Token, subscription *Subscri
 		Code:      subscription.Code,
 		Depth:     subscription.OrderBookParams.Depth,
 		Exchange:  subscription.Exchange,
-		Format:    SlimResponseFormat,
+		Format:    subscription.responseFormat(),
 		Frequency: subscription.OrderBookParams.Frequency,
 		Opcode:    subscription.Opcode,
 		Guid:      subscription.GUID,
@@ -130,7 +137,7 @@ func (ws *Websocket) prepareOrderBooksRequest(token Token, subscription *Subscri
 type OrderBookSimpleData struct {
 	Bids        []OrderBookSimpleQuote `json:"bids"`
 	Asks        []OrderBookSimpleQuote `json:"asks"`
-	MsTimestamp int                    `json:"ms_timestamp"`
+	MsTimestamp int64                  `json:"ms_timestamp"`
 	Depth       int                    `json:"depth"`
 	Existing    bool                   `json:"existing"`
 	// Snapshot      bool                 `json:"snapshot"`   // Deprecated
@@ -148,25 +155,27 @@ type OrderBookSlimData struct {
 	Asks        []OrderBookSlimQuote `json:"a"`
 	MsTimestamp int64                `json:"t"`
 	Existing    bool                 `json:"h"`
+	Depth       int                  `json:"depth,omitempty"` // Только в Heavy
 }
 
 type OrderBookSlimQuote struct {
 	Price  float64 `json:"p"`
 	Volume int64   `json:"v"`
-	Yield  int64   `json:"y"`
+	Yield  float64 `json:"y"` // Доходность облигаций дробная
 }
 
 type OrderBookHeavyData struct {
-	Bids        []OrderBookSimpleQuote `json:"bids"`
-	Asks        []OrderBookSimpleQuote `json:"asks"`
-	MsTimestamp int                    `json:"msTimestamp"`
-	Depth       int                    `json:"depth"`
-	Existing    bool                   `json:"existing"`
+	Bids        []OrderBookHeavyQuote `json:"bids"`
+	Asks        []OrderBookHeavyQuote `json:"asks"`
+	MsTimestamp int64                 `json:"msTimestamp"`
+	Depth       int                   `json:"depth"`
+	Existing    bool                  `json:"existing"`
 }
 
 type OrderBookHeavyQuote struct {
 	Price  float64 `json:"price"`
 	Volume int64   `json:"volume"`
+	Yield  float64 `json:"yield"`
 }
 
 type AllTradesRequest struct {
@@ -182,7 +191,7 @@ type AllTradesRequest struct {
 	Token                string         `json:"token"`                // Access Токен для авторизации запроса
 }
 
-func (ws *Websocket) prepareAllTradesRequest(token Token, subscription *Subscription) ([]byte, error) {
+func (ws *Websocket) prepareAllTradesRequest(token *Token, subscription *Subscription) ([]byte, error) {
 	accessToken, err := token.GetAccessToken()
 	if err != nil {
 		return nil, err
@@ -194,7 +203,7 @@ func (ws *Websocket) prepareAllTradesRequest(token Token, subscription *Subscrip
 		Guid:      subscription.GUID,
 		Code:      subscription.Code,
 		Depth:     subscription.AllTradesParams.Depth,
-		Format:    SlimResponseFormat,
+		Format:    subscription.responseFormat(),
 		Frequency: subscription.AllTradesParams.Frequency,
 		Token:     accessToken,
 	}
@@ -204,14 +213,14 @@ func (ws *Websocket) prepareAllTradesRequest(token Token, subscription *Subscrip
 
 type AllTradesSimpleData struct {
 	ID        int64     `json:"id"`
-	Orderno   int       `json:"orderno"`
+	Orderno   int64     `json:"orderno"`
 	Symbol    string    `json:"symbol"`
 	Board     string    `json:"board"`
 	Qty       int64     `json:"qty"`
 	Price     float64   `json:"price"`
 	Time      string    `json:"time"`
 	Timestamp int64     `json:"timestamp"`
-	Oi        int       `json:"oi"`
+	Oi        int64     `json:"oi"`
 	Existing  bool      `json:"existing"`
 	Side      OrderSide `json:"side"`
 }
@@ -227,17 +236,20 @@ type AllTradesSlimData struct {
 	Oi        int64     `json:"oi"`
 	Existing  bool      `json:"h"`
 	Side      OrderSide `json:"s"`
+	OrderNo   int64     `json:"orderNo,omitempty"` // Номер заявки, только в Simple и Heavy
+	Time      string    `json:"time,omitempty"`    // Время сделки строкой, только в Simple и Heavy
 }
 
 type AllTradesHeavyData struct {
 	ID        int64     `json:"id"`
+	OrderNo   int64     `json:"orderNo"`
 	Symbol    string    `json:"symbol"`
 	Board     string    `json:"board"`
 	Qty       int64     `json:"qty"`
 	Price     float64   `json:"price"`
 	Time      string    `json:"time"`
 	Timestamp int64     `json:"timestamp"`
-	Oi        int       `json:"oi"`
+	Oi        int64     `json:"oi"`
 	Existing  bool      `json:"existing"`
 	Side      OrderSide `json:"side"`
 }
@@ -257,7 +269,7 @@ type BarsRequest struct {
 	Token           string         `json:"token"`               // Access Токен для авторизации запроса
 }
 
-func (ws *Websocket) prepareBarsRequest(token Token, subscription *Subscription) ([]byte, error) {
+func (ws *Websocket) prepareBarsRequest(token *Token, subscription *Subscription) ([]byte, error) {
 	accessToken, err := token.GetAccessToken()
 	if err != nil {
 		return nil, err
@@ -272,7 +284,7 @@ func (ws *Websocket) prepareBarsRequest(token Token, subscription *Subscription)
 		SplitAdjust:     subscription.BarsParams.SplitAdjust,
 		Exchange:        subscription.Exchange,
 		InstrumentGroup: subscription.InstrumentGroup,
-		Format:          SlimResponseFormat,
+		Format:          subscription.responseFormat(),
 		Frequency:       subscription.BarsParams.Frequency,
 		Guid:            subscription.GUID,
 		Token:           accessToken,
@@ -282,12 +294,12 @@ func (ws *Websocket) prepareBarsRequest(token Token, subscription *Subscription)
 }
 
 type BarsSimpleData struct {
-	Time   string  `json:"time"`
+	Time   int64   `json:"time"`
 	Close  float64 `json:"close"`
 	Open   float64 `json:"open"`
 	High   float64 `json:"high"`
 	Low    float64 `json:"low"`
-	Volume int     `json:"volume"`
+	Volume int64   `json:"volume"`
 }
 
 type BarsSlimData struct {
@@ -300,12 +312,139 @@ type BarsSlimData struct {
 }
 
 type BarsHeavyData struct {
-	Time   string  `json:"time"`
+	Time   int64   `json:"time"`
 	Close  float64 `json:"close"`
 	Open   float64 `json:"open"`
 	High   float64 `json:"high"`
 	Low    float64 `json:"low"`
-	Volume int     `json:"volume"`
+	Volume int64   `json:"volume"`
+}
+
+type QuotesRequest struct {
+	Opcode          Opcode         `json:"opcode"`              // Код выполняемой операции
+	Code            string         `json:"code"`                // Код финансового инструмента (Тикер)
+	Exchange        Exchange       `json:"exchange,omitempty"`  // Биржа
+	InstrumentGroup string         `json:"instrumentGroup"`     // Код режима торгов (Борд). Для Биржи СПБ всегда SPBX
+	Format          ResponseFormat `json:"format"`              // Формат представления возвращаемых данных
+	Frequency       int            `json:"frequency,omitempty"` // Частота (интервал) передачи данных сервером
+	Guid            GUID           `json:"guid"`                // Не более 50 символов. Уникальный идентификатор сообщений создаваемой подписки
+	Token           string         `json:"token"`               // Access Токен для авторизации запроса
+}
+
+func (ws *Websocket) prepareQuotesRequest(token *Token, subscription *Subscription) ([]byte, error) {
+	accessToken, err := token.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	request := QuotesRequest{
+		Opcode:          subscription.Opcode,
+		Code:            subscription.Code,
+		Exchange:        subscription.Exchange,
+		InstrumentGroup: subscription.InstrumentGroup,
+		Format:          subscription.responseFormat(),
+		Frequency:       subscription.QuotesParams.Frequency,
+		Guid:            subscription.GUID,
+		Token:           accessToken,
+	}
+
+	return json.Marshal(request)
+}
+
+type QuotesSimpleData struct {
+	Symbol             string  `json:"symbol"`
+	Exchange           string  `json:"exchange"`
+	Description        string  `json:"description"`
+	PrevClosePrice     float64 `json:"prev_close_price"`
+	LastPrice          float64 `json:"last_price"`
+	LastPriceTimestamp int64   `json:"last_price_timestamp"`
+	HighPrice          float64 `json:"high_price"`
+	LowPrice           float64 `json:"low_price"`
+	OpenPrice          float64 `json:"open_price"`
+	AccruedInt         float64 `json:"accruedInt"`
+	Volume             int64   `json:"volume"`
+	OpenInterest       int64   `json:"open_interest"`
+	Ask                float64 `json:"ask"`
+	Bid                float64 `json:"bid"`
+	AskVol             int64   `json:"ask_vol"`
+	BidVol             int64   `json:"bid_vol"`
+	TotalAskVol        int64   `json:"total_ask_vol"`
+	TotalBidVol        int64   `json:"total_bid_vol"`
+	ObMsTimestamp      int64   `json:"ob_ms_timestamp"`
+	Yield              float64 `json:"yield"`
+	LotSize            float64 `json:"lotsize"`
+	LotValue           float64 `json:"lotvalue"`
+	FaceValue          float64 `json:"facevalue"`
+	Type               string  `json:"type"`
+	Change             float64 `json:"change"`
+	ChangePercent      float64 `json:"change_percent"`
+}
+
+type QuotesSlimData struct {
+	Symbol             string  `json:"sym"`
+	Exchange           string  `json:"ex"`
+	Description        string  `json:"desc"`
+	PrevClosePrice     float64 `json:"pcp"`
+	LastPrice          float64 `json:"lp"`
+	LastPriceTimestamp int64   `json:"lpt"` // Секунды
+	HighPrice          float64 `json:"h"`
+	LowPrice           float64 `json:"l"`
+	OpenPrice          float64 `json:"o"`
+	AccruedInt         float64 `json:"ai"`
+	Volume             int64   `json:"v"`
+	OpenInterest       int64   `json:"oi"`
+	Ask                float64 `json:"a"`
+	Bid                float64 `json:"b"`
+	AskVol             int64   `json:"av"`
+	BidVol             int64   `json:"bv"`
+	TotalAskVol        int64   `json:"tav"`
+	TotalBidVol        int64   `json:"tbv"`
+	ObMsTimestamp      int64   `json:"obt"` // Миллисекунды
+	Yield              float64 `json:"y"`
+	LotSize            float64 `json:"lot"`
+	LotValue           float64 `json:"lotv"`
+	FaceValue          float64 `json:"fv"`
+	Type               string  `json:"t"`
+	Change             float64 `json:"ch"`
+	ChangePercent      float64 `json:"chp"`
+}
+
+type QuotesHeavyData struct {
+	Symbol             string  `json:"symbol"`
+	Exchange           string  `json:"exchange"`
+	Description        string  `json:"description"`
+	PrevClosePrice     float64 `json:"prevClosePrice"`
+	LastPrice          float64 `json:"lastPrice"`
+	LastPriceTimestamp int64   `json:"lastPriceTimestamp"`
+	HighPrice          float64 `json:"highPrice"`
+	LowPrice           float64 `json:"lowPrice"`
+	OpenPrice          float64 `json:"openPrice"`
+	AccruedInt         float64 `json:"accruedInt"`
+	Volume             int64   `json:"volume"`
+	OpenInterest       int64   `json:"openInterest"`
+	Ask                float64 `json:"ask"`
+	Bid                float64 `json:"bid"`
+	AskVol             int64   `json:"askVol"`
+	BidVol             int64   `json:"bidVol"`
+	TotalAskVol        int64   `json:"totalAskVol"`
+	TotalBidVol        int64   `json:"totalBidVol"`
+	ObMsTimestamp      int64   `json:"obMsTimestamp"`
+	Yield              float64 `json:"yield"`
+	LotSize            float64 `json:"lotSize"`
+	LotValue           float64 `json:"lotValue"`
+	FaceValue          float64 `json:"faceValue"`
+	Type               string  `json:"type"`
+	Change             float64 `json:"change"`
+	ChangePercent      float64 `json:"changePercent"`
+}
+
+// Spread разница между лучшими ценами продажи и покупки, 0 если одной из сторон нет
+func (q QuotesSlimData) Spread() float64 {
+	if q.Ask == 0 || q.Bid == 0 {
+		return 0
+	}
+
+	return q.Ask - q.Bid
 }
 
 type UnsubscribeRequest struct {
@@ -320,10 +459,10 @@ func (c *Client) Unsubscribe(subscriberID SubscriberID, guid GUID) error {
 		return err
 	}
 
-	return c.Websocket.Unsubscribe(token, subscriberID, guid)
+	return c.Pool.Unsubscribe(token, subscriberID, guid)
 }
 
 /*
 Остальные подписки реализовать по подобию
-Trades, Quotes, ......
+Trades, ......
 */
@@ -1 +1,124 @@
 package alor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// quotesStrategy запоминает последнюю котировку
+type quotesStrategy struct {
+	BaseStrategy
+	last QuotesSlimData
+}
+
+func (s *quotesStrategy) OnQuote(data QuotesSlimData) error {
+	s.last = data
+	return nil
+}
+
+func TestQuotesSubscription(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("quotes", MOEXExchange, "SBER", "TQBR", M1TF, false, WithQuotesSubscription(0))
+	strategy := &quotesStrategy{}
+	subscriber.SetStrategy(strategy)
+	subscriber.Ready = true
+
+	subscription := subscriber.Subscriptions[QuotesOpcode]
+	require.Equal(t, GUID("QuotesSubscribe-MOEX-SBER-TQBR-Slim"), subscription.GUID)
+
+	ws := NewWebsocket("")
+	requestBytes, err := ws.prepareRequest(&Token{Access: "access_token"}, subscription)
+	require.NoError(t, err)
+
+	var request QuotesRequest
+	require.NoError(t, json.Unmarshal(requestBytes, &request))
+	require.Equal(t, QuotesRequest{
+		Opcode:          QuotesOpcode,
+		Code:            "SBER",
+		Exchange:        MOEXExchange,
+		InstrumentGroup: "TQBR",
+		Format:          SlimResponseFormat,
+		Frequency:       10,
+		Guid:            subscription.GUID,
+		Token:           "access_token",
+	}, request)
+
+	_, err = subscriber.DataProcessor.GetLastQuote()
+	require.ErrorIs(t, err, ErrNoQuote)
+
+	data := `{"sym":"SBER","lp":264.96,"lpt":1696494610,"a":264.97,"b":264.96,"oi":0,"ch":2.48,"chp":0.94}`
+	require.NoError(t, subscriber.HandleEventSync(&ChainEvent{Type: DataType, Opcode: QuotesOpcode, Data: json.RawMessage(data)}))
+
+	quote, err := subscriber.DataProcessor.GetLastQuote()
+	require.NoError(t, err)
+	require.Equal(t, 264.96, quote.LastPrice)
+	require.InDelta(t, 0.01, quote.Spread(), 1e-9)
+	require.Equal(t, 2.48, quote.Change)
+	require.Equal(t, quote, strategy.last)
+}
+
+func TestResponseFormats(t *testing.T) {
+	t.Parallel()
+
+	subscriber := NewSubscriber("formats", MOEXExchange, "SBER", "TQBR", M1TF, false,
+		WithBarsSubscription(0, 0, false, false, WithResponseFormat(HeavyResponseFormat)),
+		WithOrderBookSubscription(0, 10, WithResponseFormat(SimpleResponseFormat)),
+		WithQuotesSubscription(0, WithResponseFormat(HeavyResponseFormat)),
+	)
+	strategy := &quotesStrategy{}
+	subscriber.SetStrategy(strategy)
+	subscriber.Ready = true
+
+	bars := subscriber.Subscriptions[BarsOpcode]
+	require.Equal(t, GUID("BarsGetAndSubscribe-MOEX-SBER-TQBR-60-Heavy"), bars.GUID)
+	require.Equal(t, 500, bars.BarsParams.Frequency)
+
+	ws := NewWebsocket("")
+	requestBytes, err := ws.prepareRequest(&Token{Access: "access_token"}, bars)
+	require.NoError(t, err)
+
+	var request BarsRequest
+	require.NoError(t, json.Unmarshal(requestBytes, &request))
+	require.Equal(t, HeavyResponseFormat, request.Format)
+
+	// Все форматы приходят в стратегию и DataProcessor одинаковыми Slim структурами
+	barData := `{"time":1696494600,"open":264.5,"high":265,"low":264.1,"close":264.9,"volume":1200}`
+	require.NoError(t, subscriber.HandleEventSync(&ChainEvent{Type: DataType, Opcode: BarsOpcode, Data: json.RawMessage(barData)}))
+
+	orderBookData := `{"bids":[{"price":264.9,"volume":10}],"asks":[{"price":265,"volume":5}],"ms_timestamp":1696494610000,"existing":false}`
+	require.NoError(t, subscriber.HandleEventSync(&ChainEvent{Type: DataType, Opcode: OrderBookOpcode, Data: json.RawMessage(orderBookData)}))
+
+	quoteData := `{"symbol":"SBER","lastPrice":264.96,"lastPriceTimestamp":1696494610,"ask":264.97,"bid":264.96,"changePercent":0.94}`
+	require.NoError(t, subscriber.HandleEventSync(&ChainEvent{Type: DataType, Opcode: QuotesOpcode, Data: json.RawMessage(quoteData)}))
+
+	require.Equal(t, QuotesSlimData{Symbol: "SBER", LastPrice: 264.96, LastPriceTimestamp: 1696494610, Ask: 264.97, Bid: 264.96, ChangePercent: 0.94}, strategy.last)
+
+	orderBook, err := decodeOrderBook(SimpleResponseFormat, json.RawMessage(orderBookData))
+	require.NoError(t, err)
+	require.Equal(t, OrderBookSlimData{
+		Bids:        []OrderBookSlimQuote{{Price: 264.9, Volume: 10}},
+		Asks:        []OrderBookSlimQuote{{Price: 265, Volume: 5}},
+		MsTimestamp: 1696494610000,
+	}, orderBook)
+
+	bar, err := decodeBars(HeavyResponseFormat, json.RawMessage(barData))
+	require.NoError(t, err)
+	require.Equal(t, BarsSlimData{Time: 1696494600, Open: 264.5, High: 265, Low: 264.1, Close: 264.9, Volume: 1200}, bar)
+
+	// Поля Heavy, которых нет в Slim, доходят до стратегии, доходность облигаций дробная
+	trade, err := decodeAllTrades(HeavyResponseFormat, json.RawMessage(`{"id":7,"orderNo":123456,"symbol":"SBER","qty":3,"price":265,"time":"2023-10-05T08:30:10Z","timestamp":1696494610000,"side":"buy"}`))
+	require.NoError(t, err)
+	require.Equal(t, int64(123456), trade.OrderNo)
+	require.Equal(t, "2023-10-05T08:30:10Z", trade.Time)
+
+	bondBook, err := decodeOrderBook(HeavyResponseFormat, json.RawMessage(`{"bids":[{"price":98.5,"volume":10,"yield":12.37}],"asks":[],"msTimestamp":1696494610000,"depth":20}`))
+	require.NoError(t, err)
+	require.Equal(t, 12.37, bondBook.Bids[0].Yield)
+	require.Equal(t, 20, bondBook.Depth)
+
+	_, err = decodeBars("Fat", json.RawMessage(barData))
+	require.Error(t, err)
+}
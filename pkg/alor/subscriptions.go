@@ -2,7 +2,9 @@ package alor
 
 import (
 	"errors"
+	"maps"
 	"sync"
+	"time"
 )
 
 func NewSubscriptions() Subscriptions {
@@ -18,8 +20,10 @@ type (
 
 	SubscriptionContainer struct {
 		Subscription *Subscription
-		Active       bool
+		State        SubscriptionState
+		Error        string // Ответ брокера, отклонившего подписку
 		Items        map[SubscriberID]bool
+		LastEvent    time.Time // Последнее событие или запрос подписки, по нему ищем зависшие подписки
 		//Subscriber SubscriberID
 		// Несколько подписок - один подписчик (1кМ)
 		// Вместо контейнера всё в подписке?
@@ -40,28 +44,63 @@ func (s *Subscriptions) Add(subscriberID SubscriberID, subscription *Subscriptio
 
 	container := SubscriptionContainer{
 		Subscription: subscription,
-		Active:       false,
+		State:        PendingSubscription,
 		Items: map[SubscriberID]bool{
 			subscriberID: true,
 		},
+		LastEvent: time.Now(),
 	}
 
 	s.toAdd[GUID(subscription.GUID)] = container
 }
 
 func (s *Subscriptions) SetActive(guid GUID) {
+	s.SetState(guid, ActiveSubscription, "")
+}
+
+// SetState меняет состояние подписки, в том числе ещё не перенесённой в список на Rebalancing
+func (s *Subscriptions) SetState(guid GUID, state SubscriptionState, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, list := range []map[GUID]SubscriptionContainer{s.list, s.toAdd} {
+		container, ok := list[guid]
+		if !ok {
+			continue
+		}
+
+		container.State = state
+		container.Error = reason
+		list[guid] = container
+	}
+}
+
+// Touch отмечает, что по подписке пришли данные
+func (s *Subscriptions) Touch(guid GUID, now time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	subscriptionContainer := s.list[guid]
-	subscriptionContainer.Active = true
-	s.list[guid] = subscriptionContainer
+	container, ok := s.list[guid]
+	if !ok {
+		return
+	}
+
+	container.LastEvent = now
+	s.list[guid] = container
 }
 
 func (s *Subscriptions) Delete(subscriberID SubscriberID, guid GUID) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Подписка ещё не перенесена в список
+	if container, ok := s.toAdd[guid]; ok {
+		delete(container.Items, subscriberID)
+		if len(container.Items) == 0 {
+			delete(s.toAdd, guid)
+		}
+	}
+
 	// Получаем контейнер подписки
 	_, ok := s.list[guid]
 	if !ok {
@@ -91,11 +130,12 @@ func (s *Subscriptions) Get(guid GUID) (SubscriptionContainer, error) {
 	return subscriptionContainer, nil
 }
 
+// All копия списка подписок, её можно обходить параллельно с Rebalancing
 func (s *Subscriptions) All() (map[GUID]SubscriptionContainer, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.list, nil
+	return maps.Clone(s.list), nil
 }
 
 func (s *Subscriptions) Rebalancing() {
@@ -126,4 +166,7 @@ func (s *Subscriptions) Rebalancing() {
 			delete(s.list, guid)
 		}
 	}
+
+	clear(s.toAdd)
+	clear(s.toDelete)
 }
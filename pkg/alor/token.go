@@ -1,19 +1,26 @@
 package alor
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
-func NewToken(refreshToken string, refreshExpiration time.Time) Token {
-	return Token{
+func NewToken(refreshToken string, refreshExpiration time.Time) *Token {
+	return &Token{
 		Refresh:           refreshToken,
 		RefreshExpiration: refreshExpiration,
 	}
 }
 
+// Token access и refresh токены брокера. Access обновляется фоновым циклом клиента,
+// поэтому все поля читаются и пишутся только через методы.
 type Token struct {
 	Access            string
 	Data              TokenData
 	Refresh           string
 	RefreshExpiration time.Time
+	listeners         []func()
+	mu                sync.RWMutex
 }
 
 type TokenData struct {
@@ -31,31 +38,89 @@ type TokenData struct {
 	Iss        string    `json:"iss"`        // (Issuer): Издатель value: Alor.Identity
 }
 
+// GetAccessToken возвращает действующий access токен или ошибку, если он ещё не получен или истёк
 func (t *Token) GetAccessToken() (string, error) {
-	// TODO: check expiration, LOCK!!!
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.Access == "" {
+		return "", ErrAccessTokenEmpty
+	}
+
+	if !t.Data.Exp.IsZero() && !time.Now().Before(t.Data.Exp) {
+		return "", ErrAccessTokenExpired
+	}
 
 	return t.Access, nil
 }
 
+func (t *Token) GetRefreshToken() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.Refresh
+}
+
+func (t *Token) GetData() TokenData {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.Data
+}
+
+// AccessExpiresIn сколько осталось жить access токену, 0 - если время истечения неизвестно
+func (t *Token) AccessExpiresIn() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.Data.Exp.IsZero() {
+		return 0
+	}
+
+	return time.Until(t.Data.Exp)
+}
+
+// IsExpired истёк ли refresh токен
 func (t *Token) IsExpired() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	return t.RefreshExpiration.Before(time.Now())
 }
 
+// HoursToExpiration сколько часов осталось жить refresh токену
 func (t *Token) HoursToExpiration() int {
-	dur := time.Since(t.RefreshExpiration)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 
-	return int(dur.Hours())
+	return int(time.Until(t.RefreshExpiration).Hours())
 }
 
-func (t *Token) SetAccessToken(newAccessToken string) {
+// SetAccessToken сохраняет новый access токен с его данными и оповещает подписчиков обновления
+func (t *Token) SetAccessToken(newAccessToken string, tokenData TokenData) {
+	t.mu.Lock()
 	t.Access = newAccessToken
+	t.Data = tokenData
+	listeners := t.listeners
+	t.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
 }
 
 func (t *Token) SetRefreshToken(newRefreshToken string, expiration time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	t.Refresh = newRefreshToken
 	t.RefreshExpiration = expiration
 }
 
-func (t *Token) SetData(tokenData TokenData) {
-	t.Data = tokenData
+// OnRefresh регистрирует колбэк, вызываемый после каждого обновления access токена
+func (t *Token) OnRefresh(listener func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.listeners = append(t.listeners, listener)
 }
@@ -0,0 +1,98 @@
+package alor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	refreshTokenTimeout       = 2 * time.Second
+	defaultRefreshBefore      = time.Minute
+	defaultRefreshWarning     = 7 * 24 * time.Hour
+	refreshTokenRetryDelay    = time.Second
+	refreshTokenMaxRetryDelay = 30 * time.Second
+)
+
+// KeepTokenFresh обновляет access токен заранее, до его истечения, пока не отменён контекст.
+// Неудачное обновление повторяется с экспоненциальной задержкой, отозванный refresh токен останавливает цикл.
+func (c *Client) KeepTokenFresh(ctx context.Context) {
+	log.Println("running token refresh loop")
+	defer log.Println("token refresh loop closed")
+
+	refreshBefore := c.Config.AccessTokenRefreshBefore
+	if refreshBefore <= 0 {
+		refreshBefore = defaultRefreshBefore
+	}
+
+	for {
+		c.warnRefreshExpiration()
+
+		// Не чаще раза в секунду, даже если токен живёт меньше refreshBefore
+		timer := time.NewTimer(max(c.Token.AccessExpiresIn()-refreshBefore, refreshTokenRetryDelay))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !c.refreshTokenWithRetry(ctx) {
+			return
+		}
+	}
+}
+
+// refreshTokenWithRetry возвращает false, если контекст отменён до успешного обновления или брокер отверг refresh токен
+func (c *Client) refreshTokenWithRetry(ctx context.Context) bool {
+	retryDelay := refreshTokenRetryDelay
+
+	for {
+		refreshCtx, cancel := context.WithTimeout(ctx, refreshTokenTimeout)
+		err := c.refreshToken(refreshCtx)
+		cancel()
+
+		if err == nil {
+			log.Printf("access token refreshed, expires at %s", c.Token.GetData().Exp)
+			return true
+		}
+
+		// Отозванный refresh токен повторами не оживить, нужен новый
+		if errors.Is(err, ErrRefreshTokenRejected) {
+			log.Printf("ERROR: access token refresh stopped: %v, issue a new refresh token and restart", err)
+			return false
+		}
+
+		log.Printf("access token refresh failed: %v, retry in %v", err, retryDelay)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(retryDelay):
+		}
+
+		retryDelay = min(retryDelay*2, refreshTokenMaxRetryDelay)
+	}
+}
+
+// warnRefreshExpiration предупреждает, что refresh токен скоро придётся выпускать заново вручную
+func (c *Client) warnRefreshExpiration() {
+	c.Token.mu.RLock()
+	expiration := c.Token.RefreshExpiration
+	c.Token.mu.RUnlock()
+
+	if expiration.IsZero() {
+		return
+	}
+
+	warning := c.Config.RefreshTokenWarning
+	if warning <= 0 {
+		warning = defaultRefreshWarning
+	}
+
+	if left := time.Until(expiration); left < warning {
+		log.Printf("WARNING: refresh token expires at %s (%d hours left)", expiration.Format(time.RFC3339), c.Token.HoursToExpiration())
+	}
+}
@@ -0,0 +1,107 @@
+package alor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAccessToken(t *testing.T, exp time.Time) string {
+	t.Helper()
+
+	access, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"ent":        "client",
+		"clientid":   "115177",
+		"portfolios": "D38572",
+		"agreements": "38572",
+		"ein":        "37817",
+		"scope":      "OrdersRead OrdersCreate",
+		"iss":        "Alor.Identity",
+		"aud":        "Client",
+		"sub":        "P000000",
+		"azp":        "azp",
+		"exp":        exp.Unix(),
+		"iat":        time.Now().Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	return access
+}
+
+func TestRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	exp := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+	access := newTestAccessToken(t, exp)
+
+	var gotRefresh string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRefresh = r.URL.Query().Get("token")
+		_, _ = w.Write([]byte(`{"AccessToken":"` + access + `"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &Client{
+		Hosts:  Hosts{Authorization: server.URL},
+		Token:  NewToken("refresh_token", time.Now().AddDate(1, 0, 0)),
+		Client: server.Client(),
+	}
+
+	var refreshed atomic.Int64
+	client.Token.OnRefresh(func() { refreshed.Add(1) })
+
+	_, err := client.Token.GetAccessToken()
+	require.ErrorIs(t, err, ErrAccessTokenEmpty)
+
+	require.NoError(t, client.RefreshToken())
+	require.Equal(t, "refresh_token", gotRefresh)
+	require.Equal(t, int64(1), refreshed.Load())
+	require.True(t, exp.Equal(client.Token.GetData().Exp))
+	require.Equal(t, []string{"D38572"}, client.Token.GetData().Portfolios)
+	require.Equal(t, []string{"OrdersRead", "OrdersCreate"}, client.Token.GetData().Scope)
+	require.InDelta(t, 30*time.Minute, client.Token.AccessExpiresIn(), float64(5*time.Second))
+
+	got, err := client.Token.GetAccessToken()
+	require.NoError(t, err)
+	require.Equal(t, access, got)
+}
+
+func TestAccessTokenExpired(t *testing.T) {
+	t.Parallel()
+
+	token := NewToken("refresh_token", time.Now().AddDate(1, 0, 0))
+	token.SetAccessToken("access_token", TokenData{Exp: time.Now().Add(-time.Second)})
+
+	_, err := token.GetAccessToken()
+	require.ErrorIs(t, err, ErrAccessTokenExpired)
+	require.Negative(t, token.AccessExpiresIn())
+}
+
+func TestRefreshTokenRejected(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &Client{
+		Hosts:  Hosts{Authorization: server.URL},
+		Token:  NewToken("revoked", time.Now().AddDate(1, 0, 0)),
+		Client: server.Client(),
+	}
+
+	require.ErrorIs(t, client.RefreshToken(), ErrRefreshTokenRejected)
+
+	// Отозванный токен не повторяется
+	require.False(t, client.refreshTokenWithRetry(context.Background()))
+	require.Equal(t, int64(2), requests.Load())
+}
@@ -0,0 +1,129 @@
+package alor
+
+import "time"
+
+// BacktestFill исполнение виртуальной заявки
+type BacktestFill struct {
+	OrderID    OrderID   `json:"order_id"`
+	Time       time.Time `json:"time"`
+	Side       OrderSide `json:"side"`
+	Quantity   int64     `json:"quantity"`
+	Price      float64   `json:"price"`
+	Commission float64   `json:"commission"`
+	Comment    string    `json:"comment"`
+}
+
+// BacktestTrade закрытая (полностью или частично) виртуальная позиция
+type BacktestTrade struct {
+	Side       OrderSide `json:"side"` // Направление позиции
+	Quantity   int64     `json:"quantity"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitPrice  float64   `json:"exit_price"`
+	OpenedAt   time.Time `json:"opened_at"`
+	ClosedAt   time.Time `json:"closed_at"`
+	PnL        float64   `json:"pnl"` // Без учёта комиссии
+}
+
+// virtualAccount деньги и позиция по одному инструменту для бэктеста и бумажной торговли
+type virtualAccount struct {
+	initialCash    float64
+	commissionRate float64 // Доля от оборота
+	lotSize        float64
+	cash           float64
+	lastPrice      float64
+	position       int64 // Позиция в лотах, меньше нуля - шорт
+	avgPrice       float64
+	openedAt       time.Time
+	realizedPnL    float64
+	commission     float64
+	fills          []BacktestFill
+	trades         []BacktestTrade
+}
+
+func newVirtualAccount(initialCash, commissionRate, lotSize float64) virtualAccount {
+	if lotSize == 0 {
+		lotSize = 1
+	}
+
+	return virtualAccount{
+		initialCash:    initialCash,
+		commissionRate: commissionRate,
+		lotSize:        lotSize,
+		cash:           initialCash,
+	}
+}
+
+func (a *virtualAccount) fill(orderID OrderID, side OrderSide, quantity int64, price float64, comment string, now time.Time) {
+	value := price * float64(quantity) * a.lotSize
+	commission := value * a.commissionRate
+
+	direction := int64(1)
+	if side == SellSide {
+		direction = -1
+	}
+
+	a.cash -= value*float64(direction) + commission
+	a.commission += commission
+	a.fills = append(a.fills, BacktestFill{
+		OrderID:    orderID,
+		Time:       now,
+		Side:       side,
+		Quantity:   quantity,
+		Price:      price,
+		Commission: commission,
+		Comment:    comment,
+	})
+
+	// Закрываем встречную позицию
+	if a.position*direction < 0 {
+		closed := min(quantity, abs(a.position))
+		positionSide := BuySide
+		if a.position < 0 {
+			positionSide = SellSide
+		}
+
+		pnl := (price - a.avgPrice) * float64(closed) * a.lotSize * float64(-direction)
+
+		a.realizedPnL += pnl
+		a.trades = append(a.trades, BacktestTrade{
+			Side:       positionSide,
+			Quantity:   closed,
+			EntryPrice: a.avgPrice,
+			ExitPrice:  price,
+			OpenedAt:   a.openedAt,
+			ClosedAt:   now,
+			PnL:        pnl,
+		})
+
+		a.position += closed * direction
+		quantity -= closed
+
+		if a.position == 0 {
+			a.avgPrice = 0
+		}
+	}
+
+	// Остаток открывает или наращивает позицию
+	if quantity > 0 {
+		if a.position == 0 {
+			a.openedAt = now
+		}
+
+		held := float64(abs(a.position))
+		a.avgPrice = (a.avgPrice*held + price*float64(quantity)) / (held + float64(quantity))
+		a.position += quantity * direction
+	}
+}
+
+// equity деньги плюс позиция по последней цене
+func (a *virtualAccount) equity() float64 {
+	return a.cash + float64(a.position)*a.lastPrice*a.lotSize
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
@@ -14,14 +14,30 @@ import (
 )
 
 func NewWebsocket(url string) *Websocket {
+	subscribers := NewSubscribers()
+
+	// Стаканы схлопываются, остальные события ждут места, читатель при этом не завершается
+	queue := NewChainQueue(10000)
+	queue.SetPolicy(OverflowConflate)
+
+	return newWebsocket(url, queue, &subscribers, NewPortfolios(), NewOrderRouter(), NewSecurities())
+}
+
+// newWebsocket соединение с заданными очередью и общими для пула подписчиками, портфелями, заявками и справочником
+func newWebsocket(url string, queue *ChainQueue, subscribers *Subscribers, portfolios *Portfolios, orders *OrderRouter, securities *Securities) *Websocket {
 	return &Websocket{
 		url:           url,
-		subscribers:   NewSubscribers(),
+		subscribers:   subscribers,
 		subscriptions: NewSubscriptions(),
-		queue:         NewChainQueue(10000),
+		portfolios:    portfolios,
+		orders:        orders,
+		securities:    securities,
+		queue:         queue,
+		heartbeat:     DefaultHeartbeatConfig,
+		requests:      newPendingRequests(),
+		resumed:       newResumedTrades(),
 		done:          make(chan struct{}),
 		reconnect:     make(chan struct{}, 1),
-		ready:         true,
 	}
 }
 
@@ -30,15 +46,24 @@ type (
 		url           string
 		connection    *websocket.Conn
 		queue         *ChainQueue
-		subscribers   Subscribers
+		subscribers   *Subscribers // Общие для всех соединений пула
 		subscriptions Subscriptions
-		done          chan struct{}  // Основной канал для остановки всех горутин
-		reconnect     chan struct{}  // Канал для инициации переподключения
-		isConnecting  atomic.Bool    // Флаг процесса подключения
-		isClosing     atomic.Bool    // Флаг процесса отключения
-		mu            sync.Mutex     // Защищает connection
-		wg            sync.WaitGroup // Для ожидания завершения всех горутин
-		ready         bool
+		portfolios    *Portfolios           // Подписки клиента на данные портфелей
+		orders        *OrderRouter          // Чьи заявки приходят в потоках заявок и сделок
+		securities    *Securities           // Справочник инструментов
+		heartbeat     HeartbeatConfig       // Ping, дедлайны и переподключение
+		requests      *pendingRequests      // Запросы, ждущие ответа брокера
+		resumed       *resumedTrades        // Подписки на сделки, ждущие первой сделки после подписки
+		id            int                   // Номер соединения в пуле
+		onEvent       func(ConnectionEvent) // Подключения, разрывы и переподписки
+		onEvict       func(SubscriberID)    // Отключённый медленный подписчик уходит из пула вместе с подписками
+		lastRead      atomic.Int64          // Когда пришло последнее сообщение или pong, UnixNano
+		done          chan struct{}         // Основной канал для остановки всех горутин, закрывается один раз в Close
+		reconnect     chan struct{}         // Канал для инициации переподключения
+		isConnecting  atomic.Bool           // Флаг процесса подключения
+		isClosing     atomic.Bool           // Флаг процесса отключения
+		mu            sync.Mutex            // Защищает connection
+		wg            sync.WaitGroup        // Для ожидания завершения всех горутин
 	}
 )
 
@@ -73,33 +98,45 @@ func (ws *Websocket) Connect() error {
 	}
 	conn.EnableWriteCompression(true)
 
+	// Любое сообщение или pong продлевает дедлайн чтения, молчащее соединение считается оборванным
+	ws.markRead(conn)
+	conn.SetPongHandler(func(string) error {
+		ws.markRead(conn)
+		return nil
+	})
+
 	ws.connection = conn
 	return nil
 }
 
-// Close безопасно закрывает соединение с защитой от повторных вызовов
+// Close закрывает соединение и останавливает все его горутины, повторный вызов ничего не делает.
+// Закрытое соединение не переподключается.
 func (ws *Websocket) Close() error {
 	// Проверяем и устанавливаем флаг отключения
-	// Если он true, то в данный момент есть активный процесс отключения
+	// Если он true, то соединение уже закрывается или закрыто
 	if !ws.isClosing.CompareAndSwap(false, true) {
-		return nil // или можно вернуть ошибку "уже отключается"
+		return nil
 	}
-	defer ws.isClosing.Store(false)
 
-	// Блокируем сокет и не даём одновременно
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
+	// Сигнализируем о закрытии
+	close(ws.done)
 
-	// Всегда возвращаем канал в состояние готовности после отключения
-	defer func() { ws.done = make(chan struct{}) }()
+	// Разбор очереди остановлен, будим читателя, если он ждёт места в очереди
+	ws.queue.Close()
 
-	// Сигнализируем о закрытии
-	select {
-	case <-ws.done:
-		return nil // Уже закрыт
-	default:
-		close(ws.done)
-	}
+	err := ws.closeConnection()
+
+	log.Println("waiting all websocket goroutines")
+	// Ждем завершения всех горутин, мьютекс уже отпущен - читатель может сбросить соединение
+	ws.wg.Wait()
+
+	return err
+}
+
+func (ws *Websocket) closeConnection() error {
+	// Блокируем сокет и не даём одновременно писать
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
 	// Если уже отключены - ничего не делаем
 	if ws.connection == nil {
@@ -112,26 +149,23 @@ func (ws *Websocket) Close() error {
 
 	log.Println("sending close message")
 	// Отправляем сообщение о закрытии
-	err := ws.connection.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	err := ws.connection.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
 	if err != nil {
 		log.Println("Ошибка при отправке CloseMessage:", err)
-		return err
 	}
 
-	// Закрываем соединение
-	err = ws.connection.Close()
-	if err != nil {
+	// Закрываем соединение, читатель получит ошибку и завершится
+	if err := ws.connection.Close(); err != nil {
 		log.Println("Ошибка при закрытии соединения:", err)
 		return err
 	}
 
-	log.Println("waiting all websocket goroutines")
-	// Ждем завершения всех горутин
-	ws.wg.Wait()
 	return nil
 }
 
-func (ws *Websocket) Subscribe(token Token, subscriberID SubscriberID, subscription *Subscription) error {
+// Subscribe отправляет запрос подписки и ждёт ответа брокера.
+// Отказ возвращается как *BrokerError, молчание дольше AckTimeout - как ErrSubscriptionTimeout.
+func (ws *Websocket) Subscribe(token *Token, subscriberID SubscriberID, subscription *Subscription) error {
 	// Подготавливаем запрос
 	requestBytes, err := ws.prepareRequest(token, subscription)
 	if err != nil {
@@ -141,11 +175,12 @@ func (ws *Websocket) Subscribe(token Token, subscriberID SubscriberID, subscript
 	log.Printf("Запросили подписку на %+v", subscription)
 
 	// отправляем в сокет
-	if err := ws.Send(requestBytes); err != nil {
+	request, err := ws.request(subscription.GUID, subscription.Opcode, requestBytes)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return ws.wait(subscription.GUID, request)
 }
 
 func (ws *Websocket) Unsubscribe(token string, subscriberID SubscriberID, guid GUID) error {
@@ -160,7 +195,10 @@ func (ws *Websocket) Unsubscribe(token string, subscriberID SubscriberID, guid G
 		return err
 	}
 
-	return ws.Send(requestBytes)
+	// Ответ не ждём, но регистрируем запрос, чтобы его ответ не принять за ответ следующей подписки с тем же GUID
+	_, err = ws.request(guid, UnsubscribeOpcode, requestBytes)
+
+	return err
 }
 
 //func (ws *Websocket) GetSubscribers() []*Subscriber {
@@ -192,6 +230,11 @@ func (ws *Websocket) Send(message []byte) error {
 		return fmt.Errorf("connection is not established")
 	}
 
+	// Запись в полуоткрытое соединение не должна висеть вечно
+	if err := ws.connection.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+
 	return ws.connection.WriteMessage(websocket.TextMessage, message)
 }
 
@@ -214,48 +257,97 @@ type WsResponse struct {
 	Guid        string          `json:"guid"`
 }
 
+// Run запускает горутины соединения. Счётчик wg растёт до запуска горутин, иначе Close может их не дождаться.
+func (ws *Websocket) Run(ctx context.Context, token *Token) {
+	ws.wg.Add(4)
+
+	// Запускаем горутину для переподключения
+	go ws.ReconnectHandler(ctx, token)
+	// Запускаем разбор очереди, он один на всё время жизни соединения
+	go ws.SortQueue(ctx, token)
+	// Запускаем слушатель сообщений
+	go ws.Listen()
+	// Следим за подписками без данных
+	go ws.MonitorSubscriptions(ctx, token)
+}
+
+// Listen читает сообщения текущего соединения. При ошибке чтения, в том числе по дедлайну,
+// сбрасывает соединение и просит ReconnectHandler переподключиться.
 func (ws *Websocket) Listen() {
 	log.Println("running websocket listen loop")
 	defer log.Println("websocket listen loop closed")
 
-	ws.wg.Add(1)
 	defer ws.wg.Done()
 
+	ws.mu.Lock()
+	conn := ws.connection
+	ws.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+
+	// ping живёт столько же, сколько читатель этого соединения
+	stop := make(chan struct{})
+	defer close(stop)
+
+	ws.wg.Add(1)
+	go ws.keepAlive(conn, stop)
+
 	for {
-		select {
-		case <-ws.done:
-			return
-		default:
-			// получаем сообщение
-			_, message, err := ws.connection.ReadMessage()
-			if err != nil {
-				log.Println("Ошибка чтения:", err)
-				select {
-				case ws.reconnect <- struct{}{}:
-				case <-ws.done:
-				}
+		// получаем сообщение
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-ws.done:
 				return
+			default:
 			}
 
-			// log.Printf("Получено: %s", message)
+			log.Println("Ошибка чтения:", err)
+			ws.disconnected(conn, err)
+			return
+		}
 
-			// обрабатываем сообщение
-			var response WsResponse
-			err = json.Unmarshal(message, &response)
-			if err != nil {
-				log.Println("unmarshall failed", err)
-				return
-			}
+		ws.markRead(conn)
 
-			// log.Printf("Получено: %+v", response)
+		// log.Printf("Получено: %s", message)
 
-			err = ws.HandleResponse(response)
-			if err != nil {
-				log.Println("ws handle response error:", err)
-				return
-			}
+		// обрабатываем сообщение, битое сообщение не останавливает чтение остальных
+		var response WsResponse
+		err = json.Unmarshal(message, &response)
+		if err != nil {
+			log.Println("unmarshall failed", err)
+			continue
+		}
+
+		// log.Printf("Получено: %+v", response)
+
+		err = ws.HandleResponse(response)
+		if errors.Is(err, ErrQueueClosed) {
+			return
 		}
 
+		if err != nil {
+			log.Println("ws handle response error:", err)
+		}
+	}
+}
+
+// disconnected сбрасывает оборванное соединение и запускает переподключение
+func (ws *Websocket) disconnected(conn *websocket.Conn, err error) {
+	ws.mu.Lock()
+	if ws.connection == conn {
+		_ = conn.Close()
+		ws.connection = nil
+	}
+	ws.mu.Unlock()
+
+	ws.emit(ConnectionEvent{Type: DisconnectedEvent, Err: err})
+
+	select {
+	case ws.reconnect <- struct{}{}:
+	default: // переподключение уже запрошено
 	}
 }
 
@@ -268,10 +360,10 @@ func (ws *Websocket) HandleResponse(message WsResponse) error {
 		Data: message.Data,
 	}
 
-	// отделяем системные сообщения от данных
-	if message.RequestGuid != "" {
-		event.Type = SystemType
-		event.Guid = GUID(message.RequestGuid)
+	// Ответы на запросы сразу получают ждущие их Subscribe, в очередь попадают только данные
+	if message.RequestGuid != "" && message.Guid == "" {
+		ws.handleAck(message)
+		return nil
 	}
 
 	if message.Guid != "" {
@@ -282,13 +374,12 @@ func (ws *Websocket) HandleResponse(message WsResponse) error {
 	guidParts := strings.Split(string(event.Guid), "-")
 	event.Opcode = Opcode(guidParts[0])
 
-	err := ws.queue.Enqueue(event)
-	if err != nil {
-		if errors.Is(err, ErrQueueOverFlow) {
-			log.Println("queue too big", ws.queue.GetLength())
-			time.Sleep(time.Second * 1)
-			ws.ready = false
-		}
+	if event.Opcode == AllTradesOpcode {
+		event.Resumed = ws.resumed.take(event.Guid)
+	}
+
+	// Переполнение обрабатывает политика очереди, потери видны в счётчиках QueueStats
+	if err := ws.queue.Enqueue(event); err != nil {
 		return err
 	}
 
@@ -297,76 +388,57 @@ func (ws *Websocket) HandleResponse(message WsResponse) error {
 	return nil
 }
 
-func (ws *Websocket) ReconnectHandler(ctx context.Context, token Token) {
+// ReconnectHandler переподключается после разрыва с экспоненциальной паузой и разбросом.
+// Разбор очереди продолжает работать, после подключения запускается новый читатель и восстанавливаются подписки.
+func (ws *Websocket) ReconnectHandler(ctx context.Context, token *Token) {
 	log.Println("running websocket reconnect loop")
 	defer log.Println("websocket reconnect loop closed")
 
-	ws.wg.Add(1)
 	defer ws.wg.Done()
 
 	for {
 		select {
+		case <-ctx.Done():
+			return
+		case <-ws.done:
+			return
 		case <-ws.reconnect:
-			if ws.isClosing.Load() {
-				continue
-			}
-
-			log.Println("Попытка переподключения...")
+		}
 
-			// Закрываем предыдущее соединение, если оно есть
-			// надо ли?
-			//if ws.connection != nil {
-			//	ws.connection.Close()
-			//}
+		log.Println("Попытка переподключения...")
 
-			// Пытаемся подключиться с экспоненциальной задержкой
-			retryDelay := time.Second
-			maxRetryDelay := 30 * time.Second
+		for attempt := 1; ; attempt++ {
+			delay := ws.heartbeat.reconnectDelay(attempt - 1)
 
-			for {
-				if ws.isClosing.Load() {
-					break
-				}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			case <-ws.done:
+				return
+			}
 
-				// TODO: надо ли проверять?
-				//select {
-				//case <-ws.done:
-				//	return
-				//default:
-				//}
-
-				err := ws.Connect()
-				if err == nil {
-					log.Println("Переподключение успешно")
-					// Запускаем разбор очереди
-					go ws.SortQueue(ctx, token)
-					// Запускаем слушатель сообщений
-					go ws.Listen()
-
-					if err := ws.restoreSubscriptions(token); err != nil {
-						log.Println(err)
-					}
-					break
-				}
+			err := ws.Connect()
+			if err == nil {
+				log.Println("Переподключение успешно")
+				ws.emit(ConnectionEvent{Type: ConnectedEvent, Attempt: attempt})
 
-				log.Printf("Ошибка переподключения: %v, следующая попытка через %v", err, retryDelay)
+				// Запускаем слушатель сообщений
+				ws.wg.Add(1)
+				go ws.Listen()
 
-				select {
-				case <-time.After(retryDelay):
-				case <-ws.done:
-					return
+				if err := ws.restoreSubscriptions(token); err != nil {
+					log.Println(err)
 				}
-
-				retryDelay = min(retryDelay*2, maxRetryDelay)
-				// retryDelay = time.Duration(min(int64(retryDelay*2), int64(30*time.Second)))
+				break
 			}
-		case <-ws.done:
-			return
+
+			log.Printf("Ошибка переподключения: %v, попытка %d", err, attempt)
 		}
 	}
 }
 
-func (ws *Websocket) restoreSubscriptions(token Token) error {
+func (ws *Websocket) restoreSubscriptions(token *Token) error {
 	log.Println("restoring subscriptions")
 
 	containers, err := ws.subscriptions.All()
@@ -374,14 +446,17 @@ func (ws *Websocket) restoreSubscriptions(token Token) error {
 		return err
 	}
 
-	for _, subscriptionState := range containers {
+	for guid, subscriptionState := range containers {
+		// Новая подписка получает StaleAfter на первые данные
+		ws.subscriptions.Touch(guid, time.Now())
+
 		requestBytes, err := ws.prepareRequest(token, subscriptionState.Subscription)
 		log.Println(string(requestBytes))
 		if err != nil {
 			return err
 		}
 
-		if err := ws.Send(requestBytes); err != nil {
+		if _, err := ws.request(guid, subscriptionState.Subscription.Opcode, requestBytes); err != nil {
 			return err
 		}
 	}
@@ -389,7 +464,45 @@ func (ws *Websocket) restoreSubscriptions(token Token) error {
 	return nil
 }
 
-func (ws *Websocket) prepareRequest(token Token, subscription *Subscription) ([]byte, error) {
+// Reauthorize повторно отправляет с новым токеном подписки, которые брокер отклонил.
+// Брокер проверяет токен только при подписке, поэтому работающие подписки не трогаем,
+// а отклонённые из-за истёкшего токена оживают после обновления.
+func (ws *Websocket) Reauthorize(token *Token) error {
+	ws.mu.Lock()
+	connected := ws.connection != nil
+	ws.mu.Unlock()
+
+	// Без соединения подписки восстановит ReconnectHandler
+	if !connected {
+		return nil
+	}
+
+	containers, err := ws.subscriptions.All()
+	if err != nil {
+		return err
+	}
+
+	for guid, container := range containers {
+		if container.State != FailedSubscription {
+			continue
+		}
+
+		log.Println("resubscribing with refreshed token", guid)
+
+		requestBytes, err := ws.prepareRequest(token, container.Subscription)
+		if err != nil {
+			return err
+		}
+
+		if _, err := ws.request(guid, container.Subscription.Opcode, requestBytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ws *Websocket) prepareRequest(token *Token, subscription *Subscription) ([]byte, error) {
 	switch subscription.Opcode {
 	case BarsOpcode:
 		return ws.prepareBarsRequest(token, subscription)
@@ -397,16 +510,22 @@ func (ws *Websocket) prepareRequest(token Token, subscription *Subscription) ([]
 		return ws.prepareAllTradesRequest(token, subscription)
 	case OrderBookOpcode:
 		return ws.prepareOrderBooksRequest(token, subscription)
+	case QuotesOpcode:
+		return ws.prepareQuotesRequest(token, subscription)
+	case InstrumentsOpcode:
+		return ws.prepareInstrumentsRequest(token, subscription)
+	case PositionsOpcode, SummariesOpcode, RisksOpcode, SpectralRisksOpcode,
+		OrdersOpcode, StopOrdersOpcode, TradesOpcode:
+		return ws.preparePortfolioRequest(token, subscription)
 	}
 
-	return nil, errors.New("invalid opcode")
+	return nil, ErrInvalidOpcode
 }
 
-func (ws *Websocket) SortQueue(ctx context.Context, token Token) {
+func (ws *Websocket) SortQueue(ctx context.Context, token *Token) {
 	log.Println("running websocket queue loop")
 	defer log.Println("websocket queue loop closed")
 
-	ws.wg.Add(1)
 	defer ws.wg.Done()
 
 	for {
@@ -424,7 +543,10 @@ func (ws *Websocket) SortQueue(ctx context.Context, token Token) {
 			event, err := ws.queue.Dequeue()
 			if err != nil {
 				if errors.Is(err, ErrQueueUnderFlow) {
-					time.Sleep(time.Millisecond * 500)
+					select {
+					case <-time.After(time.Millisecond * 500):
+					case <-ws.done:
+					}
 					continue
 				}
 
@@ -442,6 +564,34 @@ func (ws *Websocket) SortQueue(ctx context.Context, token Token) {
 				continue
 			}
 
+			ws.subscriptions.Touch(event.Guid, time.Now())
+
+			// Справочник инструментов общий для всех подписчиков
+			if event.Opcode == InstrumentsOpcode {
+				ws.subscriptions.SetActive(event.Guid)
+
+				if err := ws.securities.HandleEvent(event); err != nil {
+					log.Println("securities handle error:", err)
+				}
+				continue
+			}
+
+			// Данные портфеля не относятся к подписчикам, их получает состояние портфеля клиента
+			if portfolio, ok := ws.portfolios.GetByGUID(event.Guid); ok {
+				ws.subscriptions.SetActive(event.Guid)
+
+				// Заявки и сделки получает выставивший их подписчик
+				if isOrdersOpcode(event.Opcode) {
+					ws.routeOrderEvent(event)
+					continue
+				}
+
+				if err := portfolio.HandleEvent(event); err != nil {
+					log.Println("portfolio handle error:", err)
+				}
+				continue
+			}
+
 			subscriptionContainer, err := ws.subscriptions.Get(event.Guid)
 			if err != nil {
 				log.Println(err)
@@ -468,7 +618,7 @@ func (ws *Websocket) SortQueue(ctx context.Context, token Token) {
 				}
 
 				// если подписчик помечен как завершённый
-				if subscriber.Done {
+				if subscriber.IsDone() {
 					continue
 				}
 
@@ -495,15 +645,23 @@ func (ws *Websocket) SortQueue(ctx context.Context, token Token) {
 				// В каждом сабскрибере делать свой контекст с отменой от родительского. Отменять горутину когда сабскрибер будет удаляться.
 
 				// активируем подписку
-				if item, err := ws.subscriptions.Get(event.Guid); err != nil {
-					if !item.Active {
-						item.Active = true
+				if item, err := ws.subscriptions.Get(event.Guid); err == nil {
+					if item.State != ActiveSubscription {
 						ws.subscriptions.SetActive(event.Guid)
 					}
 				}
 
+				// Асинхронный подписчик только получает событие в свою очередь,
+				// не успевающий её разбирать отключается и не тормозит остальных
 				if err := subscriber.HandleEvent(event); err != nil {
-					ws.subscribers.SetDone(subscriber.ID)
+					subscriber.fail(err)
+
+					if errors.Is(err, ErrSlowSubscriber) {
+						log.Printf("subscriber %s evicted as slow consumer: %s", subscriber.ID, err)
+						ws.evict(subscriber.ID)
+						continue
+					}
+
 					log.Println(subscriber.ID, "error in handle:", err)
 				}
 
@@ -512,29 +670,46 @@ func (ws *Websocket) SortQueue(ctx context.Context, token Token) {
 	}
 }
 
-// IsConnected проверяет, активно ли соединение WebSocket
+func (ws *Websocket) evict(subscriberID SubscriberID) {
+	if ws.onEvict != nil {
+		ws.onEvict(subscriberID)
+	}
+}
+
+// routeOrderEvent отдаёт событие заявки её владельцу, чужие заявки (например, выставленные вручную) пропускаем
+func (ws *Websocket) routeOrderEvent(event *ChainEvent) {
+	subscriberID, ok, err := ws.orders.route(event)
+	if err != nil {
+		log.Println("order event parse error:", err)
+		return
+	}
+
+	if !ok {
+		return
+	}
+
+	subscriber, err := ws.subscribers.Get(subscriberID)
+	if err != nil || subscriber.IsDone() {
+		return
+	}
+
+	if err := subscriber.HandleEvent(event); err != nil {
+		subscriber.fail(err)
+		log.Println(subscriber.ID, "error in handle order event:", err)
+	}
+}
+
+// IsConnected соединение установлено и за ReadTimeout от брокера приходили сообщения или pong
 func (ws *Websocket) IsConnected() bool {
 	ws.mu.Lock()
-	defer ws.mu.Unlock()
+	connected := ws.connection != nil
+	ws.mu.Unlock()
 
-	if ws.connection == nil {
+	if !connected {
 		return false
 	}
 
-	//  _, _, err := ws.connection.NextReader()
-	//	if err != nil {
-	//		return false
-	//	}
-	//	return true
-
-	// Простая проверка через отправку ping сообщения
-	err := ws.connection.WriteControl(
-		websocket.PingMessage,
-		[]byte{},
-		time.Now().Add(1*time.Second),
-	)
-
-	return err == nil
+	return time.Since(time.Unix(0, ws.lastRead.Load())) < ws.heartbeat.ReadTimeout
 }
 
 func (ws *Websocket) GetSubscriber(subscriberID SubscriberID) (*Subscriber, error) {
@@ -545,37 +720,80 @@ func (ws *Websocket) GetSubscribers() (map[SubscriberID]*Subscriber, error) {
 	return ws.subscribers.All(), nil
 }
 
-func (ws *Websocket) AddSubscriber(token Token, subscriber *Subscriber) error {
+// AddSubscriber ctx - контекст пула, воркер и таймер подписчика живут не дольше него
+func (ws *Websocket) AddSubscriber(ctx context.Context, token *Token, subscriber *Subscriber) error {
+	if subscriber.err != nil {
+		return subscriber.err
+	}
+
+	// Заявки подписчика, в том числе выставленные в OnStart, возвращаются ему через потоки заявок и сделок
+	subscriber.prepareCommandBus(ws.orders)
+
+	log.Println("subscriber ", subscriber.ID, "init")
+	if err := subscriber.Init(); err != nil {
+		return fmt.Errorf("subscriber %s init failed: %w", subscriber.ID, err)
+	}
+
 	log.Println("subscriber subscribe", subscriber.ID, "start subscriptions", subscriber)
 
+	// Данные приходят сразу за ответом брокера, поэтому стратегия активна
+	// и подписчик в списке ещё до запросов подписки
+	subscriber.Ready = true
+	ws.subscribers.Add(subscriber)
+
 	// активируем все подписки
+	subscribed := make([]*Subscription, 0, len(subscriber.Subscriptions))
 	for _, subscription := range subscriber.Subscriptions {
-		// отправляем команду брокеру
+		// добавляем подписчика в подписку
+		ws.subscriptions.Add(subscriber.ID, subscription)
+
+		// отправляем команду брокеру и ждём подтверждения
 		if err := ws.Subscribe(token, subscriber.ID, subscription); err != nil {
-			return err
+			// Отклонённую подписку брокер не создал, а неотвеченная могла создаться
+			if !errors.Is(err, ErrSubscriptionRejected) {
+				subscribed = append(subscribed, subscription)
+			} else {
+				_ = ws.subscriptions.Delete(subscriber.ID, subscription.GUID)
+			}
+
+			ws.rollbackSubscriber(token, subscriber, subscribed)
+			return fmt.Errorf("subscriber %s subscription %s: %w", subscriber.ID, subscription.Opcode, err)
 		}
 
-		// добавляем подписчика в подписку
-		ws.subscriptions.Add(subscriber.ID, subscription)
+		subscribed = append(subscribed, subscription)
 	}
 
-	log.Println("subscriber ", subscriber.ID, "init")
-	//if subscriber.CustomHandler != nil {
-	//	if err := subscriber.CustomHandler.Init(); err != nil {
-	//		return err
-	//	}
-	//}
-
-	// Активируем стратегии
-	subscriber.Ready = true
 	log.Printf("subscriber %s ready to work", subscriber.ID)
 
-	// добавляем подписчика в список подписчиков
-	ws.subscribers.Add(subscriber)
+	// Асинхронный подписчик разбирает свою очередь в отдельной горутине до RemoveSubscriber или остановки пула
+	subscriber.Start(ctx)
 
 	return nil
 }
 
+// rollbackSubscriber отписывает и убирает подписчика, одну из подписок которого брокер не принял
+func (ws *Websocket) rollbackSubscriber(token *Token, subscriber *Subscriber, subscriptions []*Subscription) {
+	subscriber.Ready = false
+	subscriber.setDone()
+
+	accessToken, err := token.GetAccessToken()
+
+	for _, subscription := range subscriptions {
+		if err == nil {
+			_ = ws.Unsubscribe(accessToken, subscriber.ID, subscription.GUID)
+		}
+
+		_ = ws.subscriptions.Delete(subscriber.ID, subscription.GUID)
+	}
+
+	if err := subscriber.DeInit(); err != nil {
+		log.Println(subscriber.ID, "error in deinit:", err)
+	}
+
+	ws.subscribers.Delete(subscriber.ID)
+	ws.orders.Forget(subscriber.ID)
+}
+
 func (ws *Websocket) RemoveSubscriber(token string, subscriberID SubscriberID) error {
 	log.Println("subscriber unsubscribe", subscriberID, "stop subscriptions")
 
@@ -586,7 +804,8 @@ func (ws *Websocket) RemoveSubscriber(token string, subscriberID SubscriberID) e
 	}
 
 	// Больше не принимает события
-	ws.subscribers.SetDone(subscriber.ID)
+	subscriber.setDone()
+	subscriber.Stop()
 
 	// Отписывается от всех подписок
 	for _, subscription := range subscriber.Subscriptions {
@@ -598,13 +817,12 @@ func (ws *Websocket) RemoveSubscriber(token string, subscriberID SubscriberID) e
 	}
 
 	log.Println("subscriber ", subscriber.ID, "deinit")
-	//if subscriber.CustomHandler != nil {
-	//	if err := subscriber.CustomHandler.DeInit(); err != nil {
-	//		return err
-	//	}
-	//}
+	if err := subscriber.DeInit(); err != nil {
+		log.Println(subscriber.ID, "error in deinit:", err)
+	}
 
 	ws.subscribers.Delete(subscriberID)
+	ws.orders.Forget(subscriberID)
 
 	return nil
 }
@@ -619,11 +837,23 @@ func (ws *Websocket) RemoveAllSubscribers(token string) error {
 	return nil
 }
 
+// GetAllStrategyBars копии баров подписчика, снятые между его событиями
 func (ws *Websocket) GetAllStrategyBars(subscriberID SubscriberID) ([]*Bar, error) {
 	subscriber, err := ws.subscribers.Get(subscriberID)
 	if err != nil {
 		return nil, ErrSubscriberNotFound
 	}
 
-	return subscriber.DataProcessor.bars.GetAllBars()
+	var bars []*Bar
+	err = subscriber.view(func(processor *DataProcessor) error {
+		all, err := processor.bars.GetAllBars()
+		if err != nil {
+			return err
+		}
+
+		bars = cloneBars(all)
+		return nil
+	})
+
+	return bars, err
 }
@@ -0,0 +1,265 @@
+package alor
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// HeartbeatConfig проверка живости соединения и его подписок
+type HeartbeatConfig struct {
+	PingInterval      time.Duration        // Как часто слать ping, по умолчанию 15 секунд
+	ReadTimeout       time.Duration        // Сколько ждать любого сообщения или pong, по умолчанию 45 секунд
+	StaleAfter        time.Duration        // Подписка без данных дольше переподписывается, по умолчанию 2 минуты
+	ReconnectMinDelay time.Duration        // Первая пауза перед переподключением, по умолчанию секунда
+	ReconnectMaxDelay time.Duration        // Предел паузы, по умолчанию 30 секунд
+	AckTimeout        time.Duration        // Сколько ждать ответа брокера на подписку, по умолчанию 10 секунд
+	TradingSession    func(time.Time) bool // Идут ли торги, вне торгов тишина в подписках нормальна. По умолчанию MOEXTradingSession
+}
+
+var DefaultHeartbeatConfig = HeartbeatConfig{
+	PingInterval:      15 * time.Second,
+	ReadTimeout:       45 * time.Second,
+	StaleAfter:        2 * time.Minute,
+	ReconnectMinDelay: time.Second,
+	ReconnectMaxDelay: 30 * time.Second,
+	AckTimeout:        10 * time.Second,
+	TradingSession:    MOEXTradingSession,
+}
+
+// writeTimeout сколько ждать записи в сокет
+const writeTimeout = 10 * time.Second
+
+func (c HeartbeatConfig) withDefaults() HeartbeatConfig {
+	if c.PingInterval <= 0 {
+		c.PingInterval = DefaultHeartbeatConfig.PingInterval
+	}
+
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = DefaultHeartbeatConfig.ReadTimeout
+	}
+
+	if c.StaleAfter <= 0 {
+		c.StaleAfter = DefaultHeartbeatConfig.StaleAfter
+	}
+
+	if c.ReconnectMinDelay <= 0 {
+		c.ReconnectMinDelay = DefaultHeartbeatConfig.ReconnectMinDelay
+	}
+
+	if c.ReconnectMaxDelay < c.ReconnectMinDelay {
+		c.ReconnectMaxDelay = max(DefaultHeartbeatConfig.ReconnectMaxDelay, c.ReconnectMinDelay)
+	}
+
+	if c.AckTimeout <= 0 {
+		c.AckTimeout = DefaultHeartbeatConfig.AckTimeout
+	}
+
+	if c.TradingSession == nil {
+		c.TradingSession = DefaultHeartbeatConfig.TradingSession
+	}
+
+	return c
+}
+
+// reconnectDelay пауза перед попыткой attempt: экспонента с разбросом в половину,
+// чтобы соединения пула не переподключались одновременно
+func (c HeartbeatConfig) reconnectDelay(attempt int) time.Duration {
+	delay := c.ReconnectMinDelay
+	for range attempt {
+		delay *= 2
+		if delay >= c.ReconnectMaxDelay {
+			delay = c.ReconnectMaxDelay
+			break
+		}
+	}
+
+	half := delay / 2
+
+	return half + rand.N(half+1)
+}
+
+var moscow = time.FixedZone("MSK", 3*60*60)
+
+// MOEXTradingSession будни с 06:50 до 23:50 по Москве: утренняя, основная и вечерняя сессии
+func MOEXTradingSession(now time.Time) bool {
+	now = now.In(moscow)
+
+	if now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
+		return false
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+
+	return minutes >= 6*60+50 && minutes < 23*60+50
+}
+
+// AlwaysTradingSession торги идут круглосуточно, например, для тестового контура
+func AlwaysTradingSession(time.Time) bool {
+	return true
+}
+
+type ConnectionEventType string
+
+const (
+	ConnectedEvent    ConnectionEventType = "connected"
+	DisconnectedEvent ConnectionEventType = "disconnected"
+	ResubscribedEvent ConnectionEventType = "resubscribed"
+)
+
+// ConnectionEvent изменение состояния соединения пула или его подписки
+type ConnectionEvent struct {
+	Type       ConnectionEventType `json:"type"`
+	Connection int                 `json:"connection"`        // Номер соединения в пуле
+	GUID       GUID                `json:"guid,omitempty"`    // Переподписанная подписка
+	Attempt    int                 `json:"attempt,omitempty"` // Номер попытки переподключения
+	Err        error               `json:"-"`                 // Причина разрыва
+	Time       time.Time           `json:"time"`
+}
+
+func (ws *Websocket) emit(event ConnectionEvent) {
+	event.Connection = ws.id
+	event.Time = time.Now().UTC()
+
+	if ws.onEvent != nil {
+		ws.onEvent(event)
+	}
+}
+
+// markRead соединение живо: пришло сообщение или pong
+func (ws *Websocket) markRead(conn *websocket.Conn) {
+	ws.lastRead.Store(time.Now().UnixNano())
+	_ = conn.SetReadDeadline(time.Now().Add(ws.heartbeat.ReadTimeout))
+}
+
+// keepAlive шлёт ping, пока жив читатель соединения conn. Без pong за ReadTimeout
+// истекает дедлайн чтения, и полуоткрытое соединение уходит на переподключение.
+func (ws *Websocket) keepAlive(conn *websocket.Conn, stop <-chan struct{}) {
+	defer ws.wg.Done()
+
+	ticker := time.NewTicker(ws.heartbeat.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ws.done:
+			return
+		case <-ticker.C:
+			// WriteControl можно вызывать параллельно с остальной записью
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				log.Println("websocket ping failed:", err)
+				return
+			}
+		}
+	}
+}
+
+// isMarketData рыночные данные идут во время торгов постоянно, данные счёта могут молчать часами
+func isMarketData(opcode Opcode) bool {
+	switch opcode {
+	case BarsOpcode, AllTradesOpcode, OrderBookOpcode, QuotesOpcode:
+		return true
+	}
+
+	return false
+}
+
+// MonitorSubscriptions переподписывает подписки на рыночные данные, по которым во время торгов
+// дольше StaleAfter не было событий
+func (ws *Websocket) MonitorSubscriptions(ctx context.Context, token *Token) {
+	defer ws.wg.Done()
+
+	ticker := time.NewTicker(max(ws.heartbeat.StaleAfter/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ws.done:
+			return
+		case now := <-ticker.C:
+			if !ws.IsConnected() {
+				continue
+			}
+
+			containers, err := ws.subscriptions.All()
+			if err != nil {
+				continue
+			}
+
+			// Запросы уходят все сразу, ответы собираются потом: переподписка N подписок
+			// занимает не дольше одного AckTimeout
+			pending := make(map[GUID]*pendingRequest)
+			for guid, container := range containers {
+				if !ws.isStale(container, now) {
+					continue
+				}
+
+				request, err := ws.resubscribe(token, container.Subscription)
+				if err != nil {
+					log.Printf("websocket resubscribe %s failed: %s", guid, err)
+					continue
+				}
+
+				pending[guid] = request
+			}
+
+			for guid, request := range pending {
+				if err := ws.wait(guid, request); err != nil {
+					log.Printf("websocket resubscribe %s failed: %s", guid, err)
+					continue
+				}
+
+				ws.emit(ConnectionEvent{Type: ResubscribedEvent, GUID: guid})
+			}
+		}
+	}
+}
+
+func (ws *Websocket) isStale(container SubscriptionContainer, now time.Time) bool {
+	subscription := container.Subscription
+
+	if !isMarketData(subscription.Opcode) || now.Sub(container.LastEvent) < ws.heartbeat.StaleAfter {
+		return false
+	}
+
+	if !ws.heartbeat.TradingSession(now) {
+		return false
+	}
+
+	// Если инструмент есть в справочнике, доверяем его статусу торгов: в клиринг данных нет
+	security, err := ws.securities.Get(subscription.Exchange, subscription.Code, subscription.InstrumentGroup)
+	if err == nil && !security.IsTrading() {
+		return false
+	}
+
+	return true
+}
+
+// resubscribe отписывается и подписывается заново под тем же GUID, ответ на подписку ждёт вызывающий
+func (ws *Websocket) resubscribe(token *Token, subscription *Subscription) (*pendingRequest, error) {
+	accessToken, err := token.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ws.Unsubscribe(accessToken, clientOwner, subscription.GUID); err != nil {
+		return nil, err
+	}
+
+	// Новая подписка получает StaleAfter на первые данные
+	ws.subscriptions.Touch(subscription.GUID, time.Now())
+
+	requestBytes, err := ws.prepareRequest(token, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.request(subscription.GUID, subscription.Opcode, requestBytes)
+}
@@ -0,0 +1,510 @@
+package alor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// PoolLimits ограничения пула соединений вебсокета
+type PoolLimits struct {
+	MaxConnections                int            // Сколько соединений можно открыть
+	MaxSubscriptionsPerConnection int            // Сколько подписок держит одно соединение
+	QueueSize                     int            // Размер очереди соединения
+	QueuePolicy                   OverflowPolicy // Что делать при переполнении очереди соединения
+}
+
+var DefaultPoolLimits = PoolLimits{
+	MaxConnections:                4,
+	MaxSubscriptionsPerConnection: 50,
+	QueueSize:                     10000,
+	QueuePolicy:                   OverflowConflate,
+}
+
+// ConnectionStats нагрузка одного соединения пула
+type ConnectionStats struct {
+	Subscriptions int        `json:"subscriptions"`
+	Queue         QueueStats `json:"queue"`
+	Connected     bool       `json:"connected"`
+}
+
+func NewWebsocketPool(url string, limits PoolLimits, heartbeat HeartbeatConfig) *WebsocketPool {
+	if limits.MaxConnections <= 0 {
+		limits.MaxConnections = DefaultPoolLimits.MaxConnections
+	}
+
+	if limits.MaxSubscriptionsPerConnection <= 0 {
+		limits.MaxSubscriptionsPerConnection = DefaultPoolLimits.MaxSubscriptionsPerConnection
+	}
+
+	if limits.QueueSize <= 0 {
+		limits.QueueSize = DefaultPoolLimits.QueueSize
+	}
+
+	if !limits.QueuePolicy.IsValid() {
+		limits.QueuePolicy = DefaultPoolLimits.QueuePolicy
+	}
+
+	subscribers := NewSubscribers()
+
+	return &WebsocketPool{
+		url:         url,
+		limits:      limits,
+		heartbeat:   heartbeat.withDefaults(),
+		subscribers: &subscribers,
+		portfolios:  NewPortfolios(),
+		orders:      NewOrderRouter(),
+		securities:  NewSecurities(),
+		load:        make(map[*Websocket]int),
+		placement:   make(map[SubscriberID]placement),
+		clientSubs:  make(map[GUID]*Websocket),
+	}
+}
+
+// placement соединение подписчика и сколько мест в нём заняли его подписки
+type placement struct {
+	ws    *Websocket
+	count int
+}
+
+// WebsocketPool распределяет подписки по нескольким соединениям.
+// У каждого соединения свои читатель, очередь и переподключение, подписчики и данные счёта общие.
+// Все подписки одного подписчика живут в одном соединении, поэтому его события приходят по порядку.
+type WebsocketPool struct {
+	url         string
+	limits      PoolLimits
+	heartbeat   HeartbeatConfig
+	listeners   []func(ConnectionEvent)
+	listenersMu sync.RWMutex // Отдельно от mu: события приходят и из-под него, при открытии соединения
+	ctx         context.Context
+	cancel      context.CancelFunc // Останавливает воркеры и таймеры подписчиков при Close
+	token       *Token
+	connections []*Websocket
+	opening     int // Соединения, которые сейчас подключаются, место под них уже занято
+	nextID      int // Номер следующего соединения
+	subscribers *Subscribers
+	portfolios  *Portfolios
+	orders      *OrderRouter
+	securities  *Securities
+	load        map[*Websocket]int         // Занятые подписками места в соединении
+	placement   map[SubscriberID]placement // Соединение подписчика
+	clientSubs  map[GUID]*Websocket        // Соединение подписки клиента (портфели, справочник)
+	history     AllTradesHistory           // Догрузка пропущенных сделок для подписчиков без своей истории
+	clock       Clock                      // Часы биржи для таймера баров подписчиков без своих часов
+	mu          sync.Mutex
+	wg          sync.WaitGroup // Отключение медленных подписчиков
+}
+
+// Connect открывает первое соединение, остальные открываются по мере роста числа подписок
+func (p *WebsocketPool) Connect(ctx context.Context, token *Token) error {
+	p.mu.Lock()
+	if len(p.connections) > 0 || p.opening > 0 {
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.token = token
+	id := p.reserveConnection()
+	p.mu.Unlock()
+
+	_, err := p.openConnection(id, 0)
+
+	return err
+}
+
+// reserveConnection занимает место под новое соединение, вызывается под p.mu
+func (p *WebsocketPool) reserveConnection() int {
+	p.opening++
+	p.nextID++
+
+	return p.nextID
+}
+
+// openConnection подключается без p.mu, чтобы рукопожатие не держало остальные операции пула,
+// и только потом добавляет соединение в пул с count занятыми местами
+func (p *WebsocketPool) openConnection(id int, count int) (*Websocket, error) {
+	p.mu.Lock()
+	ctx, token := p.ctx, p.token
+	p.mu.Unlock()
+
+	queue := NewChainQueue(p.limits.QueueSize)
+	queue.SetPolicy(p.limits.QueuePolicy)
+
+	ws := newWebsocket(p.url, queue, p.subscribers, p.portfolios, p.orders, p.securities)
+	ws.heartbeat = p.heartbeat
+	ws.id = id
+	ws.onEvent = p.emit
+	ws.onEvict = p.evict
+
+	err := ws.Connect()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.opening--
+
+	if err != nil {
+		return nil, err
+	}
+
+	ws.emit(ConnectionEvent{Type: ConnectedEvent})
+
+	ws.Run(ctx, token)
+
+	p.connections = append(p.connections, ws)
+	p.load[ws] += count
+
+	return ws, nil
+}
+
+// acquire выбирает наименее загруженное соединение, в котором есть count свободных мест,
+// и открывает новое, если такого нет
+func (p *WebsocketPool) acquire(count int) (*Websocket, error) {
+	p.mu.Lock()
+
+	if p.ctx == nil {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("websocket pool is not connected")
+	}
+
+	if count > p.limits.MaxSubscriptionsPerConnection {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d subscriptions, %d per connection", ErrPoolExhausted, count, p.limits.MaxSubscriptionsPerConnection)
+	}
+
+	var best *Websocket
+	for _, ws := range p.connections {
+		if p.load[ws]+count > p.limits.MaxSubscriptionsPerConnection {
+			continue
+		}
+
+		if best == nil || p.load[ws] < p.load[best] {
+			best = ws
+		}
+	}
+
+	if best != nil {
+		p.load[best] += count
+		p.mu.Unlock()
+
+		return best, nil
+	}
+
+	if len(p.connections)+p.opening >= p.limits.MaxConnections {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d connections", ErrPoolExhausted, len(p.connections)+p.opening)
+	}
+
+	id := p.reserveConnection()
+	p.mu.Unlock()
+
+	return p.openConnection(id, count)
+}
+
+func (p *WebsocketPool) release(ws *Websocket, count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.load[ws] = max(p.load[ws]-count, 0)
+}
+
+// SetAllTradesHistory откуда подписчики догружают сделки, пропущенные при переподключении
+func (p *WebsocketPool) SetAllTradesHistory(history AllTradesHistory) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.history = history
+}
+
+// SetClock по каким часам подписчики закрывают бары таймером
+func (p *WebsocketPool) SetClock(clock Clock) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clock = clock
+}
+
+func (p *WebsocketPool) AddSubscriber(token *Token, subscriber *Subscriber) error {
+	p.mu.Lock()
+	ctx := p.ctx
+	if subscriber.tradesHistory == nil {
+		subscriber.tradesHistory = p.history
+	}
+
+	if subscriber.clock == nil {
+		subscriber.clock = p.clock
+	}
+	p.mu.Unlock()
+
+	ws, err := p.acquire(len(subscriber.Subscriptions))
+	if err != nil {
+		return err
+	}
+
+	if err := ws.AddSubscriber(ctx, token, subscriber); err != nil {
+		p.release(ws, len(subscriber.Subscriptions))
+		return err
+	}
+
+	p.mu.Lock()
+	p.placement[subscriber.ID] = placement{ws: ws, count: len(subscriber.Subscriptions)}
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *WebsocketPool) RemoveSubscriber(token string, subscriberID SubscriberID) error {
+	// Место освобождает только один вызов, даже если подписчика одновременно отключают и удаляют
+	p.mu.Lock()
+	place, ok := p.placement[subscriberID]
+	delete(p.placement, subscriberID)
+	p.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := place.ws.RemoveSubscriber(token, subscriberID); err != nil {
+		p.mu.Lock()
+		p.placement[subscriberID] = place
+		p.mu.Unlock()
+
+		return err
+	}
+
+	p.release(place.ws, place.count)
+
+	return nil
+}
+
+// evict убирает отключённого медленного подписчика: отписка, освобождение места в соединении.
+// Вызывается из разбора очереди соединения, поэтому не ждёт остановки воркера подписчика.
+func (p *WebsocketPool) evict(subscriberID SubscriberID) {
+	p.mu.Lock()
+	token := p.token
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		accessToken, err := token.GetAccessToken()
+		if err != nil {
+			log.Printf("subscriber %s eviction: %s", subscriberID, err)
+			return
+		}
+
+		if err := p.RemoveSubscriber(accessToken, subscriberID); err != nil {
+			log.Printf("subscriber %s eviction: %s", subscriberID, err)
+		}
+	}()
+}
+
+func (p *WebsocketPool) RemoveAllSubscribers(token string) error {
+	for _, subscriber := range p.subscribers.All() {
+		_ = p.RemoveSubscriber(token, subscriber.ID)
+	}
+
+	return nil
+}
+
+// subscribeClient подписка, которая принадлежит клиенту, а не подписчику
+func (p *WebsocketPool) subscribeClient(token *Token, subscription *Subscription) error {
+	ws, err := p.acquire(1)
+	if err != nil {
+		return err
+	}
+
+	ws.subscriptions.Add(clientOwner, subscription)
+
+	if err := ws.Subscribe(token, clientOwner, subscription); err != nil {
+		_ = ws.subscriptions.Delete(clientOwner, subscription.GUID)
+		p.release(ws, 1)
+		return err
+	}
+
+	p.mu.Lock()
+	p.clientSubs[subscription.GUID] = ws
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *WebsocketPool) unsubscribeClient(token string, guid GUID) error {
+	p.mu.Lock()
+	ws, ok := p.clientSubs[guid]
+	delete(p.clientSubs, guid)
+	p.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	p.release(ws, 1)
+	_ = ws.subscriptions.Delete(clientOwner, guid)
+
+	return ws.Unsubscribe(token, clientOwner, guid)
+}
+
+// Unsubscribe отписывает от подписки в соединении подписчика
+func (p *WebsocketPool) Unsubscribe(token string, subscriberID SubscriberID, guid GUID) error {
+	p.mu.Lock()
+	place, ok := p.placement[subscriberID]
+	p.mu.Unlock()
+
+	if !ok {
+		return p.unsubscribeClient(token, guid)
+	}
+
+	return place.ws.Unsubscribe(token, subscriberID, guid)
+}
+
+// Reauthorize повторяет с новым токеном неактивные подписки во всех соединениях
+func (p *WebsocketPool) Reauthorize(token *Token) error {
+	for _, ws := range p.Connections() {
+		if err := ws.Reauthorize(token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *WebsocketPool) Close() error {
+	var closeErr error
+
+	p.mu.Lock()
+	cancel := p.cancel
+	p.mu.Unlock()
+
+	// Воркеры оставшихся подписчиков завершаются вместе с пулом
+	if cancel != nil {
+		cancel()
+	}
+
+	for _, subscriber := range p.subscribers.All() {
+		subscriber.Stop()
+	}
+
+	p.wg.Wait()
+
+	for _, ws := range p.Connections() {
+		if err := ws.Close(); err != nil {
+			closeErr = err
+		}
+	}
+
+	return closeErr
+}
+
+// OnEvent регистрирует колбэк на подключения, разрывы и переподписки всех соединений пула
+func (p *WebsocketPool) OnEvent(listener func(ConnectionEvent)) {
+	p.listenersMu.Lock()
+	defer p.listenersMu.Unlock()
+
+	p.listeners = append(p.listeners, listener)
+}
+
+func (p *WebsocketPool) emit(event ConnectionEvent) {
+	if event.Err != nil {
+		log.Printf("websocket pool: connection %d %s: %s", event.Connection, event.Type, event.Err)
+	} else {
+		log.Printf("websocket pool: connection %d %s %s", event.Connection, event.Type, event.GUID)
+	}
+
+	p.listenersMu.RLock()
+	listeners := append([]func(ConnectionEvent){}, p.listeners...)
+	p.listenersMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// Connections копия списка соединений
+func (p *WebsocketPool) Connections() []*Websocket {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*Websocket{}, p.connections...)
+}
+
+// Stats нагрузка по соединениям в порядке их открытия
+func (p *WebsocketPool) Stats() []ConnectionStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]ConnectionStats, 0, len(p.connections))
+	for _, ws := range p.connections {
+		stats = append(stats, ConnectionStats{
+			Subscriptions: p.load[ws],
+			Queue:         ws.queue.Stats(),
+			Connected:     ws.IsConnected(),
+		})
+	}
+
+	return stats
+}
+
+func (p *WebsocketPool) GetSubscriber(subscriberID SubscriberID) (*Subscriber, error) {
+	return p.subscribers.Get(subscriberID)
+}
+
+func (p *WebsocketPool) GetSubscribers() (map[SubscriberID]*Subscriber, error) {
+	return p.subscribers.All(), nil
+}
+
+// GetAllStrategyBars копии баров подписчика, снятые между его событиями
+func (p *WebsocketPool) GetAllStrategyBars(subscriberID SubscriberID) ([]*Bar, error) {
+	subscriber, err := p.subscribers.Get(subscriberID)
+	if err != nil {
+		return nil, ErrSubscriberNotFound
+	}
+
+	var bars []*Bar
+	err = subscriber.view(func(processor *DataProcessor) error {
+		all, err := processor.bars.GetAllBars()
+		if err != nil {
+			return err
+		}
+
+		bars = cloneBars(all)
+		return nil
+	})
+
+	return bars, err
+}
+
+// GetTransformedStrategyBars производные бары подписчика, см. DataProcessor.TransformedBars
+func (p *WebsocketPool) GetTransformedStrategyBars(subscriberID SubscriberID, transform string) ([]*Bar, error) {
+	subscriber, err := p.subscribers.Get(subscriberID)
+	if err != nil {
+		return nil, ErrSubscriberNotFound
+	}
+
+	var bars []*Bar
+	err = subscriber.view(func(processor *DataProcessor) (err error) {
+		bars, err = processor.TransformedBars(transform)
+		return err
+	})
+
+	return bars, err
+}
+
+// GetStrategyFootprints кластеры последних баров подписчика, см. DataProcessor.Footprints
+func (p *WebsocketPool) GetStrategyFootprints(subscriberID SubscriberID, timeframe Timeframe, count int) ([]BarFootprint, error) {
+	subscriber, err := p.subscribers.Get(subscriberID)
+	if err != nil {
+		return nil, ErrSubscriberNotFound
+	}
+
+	var footprints []BarFootprint
+	err = subscriber.view(func(processor *DataProcessor) (err error) {
+		footprints, err = processor.Footprints(timeframe, count)
+		return err
+	})
+
+	return footprints, err
+}
@@ -0,0 +1,184 @@
+package alor
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type SubscriptionState string
+
+const (
+	PendingSubscription SubscriptionState = "pending" // Запрос отправлен, ответа брокера ещё нет
+	ActiveSubscription  SubscriptionState = "active"  // Брокер подтвердил подписку или прислал данные
+	FailedSubscription  SubscriptionState = "failed"  // Брокер отклонил подписку
+)
+
+// BrokerError брокер ответил на запрос вебсокета ошибкой
+type BrokerError struct {
+	GUID     GUID   `json:"guid"`
+	HttpCode int    `json:"http_code"`
+	Message  string `json:"message"`
+}
+
+func (e *BrokerError) Error() string {
+	return fmt.Sprintf("broker rejected request %s: %d %s", e.GUID, e.HttpCode, e.Message)
+}
+
+func (e *BrokerError) Unwrap() []error {
+	if e.HttpCode == http.StatusUnauthorized {
+		return []error{ErrSubscriptionRejected, ErrUnauthorized}
+	}
+
+	return []error{ErrSubscriptionRejected}
+}
+
+// pendingRequest запрос, ждущий ответа брокера
+type pendingRequest struct {
+	opcode   Opcode
+	response chan WsResponse // С буфером, читатель вебсокета не ждёт получателя
+	deadline time.Time
+}
+
+// pendingRequests ответы брокера приходят с requestGuid, равным GUID подписки.
+// На один GUID может ждать несколько запросов (отписка и новая подписка), ответы приходят в порядке запросов.
+type pendingRequests struct {
+	list map[GUID][]*pendingRequest
+	mu   sync.Mutex
+}
+
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{
+		list: make(map[GUID][]*pendingRequest),
+	}
+}
+
+func (r *pendingRequests) add(guid GUID, opcode Opcode, timeout time.Duration) *pendingRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request := &pendingRequest{
+		opcode:   opcode,
+		response: make(chan WsResponse, 1),
+		deadline: time.Now().Add(timeout),
+	}
+
+	r.list[guid] = append(r.list[guid], request)
+
+	return request
+}
+
+// resolve отдаёт ответ самому старому из неистёкших запросов
+func (r *pendingRequests) resolve(guid GUID, response WsResponse) (*pendingRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	requests := r.list[guid]
+
+	for len(requests) > 0 {
+		request := requests[0]
+		requests = requests[1:]
+
+		if now.After(request.deadline) {
+			continue
+		}
+
+		request.response <- response
+		r.set(guid, requests)
+
+		return request, true
+	}
+
+	r.set(guid, requests)
+
+	return nil, false
+}
+
+func (r *pendingRequests) remove(guid GUID, request *pendingRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	requests := r.list[guid]
+	for i, item := range requests {
+		if item == request {
+			r.set(guid, append(requests[:i:i], requests[i+1:]...))
+			return
+		}
+	}
+}
+
+func (r *pendingRequests) set(guid GUID, requests []*pendingRequest) {
+	if len(requests) == 0 {
+		delete(r.list, guid)
+		return
+	}
+
+	r.list[guid] = requests
+}
+
+// request отправляет запрос и регистрирует ожидание ответа на него
+func (ws *Websocket) request(guid GUID, opcode Opcode, message []byte) (*pendingRequest, error) {
+	request := ws.requests.add(guid, opcode, ws.heartbeat.AckTimeout)
+
+	if opcode != UnsubscribeOpcode {
+		ws.subscriptions.SetState(guid, PendingSubscription, "")
+	}
+
+	// Между прошлыми данными и новой подпиской могли пройти сделки
+	if opcode == AllTradesOpcode {
+		ws.resumed.mark(guid)
+	}
+
+	if err := ws.Send(message); err != nil {
+		ws.requests.remove(guid, request)
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// wait ждёт ответа брокера на запрос до AckTimeout
+func (ws *Websocket) wait(guid GUID, request *pendingRequest) error {
+	timer := time.NewTimer(time.Until(request.deadline))
+	defer timer.Stop()
+
+	select {
+	case response := <-request.response:
+		if response.HttpCode != http.StatusOK {
+			return &BrokerError{GUID: guid, HttpCode: response.HttpCode, Message: response.Message}
+		}
+
+		return nil
+	case <-timer.C:
+		ws.requests.remove(guid, request)
+		return fmt.Errorf("%w: %s", ErrSubscriptionTimeout, guid)
+	case <-ws.done:
+		return fmt.Errorf("connection is closing")
+	}
+}
+
+// handleAck отдаёт ответ брокера ждущему запросу и обновляет состояние подписки
+func (ws *Websocket) handleAck(response WsResponse) {
+	guid := GUID(response.RequestGuid)
+
+	request, ok := ws.requests.resolve(guid, response)
+	if !ok {
+		log.Printf("системное сообщение без запроса %+v\n", response)
+		return
+	}
+
+	if request.opcode == UnsubscribeOpcode {
+		return
+	}
+
+	if response.HttpCode != http.StatusOK {
+		log.Printf("subscription %s rejected: %d %s", guid, response.HttpCode, response.Message)
+		ws.subscriptions.SetState(guid, FailedSubscription, response.Message)
+		return
+	}
+
+	ws.subscriptions.SetState(guid, ActiveSubscription, "")
+}